@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// captureFiles are the files --capture writes, one List per resource.
+var captureFiles = []string{"namespaces.yaml", "nodes.yaml", "daemonsets.yaml", "deployments.yaml"}
+
+// captureFixtures writes the namespaces, nodes, DaemonSets and Deployments the
+// collectors read into dir as YAML Lists, so a support case can be reproduced
+// by seeding a fake clientset with loadCapture. Object UIDs, which include the
+// cluster UUID, and credential-looking env values and flags are redacted.
+func captureFixtures(ctx context.Context, clientset kubernetes.Interface, dir string) error {
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return withExitCode(exitCollectFailed, fmt.Errorf("list namespaces: %w", err))
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return withExitCode(exitCollectFailed, fmt.Errorf("list nodes: %w", err))
+	}
+	daemonSets, err := clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return withExitCode(exitCollectFailed, fmt.Errorf("list daemonsets: %w", err))
+	}
+	deployments, err := clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return withExitCode(exitCollectFailed, fmt.Errorf("list deployments: %w", err))
+	}
+
+	namespaces.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "NamespaceList"}
+	for i := range namespaces.Items {
+		redactObjectMeta(&namespaces.Items[i].ObjectMeta)
+	}
+	nodes.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "NodeList"}
+	for i := range nodes.Items {
+		redactObjectMeta(&nodes.Items[i].ObjectMeta)
+	}
+	daemonSets.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSetList"}
+	for i := range daemonSets.Items {
+		redactObjectMeta(&daemonSets.Items[i].ObjectMeta)
+		redactPodTemplate(&daemonSets.Items[i].Spec.Template)
+	}
+	deployments.TypeMeta = metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DeploymentList"}
+	for i := range deployments.Items {
+		redactObjectMeta(&deployments.Items[i].ObjectMeta)
+		redactPodTemplate(&deployments.Items[i].Spec.Template)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create capture directory: %w", err)
+	}
+	lists := []runtime.Object{namespaces, nodes, daemonSets, deployments}
+	for i, list := range lists {
+		raw, err := yaml.Marshal(list)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", captureFiles[i], err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, captureFiles[i]), raw, 0o600); err != nil {
+			return fmt.Errorf("write %s: %w", captureFiles[i], err)
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"dir":         dir,
+		"namespaces":  len(namespaces.Items),
+		"nodes":       len(nodes.Items),
+		"daemonsets":  len(daemonSets.Items),
+		"deployments": len(deployments.Items),
+	}).Info("capture written")
+	return nil
+}
+
+// loadCapture decodes the files written by captureFixtures into objects for
+// fake.NewClientset. Missing files are skipped.
+func loadCapture(dir string) ([]runtime.Object, error) {
+	decoder := scheme.Codecs.UniversalDeserializer()
+	var objects []runtime.Object
+	for _, name := range captureFiles {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		list, _, err := decoder.Decode(raw, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", name, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		objects = append(objects, items...)
+	}
+	return objects, nil
+}
+
+// redactObjectMeta drops the UIDs that identify the cluster, and the managed
+// fields that only add noise.
+func redactObjectMeta(m *metav1.ObjectMeta) {
+	if m.UID != "" {
+		m.UID = "REDACTED"
+	}
+	for i := range m.OwnerReferences {
+		m.OwnerReferences[i].UID = "REDACTED"
+	}
+	m.ManagedFields = nil
+}
+
+// redactPodTemplate hides env values holding an install UUID, such as Rancher's
+// CATTLE_INSTALL_UUID, and env values and --flag=value command and args entries
+// whose names look credential-bearing.
+func redactPodTemplate(template *corev1.PodTemplateSpec) {
+	redactObjectMeta(&template.ObjectMeta)
+	containers := [][]corev1.Container{template.Spec.InitContainers, template.Spec.Containers}
+	for _, list := range containers {
+		for i := range list {
+			c := &list[i]
+			for j := range c.Env {
+				if strings.Contains(strings.ToLower(c.Env[j].Name), "uuid") && c.Env[j].Value != "" {
+					c.Env[j].Value = "REDACTED"
+					continue
+				}
+				c.Env[j].Value = redactValue(c.Env[j].Name, c.Env[j].Value)
+			}
+			redactFlags(c.Command)
+			redactFlags(c.Args)
+		}
+	}
+}
+
+// redactFlags hides the values of credential-bearing --flag=value arguments
+// in place.
+func redactFlags(args []string) {
+	for i, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(name, "-") {
+			args[i] = name + "=" + redactValue(name, value)
+		}
+	}
+}
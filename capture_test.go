@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rancher/rke2-security-responder/telemetry"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCapture_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	*capture = dir
+	t.Cleanup(func() { *capture = "" })
+
+	original := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system", UID: "cattle-uid"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "server-1",
+				UID:    "node-uid",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"},
+			},
+			Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "SLE Micro 6.1", KernelVersion: "6.4.0-150600.23.47-default", Architecture: "amd64"}},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-canal", Namespace: "kube-system"},
+			Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:    "calico-node",
+					Image:   "rancher/hardened-calico:v3.29.2-build20250306",
+					Command: []string{"calico-node", "--token=command-secret"},
+					Args:    []string{"--api-token=secret-value", "--log-level=info"},
+				}},
+			}}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "rancher/rancher-agent:v2.10.3",
+					Env: []corev1.EnvVar{
+						{Name: "CATTLE_INSTALL_UUID", Value: "rancher-install-uuid"},
+						{Name: "CATTLE_TOKEN", Value: "secret-value"},
+						{Name: "CATTLE_CLUSTER_NAME", Value: "local"},
+					},
+				}},
+			}}},
+		},
+	)
+
+	if err := runWithClientset(context.Background(), original); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	for _, name := range captureFiles {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		for _, leaked := range []string{"test-cluster-uuid", "rancher-install-uuid", "secret-value", "command-secret", "node-uid"} {
+			if strings.Contains(string(raw), leaked) {
+				t.Errorf("%s contains %q", name, leaked)
+			}
+		}
+	}
+
+	objects, err := loadCapture(dir)
+	if err != nil {
+		t.Fatalf("loadCapture() error = %v", err)
+	}
+	replayed := fake.NewClientset(objects...)
+
+	want, err := telemetry.Collect(context.Background(), original, "recommended")
+	if err != nil {
+		t.Fatalf("Collect(original) error = %v", err)
+	}
+	got, err := telemetry.Collect(context.Background(), replayed, "recommended")
+	if err != nil {
+		t.Fatalf("Collect(replayed) error = %v", err)
+	}
+
+	if got.ExtraTagInfo["clusteruuid"] != "REDACTED" {
+		t.Errorf("replayed clusteruuid = %q, want REDACTED", got.ExtraTagInfo["clusteruuid"])
+	}
+	if got.ExtraFieldInfo["rancher-install-uuid"] != "REDACTED" {
+		t.Errorf("replayed rancher-install-uuid = %v, want REDACTED", got.ExtraFieldInfo["rancher-install-uuid"])
+	}
+	// Apart from the redacted UUID and the timing, the replay must see the same cluster
+	for _, key := range []string{"rancher-install-uuid", "collect-duration-ms"} {
+		delete(want.ExtraFieldInfo, key)
+		delete(got.ExtraFieldInfo, key)
+	}
+	for key, value := range want.ExtraFieldInfo {
+		if got.ExtraFieldInfo[key] != value {
+			t.Errorf("replayed %s = %v, want %v", key, got.ExtraFieldInfo[key], value)
+		}
+	}
+}
+
+func TestLoadCapture_MissingFiles(t *testing.T) {
+	objects, err := loadCapture(t.TempDir())
+	if err != nil {
+		t.Fatalf("loadCapture() error = %v", err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("loadCapture() = %d objects, want 0", len(objects))
+	}
+}
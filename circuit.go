@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// circuitState is persisted between runs. Each CronJob run is a fresh process,
+// so the failure streak has to live in a file.
+type circuitState struct {
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	OpenUntil           time.Time `json:"openUntil,omitzero"`
+}
+
+// circuitBreaker skips the send after repeated failures, so air-gapped clusters
+// don't pay for a doomed send plus retries on every run.
+type circuitBreaker struct {
+	path      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	state     circuitState
+}
+
+// loadCircuitBreaker reads the state file at path. A missing or unreadable file
+// starts a closed circuit rather than failing the run.
+func loadCircuitBreaker(path string, threshold int, cooldown time.Duration) *circuitBreaker {
+	cb := &circuitBreaker{path: path, threshold: threshold, cooldown: cooldown, now: time.Now}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logrus.WithError(err).WithField("path", path).Warn("failed to read circuit state, starting closed")
+		}
+		return cb
+	}
+	if err := json.Unmarshal(raw, &cb.state); err != nil {
+		logrus.WithError(err).WithField("path", path).Warn("invalid circuit state, starting closed")
+		cb.state = circuitState{}
+	}
+	return cb
+}
+
+// isOpen reports whether sending should be skipped for this run.
+func (cb *circuitBreaker) isOpen() bool {
+	return cb.now().Before(cb.state.OpenUntil)
+}
+
+// recordSuccess closes the circuit and resets the failure streak.
+func (cb *circuitBreaker) recordSuccess() error {
+	cb.state = circuitState{}
+	return cb.save()
+}
+
+// recordFailure extends the failure streak and opens the circuit for the
+// cooldown once the threshold is reached. The streak is kept while open, so a
+// failed attempt after the cooldown reopens the circuit immediately.
+func (cb *circuitBreaker) recordFailure() error {
+	cb.state.ConsecutiveFailures++
+	if cb.threshold > 0 && cb.state.ConsecutiveFailures >= cb.threshold {
+		cb.state.OpenUntil = cb.now().Add(cb.cooldown)
+	}
+	return cb.save()
+}
+
+func (cb *circuitBreaker) save() error {
+	raw, err := json.Marshal(cb.state)
+	if err != nil {
+		return fmt.Errorf("encode circuit state: %w", err)
+	}
+	if err := os.WriteFile(cb.path, raw, 0o600); err != nil {
+		return fmt.Errorf("write circuit state %s: %w", cb.path, err)
+	}
+	return nil
+}
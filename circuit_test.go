@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	load := func() *circuitBreaker {
+		cb := loadCircuitBreaker(path, 2, time.Hour)
+		cb.now = func() time.Time { return now }
+		return cb
+	}
+
+	cb := load()
+	if cb.isOpen() {
+		t.Fatal("circuit without state file should be closed")
+	}
+
+	// First failure stays below the threshold
+	if err := cb.recordFailure(); err != nil {
+		t.Fatalf("recordFailure() error = %v", err)
+	}
+	if cb = load(); cb.isOpen() {
+		t.Fatal("circuit should stay closed below threshold")
+	}
+
+	// Second failure opens the circuit for the cooldown
+	if err := cb.recordFailure(); err != nil {
+		t.Fatalf("recordFailure() error = %v", err)
+	}
+	if cb = load(); !cb.isOpen() {
+		t.Fatal("circuit should open at threshold")
+	}
+
+	// After the cooldown the next run may try again; another failure reopens
+	now = now.Add(time.Hour)
+	if cb = load(); cb.isOpen() {
+		t.Fatal("circuit should close after cooldown")
+	}
+	if err := cb.recordFailure(); err != nil {
+		t.Fatalf("recordFailure() error = %v", err)
+	}
+	if cb = load(); !cb.isOpen() {
+		t.Fatal("failure after cooldown should reopen the circuit")
+	}
+
+	// A success resets the streak
+	now = now.Add(time.Hour)
+	cb = load()
+	if err := cb.recordSuccess(); err != nil {
+		t.Fatalf("recordSuccess() error = %v", err)
+	}
+	cb = load()
+	if cb.isOpen() || cb.state.ConsecutiveFailures != 0 {
+		t.Errorf("after success: open = %v, failures = %d, want closed with 0", cb.isOpen(), cb.state.ConsecutiveFailures)
+	}
+}
+
+func TestLoadCircuitBreaker_InvalidState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cb := loadCircuitBreaker(path, 3, time.Hour)
+	if cb.isOpen() || cb.state.ConsecutiveFailures != 0 {
+		t.Errorf("invalid state should start closed, got %+v", cb.state)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rancher/rke2-security-responder/telemetry"
+	"github.com/sirupsen/logrus"
+)
+
+// deadLetter is one line of a dead-letter file: a payload that could not be
+// sent, kept with the time it was collected and the idempotency key of the
+// original run so a replay is deduplicated against any partial delivery.
+type deadLetter struct {
+	Timestamp      time.Time       `json:"timestamp"`
+	IdempotencyKey string          `json:"idempotencyKey"`
+	Payload        *telemetry.Data `json:"payload"`
+}
+
+// appendDeadLetter appends entry as a JSON line to path, creating it if needed.
+func appendDeadLetter(path string, entry deadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open dead-letter file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write dead-letter file: %w", err)
+	}
+	return f.Close()
+}
+
+// readDeadLetters parses every non-empty line of the dead-letter file at path.
+func readDeadLetters(path string) ([]deadLetter, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read dead-letter file: %w", err)
+	}
+	var entries []deadLetter
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry deadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("dead-letter file %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read dead-letter file: %w", err)
+	}
+	return entries, nil
+}
+
+// writeDeadLetters replaces the dead-letter file at path with entries. The file
+// is written next to the original and renamed, so a crash never loses entries.
+func writeDeadLetters(path string, entries []deadLetter) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("encode dead letter: %w", err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write dead-letter file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write dead-letter file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write dead-letter file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write dead-letter file: %w", err)
+	}
+	return nil
+}
+
+// replayDeadLetters resends every payload in the dead-letter file at path,
+// unchanged apart from a "collected-at" field carrying the original collection
+// time. Entries that fail again are kept in the file for the next replay.
+func replayDeadLetters(ctx context.Context, path, endpoint string, opts []telemetry.SendOption) error {
+	entries, err := readDeadLetters(path)
+	if err != nil {
+		return withExitCode(exitConfigError, err)
+	}
+
+	var remaining []deadLetter
+	for _, entry := range entries {
+		if entry.Payload == nil {
+			continue
+		}
+		if entry.Payload.ExtraFieldInfo == nil {
+			entry.Payload.ExtraFieldInfo = make(map[string]interface{})
+		}
+		entry.Payload.ExtraFieldInfo["collected-at"] = entry.Timestamp.Format(time.RFC3339)
+
+		entryOpts := append(opts[:len(opts):len(opts)], telemetry.WithIdempotencyKey(entry.IdempotencyKey))
+		sender, err := newSender(endpoint, entryOpts)
+		if err != nil {
+			return withExitCode(exitConfigError, err)
+		}
+		if _, err := sender.Send(ctx, entry.Payload); err != nil {
+			logrus.WithError(err).WithField("timestamp", entry.Timestamp).Warn("replay failed, keeping entry")
+			remaining = append(remaining, entry)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"sent": len(entries) - len(remaining), "remaining": len(remaining)}).Info("replay finished")
+	if err := writeDeadLetters(path, remaining); err != nil {
+		return err
+	}
+	if len(remaining) > 0 && *requireSend {
+		return withExitCode(exitSendFailed, fmt.Errorf("replay: %d payloads could not be sent", len(remaining)))
+	}
+	return nil
+}
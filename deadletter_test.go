@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/rke2-security-responder/telemetry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunWithClientset_DeadLetterOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	*deadLetterFile = path
+	t.Cleanup(func() { *deadLetterFile = "" })
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", "http://127.0.0.1:1")
+
+	// The deadline cuts the retry delay short so the send fails quickly
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+	if err := runWithClientset(ctx, clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	entries, err := readDeadLetters(path)
+	if err != nil {
+		t.Fatalf("readDeadLetters() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(entries))
+	}
+	if entries[0].Payload.ExtraTagInfo["clusteruuid"] != "test-cluster-uuid" {
+		t.Errorf("clusteruuid = %q, want test-cluster-uuid", entries[0].Payload.ExtraTagInfo["clusteruuid"])
+	}
+	if entries[0].IdempotencyKey == "" || entries[0].Timestamp.IsZero() {
+		t.Errorf("dead letter missing key or timestamp: %+v", entries[0])
+	}
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	type received struct {
+		key  string
+		data telemetry.Data
+	}
+	var mu sync.Mutex
+	var got []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data telemetry.Data
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		mu.Lock()
+		got = append(got, received{key: r.Header.Get(telemetry.IdempotencyKeyHeader), data: data})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(telemetry.Response{})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	collected := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	for _, uuid := range []string{"cluster-a", "cluster-b"} {
+		entry := deadLetter{
+			Timestamp:      collected,
+			IdempotencyKey: "key-" + uuid,
+			Payload: &telemetry.Data{
+				AppVersion:     "v1.32.2+rke2r1",
+				ExtraTagInfo:   map[string]string{"clusteruuid": uuid},
+				ExtraFieldInfo: map[string]interface{}{"mode": "recommended"},
+			},
+		}
+		if err := appendDeadLetter(path, entry); err != nil {
+			t.Fatalf("appendDeadLetter() error = %v", err)
+		}
+	}
+
+	if err := replayDeadLetters(context.Background(), path, server.URL, nil); err != nil {
+		t.Fatalf("replayDeadLetters() error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("server received %d payloads, want 2", len(got))
+	}
+	for i, uuid := range []string{"cluster-a", "cluster-b"} {
+		if got[i].data.ExtraTagInfo["clusteruuid"] != uuid {
+			t.Errorf("payload %d clusteruuid = %q, want %q", i, got[i].data.ExtraTagInfo["clusteruuid"], uuid)
+		}
+		if got[i].data.ExtraFieldInfo["collected-at"] != "2026-03-01T08:00:00Z" {
+			t.Errorf("payload %d collected-at = %v, want 2026-03-01T08:00:00Z", i, got[i].data.ExtraFieldInfo["collected-at"])
+		}
+		if got[i].key != "key-"+uuid {
+			t.Errorf("payload %d idempotency key = %q, want key-%s", i, got[i].key, uuid)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if len(raw) != 0 {
+		t.Errorf("dead-letter file after successful replay = %q, want empty", raw)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rancher/rke2-security-responder/telemetry"
+	"github.com/sirupsen/logrus"
+)
+
+// echoSend sends data through telemetry.Send, with the same send options as
+// a real run, to an in-process echo server on the loopback interface instead
+// of the endpoint. The request as the server observed it, headers and body,
+// is written to w, so transport features can be checked end to end.
+func echoSend(ctx context.Context, data *telemetry.Data, w io.Writer) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("start echo server: %w", err)
+	}
+	var (
+		mu       sync.Mutex
+		observed []byte
+	)
+	server := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		dump, err := httputil.DumpRequest(r, true)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		observed = dump
+		mu.Unlock()
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(telemetry.Response{})
+	})}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.WithError(err).Warn("echo server stopped")
+		}
+	}()
+	defer func() { _ = server.Close() }()
+
+	endpoint := "http://" + listener.Addr().String()
+	// Keep the relay envelope of a webhook endpoint so it can be checked too
+	if strings.HasPrefix(sendEndpoint(), webhookPrefix) {
+		endpoint = webhookPrefix + endpoint
+	}
+	sendOpts := append(baseSendOptions(endpoint), telemetry.WithIdempotencyKey(uuid.NewString()))
+	sender, err := newSender(endpoint, sendOpts)
+	if err != nil {
+		return withExitCode(exitConfigError, err)
+	}
+	result, err := telemetry.Deliver(ctx, sender, data)
+	if err != nil {
+		return withExitCode(exitSendFailed, fmt.Errorf("dry-run send: %w", err))
+	}
+	logrus.WithFields(logrus.Fields{"endpoint": endpoint, "attempts": result.Attempts}).Info("dry-run send: request echoed, real endpoint not contacted")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, err := w.Write(observed); err != nil {
+		return fmt.Errorf("write observed request: %w", err)
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
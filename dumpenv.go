@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rancher/rke2-security-responder/telemetry"
+)
+
+// serviceAccountTokenPath is where the in-cluster config reads its token.
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// envVars are the environment variables the tool reads, directly or through
+// the proxy support of the HTTP transport.
+var envVars = []string{
+	"SECURITY_RESPONDER_ENDPOINT",
+	"SECURITY_RESPONDER_MODE",
+	"SECURITY_RESPONDER_KUBECONFIG",
+	"SECURITY_RESPONDER_DEV",
+	"SECURITY_RESPONDER_FORCE_RELEASE",
+	"SECURITY_RESPONDER_NO_UUID",
+	"SECURITY_RESPONDER_BUCKET_COUNTS",
+	"SECURITY_RESPONDER_INSECURE",
+	"SECURITY_RESPONDER_STATE_FILE",
+	"SECURITY_RESPONDER_RATE_LIMIT_FILE",
+	"SECURITY_RESPONDER_DEAD_LETTER",
+	"SECURITY_RESPONDER_GPU_RESOURCES",
+	"SECURITY_RESPONDER_TAGS",
+	"SECURITY_RESPONDER_CLUSTER_ID",
+	"SECURITY_RESPONDER_UUID_SOURCES",
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy",
+}
+
+// sensitiveNameFragments mark env var, flag and query parameter names whose
+// values are never printed.
+var sensitiveNameFragments = []string{"token", "secret", "password", "passwd", "key", "auth", "signature"}
+
+// envDump is the --dump-env output: the raw inputs plus the values derived
+// from them, so support can see what a run would actually use.
+type envDump struct {
+	Version             string            `json:"version"`
+	Env                 map[string]string `json:"env"`
+	Flags               map[string]string `json:"flags"`
+	Endpoint            string            `json:"endpoint"`
+	Mode                string            `json:"mode"`
+	SendTimeout         string            `json:"sendTimeout"`
+	SendOverallTimeout  string            `json:"sendOverallTimeout"`
+	ServiceAccountToken bool              `json:"serviceAccountToken"`
+}
+
+// writeEnvDump prints the effective configuration as JSON to w, with
+// credentials redacted. Only variables that are set are listed. The service
+// account token is reported by presence only.
+func writeEnvDump(w io.Writer, tokenPath string) error {
+	dump := envDump{
+		Version:            Version,
+		Env:                make(map[string]string),
+		Flags:              make(map[string]string),
+		Endpoint:           redactValue("endpoint", sendEndpoint()),
+		Mode:               collectionMode(),
+		SendTimeout:        telemetry.DefaultTimeout.String(),
+		SendOverallTimeout: telemetry.DefaultOverallTimeout.String(),
+	}
+	for _, name := range envVars {
+		if value, ok := os.LookupEnv(name); ok {
+			dump.Env[name] = redactValue(name, value)
+		}
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		dump.Flags[f.Name] = redactValue(f.Name, f.Value.String())
+	})
+	if _, err := os.Stat(tokenPath); err == nil {
+		dump.ServiceAccountToken = true
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dump); err != nil {
+		return fmt.Errorf("write env dump: %w", err)
+	}
+	return nil
+}
+
+// redactValue hides value entirely when name looks credential-bearing, and
+// otherwise strips user info and sensitive query parameters from URLs, as
+// proxy and endpoint URLs may embed credentials.
+func redactValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if isSensitiveName(name) {
+		return "REDACTED"
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return value
+	}
+	if u.User != nil {
+		u.User = url.User("REDACTED")
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			if isSensitiveName(key) {
+				query.Set(key, "REDACTED")
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+func isSensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, fragment := range sensitiveNameFragments {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package main
+
+import "errors"
+
+// Exit codes let wrapper scripts branch on the outcome of a run. Errors that
+// don't carry one of the specific codes exit with exitFailure.
+const (
+	exitOK            = 0
+	exitFailure       = 1
+	exitCollectFailed = 2
+	exitSendFailed    = 3
+	exitConfigError   = 4
+)
+
+// exitError attaches an exit code to an error returned by run.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode marks err to exit with code. A nil err stays nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode maps an error returned by run to the process exit code.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitFailure
+}
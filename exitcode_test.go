@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: exitOK},
+		{name: "unclassified", err: errors.New("boom"), expected: exitFailure},
+		{name: "collect", err: withExitCode(exitCollectFailed, errors.New("no kube-system")), expected: exitCollectFailed},
+		{name: "send", err: withExitCode(exitSendFailed, errors.New("refused")), expected: exitSendFailed},
+		{name: "wrapped config", err: fmt.Errorf("startup: %w", withExitCode(exitConfigError, errors.New("bad flag"))), expected: exitConfigError},
+		{name: "nil stays nil", err: withExitCode(exitSendFailed, nil), expected: exitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.expected {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunWithClientset_ExitCodes(t *testing.T) {
+	kubeSystem := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	}
+
+	tests := []struct {
+		name        string
+		objects     []runtime.Object
+		include     string
+		requireSend bool
+		expected    int
+	}{
+		{name: "send failure warns by default", objects: kubeSystem, expected: exitOK},
+		{name: "send failure with --require-send", objects: kubeSystem, requireSend: true, expected: exitSendFailed},
+		{name: "collection failure", expected: exitCollectFailed},
+		{name: "invalid flag", objects: kubeSystem, include: "bogus", expected: exitConfigError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*collectors, *requireSend = tt.include, tt.requireSend
+			t.Cleanup(func() { *collectors, *requireSend = "", false })
+			t.Setenv("SECURITY_RESPONDER_ENDPOINT", "http://127.0.0.1:1")
+
+			// The deadline cuts the retry delay short so the send fails quickly
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			err := runWithClientset(ctx, fake.NewClientset(tt.objects...))
+			if got := exitCode(err); got != tt.expected {
+				t.Errorf("exitCode(%v) = %d, want %d", err, got, tt.expected)
+			}
+		})
+	}
+}
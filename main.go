@@ -3,23 +3,73 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/rancher/rke2-security-responder/telemetry"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/transport"
 )
 
 var Version = "dev"
 
+// stdout receives the --collect-only payload. Logs go to stderr, so stdout
+// stays pure JSON for piping into tools like jq.
+var stdout io.Writer = os.Stdout
+
 var (
-	verbose = flag.Bool("verbose", false, "enable verbose logging")
-	debug   = flag.Bool("debug", false, "dry-run: collect data but don't send")
+	verbose       = flag.Bool("verbose", false, "enable verbose logging")
+	debug         = flag.Bool("debug", false, "dry-run: collect data but don't send")
+	collectOnly   = flag.Bool("collect-only", false, "collect data, print it as JSON to stdout and exit without sending")
+	insecure      = flag.Bool("insecure", false, "skip TLS certificate verification when sending (lab use only)")
+	dumpRequest   = flag.String("dump-request", "", "write the exact HTTP request sent to this file (credential headers redacted)")
+	maxPayload    = flag.Int("max-payload-bytes", 0, "drop least essential fields until the payload fits this size (0 = unlimited)")
+	kubeconfig    = flag.String("kubeconfig", "", "kubeconfig to fall back to when not running in-cluster (or SECURITY_RESPONDER_KUBECONFIG)")
+	startupJitter = flag.Duration("startup-jitter", 0, "sleep a random duration up to this before collecting, to spread load from many clusters")
+	noUUID        = flag.Bool("no-uuid", false, "omit the cluster UUID from the payload (or SECURITY_RESPONDER_NO_UUID=true)")
+	bucketCounts  = flag.Bool("bucket-counts", false, "send count fields as ranges such as 6-20 instead of exact numbers (or SECURITY_RESPONDER_BUCKET_COUNTS=true)")
+
+	stateFile        = flag.String("state-file", "", "persist consecutive send failures here to enable the circuit breaker (or SECURITY_RESPONDER_STATE_FILE)")
+	circuitThreshold = flag.Int("circuit-threshold", 3, "consecutive send failures before sending is skipped")
+	circuitCooldown  = flag.Duration("circuit-cooldown", 24*time.Hour, "how long sending is skipped once the circuit is open")
+
+	rateLimitFile     = flag.String("rate-limit-file", "", "send at most once per --rate-limit-interval across all runs sharing this file (or SECURITY_RESPONDER_RATE_LIMIT_FILE)")
+	rateLimitInterval = flag.Duration("rate-limit-interval", time.Hour, "minimum time between sends sharing the --rate-limit-file")
+
+	deadLetterFile = flag.String("dead-letter", "", "append payloads that could not be sent to this JSON-lines file (or SECURITY_RESPONDER_DEAD_LETTER)")
+	replay         = flag.String("replay", "", "resend the payloads in this dead-letter file instead of collecting, then exit")
+
+	dumpEnv = flag.Bool("dump-env", false, "print the effective configuration (credentials redacted) as JSON and exit")
+
+	collectors        = flag.String("collectors", "", "comma-separated optional collectors to run (default all)")
+	collectorsExclude = flag.String("collectors-exclude", "", "comma-separated optional collectors to skip")
+	detectorTimeout   = flag.Duration("detector-timeout", telemetry.DefaultDetectorTimeout, "abandon an optional collector that takes longer than this (0 = no limit)")
+
+	capture = flag.String("capture", "", "write redacted namespaces, nodes, DaemonSets and Deployments as YAML into this directory and exit")
+
+	dryRunSend = flag.Bool("dry-run-send", false, "send the payload to an in-process echo server instead of the endpoint and print the request it received")
+
+	requireSend = flag.Bool("require-send", false, "exit with an error when the payload could not be sent, instead of only warning")
 )
 
 func main() {
@@ -29,65 +79,482 @@ func main() {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 
+	if *dumpEnv {
+		if err := writeEnvDump(stdout, serviceAccountTokenPath); err != nil {
+			logrus.WithError(err).Fatal("dump-env failed")
+		}
+		return
+	}
+
 	if err := run(); err != nil {
-		logrus.WithError(err).Fatal("run failed")
+		logrus.WithError(err).Error("run failed")
+		os.Exit(exitCode(err))
 	}
 }
 
 func run() error {
 	logrus.WithField("version", Version).Info("starting")
 
-	config, err := rest.InClusterConfig()
+	// SIGTERM from pod termination cancels the jitter sleep and any in-flight send
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
 	if err != nil {
-		return fmt.Errorf("in-cluster config: %w", err)
+		return withExitCode(exitConfigError, err)
+	}
+	defer func() {
+		// Flush spans even when ctx was cancelled by a signal
+		flushCtx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logrus.WithError(err).Warn("failed to flush traces")
+		}
+	}()
+
+	// Replaying needs no cluster access, so exported files can be sent from anywhere
+	if *replay != "" {
+		endpoint := sendEndpoint()
+		return replayDeadLetters(ctx, *replay, endpoint, baseSendOptions(endpoint))
 	}
 
+	kubeconfigPath := *kubeconfig
+	if kubeconfigPath == "" {
+		kubeconfigPath = os.Getenv("SECURITY_RESPONDER_KUBECONFIG")
+	}
+	config, err := loadConfig(kubeconfigPath)
+	if err != nil {
+		return withExitCode(exitConfigError, err)
+	}
+
+	apiCalls := &atomic.Int64{}
+	config.Wrap(countRequests(apiCalls))
+
+	clientset, err := newClientset(config)
+	if err != nil {
+		return withExitCode(exitConfigError, err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return withExitCode(exitConfigError, fmt.Errorf("dynamic client: %w", err))
+	}
+
+	return runWithClientset(ctx, clientset, telemetry.WithDynamicClient(dynamicClient), telemetry.WithAPICallCounter(apiCalls))
+}
+
+// countRequests returns a transport wrapper that increments counter for every
+// request sent to the API server, discovery included.
+func countRequests(counter *atomic.Int64) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			counter.Add(1)
+			return rt.RoundTrip(req)
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newClientset builds the production clientset. Everything after it only needs
+// kubernetes.Interface, so tests can substitute a fake clientset.
+func newClientset(config *rest.Config) (kubernetes.Interface, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return fmt.Errorf("kubernetes client: %w", err)
+		return nil, fmt.Errorf("kubernetes client: %w", err)
+	}
+	return clientset, nil
+}
+
+// runWithClientset collects and sends the payload using an existing clientset.
+// extraOpts are passed to Collect after the flag-derived options.
+func runWithClientset(ctx context.Context, clientset kubernetes.Interface, extraOpts ...telemetry.CollectOption) error {
+	if *capture != "" {
+		return captureFixtures(ctx, clientset, *capture)
 	}
 
-	ctx := context.Background()
+	mode := collectionMode()
+	collectOpts, err := collectorOptions()
+	if err != nil {
+		return withExitCode(exitConfigError, err)
+	}
+	collectOpts = append(collectOpts, telemetry.WithDetectorTimeout(*detectorTimeout))
+	collectOpts = append(collectOpts, extraOpts...)
 
-	mode := os.Getenv("SECURITY_RESPONDER_MODE")
-	if mode == "" {
-		mode = "recommended"
+	if *startupJitter > 0 {
+		delay := jitterDelay(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), *startupJitter)
+		logrus.WithField("delay", delay).Info("waiting before collecting")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("startup jitter: %w", ctx.Err())
+		case <-time.After(delay):
+		}
 	}
 
-	data, err := telemetry.Collect(ctx, clientset, mode)
+	if spec := os.Getenv("SECURITY_RESPONDER_GPU_RESOURCES"); spec != "" {
+		resources, err := telemetry.ParseGPUResources(spec)
+		if err != nil {
+			logrus.WithError(err).Warn("ignoring SECURITY_RESPONDER_GPU_RESOURCES")
+		} else {
+			collectOpts = append(collectOpts, telemetry.WithGPUResources(resources))
+		}
+	}
+
+	if id := os.Getenv("SECURITY_RESPONDER_CLUSTER_ID"); id != "" {
+		collectOpts = append(collectOpts, telemetry.WithClusterID(id))
+	}
+	if spec := os.Getenv("SECURITY_RESPONDER_UUID_SOURCES"); spec != "" {
+		sources, err := telemetry.ParseUUIDSources(spec)
+		if err != nil {
+			logrus.WithError(err).Warn("ignoring SECURITY_RESPONDER_UUID_SOURCES")
+		} else {
+			collectOpts = append(collectOpts, telemetry.WithUUIDSources(sources...))
+		}
+	}
+
+	if spec := os.Getenv("SECURITY_RESPONDER_TAGS"); spec != "" {
+		tags, err := telemetry.ParseTags(spec)
+		if err != nil {
+			logrus.WithError(err).Warn("ignoring SECURITY_RESPONDER_TAGS")
+		} else {
+			collectOpts = append(collectOpts, telemetry.WithTags(tags))
+		}
+	}
+
+	data, err := telemetry.Collect(ctx, clientset, mode, collectOpts...)
 	if err != nil {
-		return fmt.Errorf("collect data: %w", err)
+		return withExitCode(exitCollectFailed, fmt.Errorf("collect data: %w", err))
 	}
 
 	// Mark non-release builds for server-side filtering
-	// Clean tags: v1.2.3, v1.2.3-rc1, v1.2.3+rke2r1
-	// Non-clean: v1.2.3-5-gabcdef (commits after tag), v1.2.3-dirty, abcdef (no tag), dev
-	if !isReleaseVersion(Version) || os.Getenv("SECURITY_RESPONDER_DEV") == "true" {
+	if isDevBuild(Version) {
 		data.ExtraFieldInfo["dev"] = true
 	}
 
+	if *collectOnly {
+		telemetry.ApplyTransformers(data, payloadTransformers()...)
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("write payload: %w", err)
+		}
+		return nil
+	}
+
 	if *debug {
-		jsonData, _ := json.MarshalIndent(data, "", "  ")
+		telemetry.ApplyTransformers(data, payloadTransformers()...)
+		logged := data
+		if !*verbose {
+			logged = redactForLog(data)
+		}
+		jsonData, _ := json.MarshalIndent(logged, "", "  ")
 		logrus.WithField("payload", string(jsonData)).Info("debug mode: skipping send")
 		return nil
 	}
 
-	endpoint := os.Getenv("SECURITY_RESPONDER_ENDPOINT")
-	if endpoint == "" {
-		endpoint = telemetry.DefaultEndpoint
+	if *dryRunSend {
+		return echoSend(ctx, data, stdout)
+	}
+
+	endpoint := sendEndpoint()
+
+	statePath := *stateFile
+	if statePath == "" {
+		statePath = os.Getenv("SECURITY_RESPONDER_STATE_FILE")
+	}
+	var breaker *circuitBreaker
+	if statePath != "" {
+		breaker = loadCircuitBreaker(statePath, *circuitThreshold, *circuitCooldown)
+		if breaker.isOpen() {
+			logrus.WithFields(logrus.Fields{
+				"failures":  breaker.state.ConsecutiveFailures,
+				"openUntil": breaker.state.OpenUntil.Format(time.RFC3339),
+			}).Info("circuit open: skipping send after repeated failures")
+			if *requireSend {
+				return withExitCode(exitSendFailed, errors.New("send skipped: circuit open"))
+			}
+			return nil
+		}
+	}
+
+	rateLimitPath := *rateLimitFile
+	if rateLimitPath == "" {
+		rateLimitPath = os.Getenv("SECURITY_RESPONDER_RATE_LIMIT_FILE")
+	}
+	if rateLimitPath != "" {
+		ok, next, err := newSendThrottle(rateLimitPath, *rateLimitInterval).acquire()
+		if err != nil {
+			// Throttling protects shared egress; a broken file must not silence the fleet
+			logrus.WithError(err).Warn("failed to check rate limit, sending anyway")
+		} else if !ok {
+			logrus.WithField("nextSend", next.Format(time.RFC3339)).Info("rate limited: skipping send")
+			if *requireSend {
+				return withExitCode(exitSendFailed, errors.New("send skipped: rate limited"))
+			}
+			return nil
+		}
+	}
+
+	// One key per run: retries of the same payload share it, the next run gets a new one
+	idempotencyKey := uuid.NewString()
+	sendOpts := append(baseSendOptions(endpoint), telemetry.WithIdempotencyKey(idempotencyKey))
+	sender, err := newSender(endpoint, sendOpts)
+	if err != nil {
+		return withExitCode(exitConfigError, err)
+	}
+
+	result, err := telemetry.Deliver(ctx, sender, data)
+	if err != nil {
+		fields := logrus.Fields{"attempts": result.Attempts}
+		if result.StatusCode != 0 {
+			// The endpoint was reachable but rejected the payload
+			fields["statusCode"] = result.StatusCode
+			logrus.WithFields(fields).WithError(err).Warn("endpoint rejected data")
+		} else {
+			logrus.WithFields(fields).WithError(err).Warn("failed to send (expected in disconnected environments)")
+		}
+
+		deadLetterPath := *deadLetterFile
+		if deadLetterPath == "" {
+			deadLetterPath = os.Getenv("SECURITY_RESPONDER_DEAD_LETTER")
+		}
+		if deadLetterPath != "" {
+			entry := deadLetter{Timestamp: time.Now().UTC(), IdempotencyKey: idempotencyKey, Payload: data}
+			if err := appendDeadLetter(deadLetterPath, entry); err != nil {
+				logrus.WithError(err).Warn("failed to write dead letter")
+			} else {
+				logrus.WithField("path", deadLetterPath).Info("payload saved for replay")
+			}
+		}
+	}
+
+	if err == nil && result.Response != nil {
+		logAcknowledgement(result.Response.Acknowledged, data.ExtraFieldInfo["dev"] == true)
+		checkCollectorVersion(Version, result.Response.MinCollectorVersion)
 	}
 
-	if _, err := telemetry.Send(ctx, data, endpoint); err != nil {
-		logrus.WithError(err).Warn("failed to send (expected in disconnected environments)")
+	if breaker != nil {
+		record := breaker.recordSuccess
+		if err != nil {
+			record = breaker.recordFailure
+		}
+		if err := record(); err != nil {
+			logrus.WithError(err).Warn("failed to update circuit state")
+		}
 	}
 
+	if err != nil && *requireSend {
+		return withExitCode(exitSendFailed, fmt.Errorf("send: %w", err))
+	}
 	return nil
 }
 
+// sensitiveLogKeys are payload tags and fields masked in the --debug payload log
+// unless --verbose is set. They are random, but still identify the cluster.
+var sensitiveLogKeys = []string{"clusteruuid", "clusterIdentity", "rancher-install-uuid"}
+
+// redactForLog returns a copy of data with the sensitiveLogKeys that have a
+// value replaced by REDACTED. data itself is not modified.
+func redactForLog(data *telemetry.Data) *telemetry.Data {
+	redacted := *data
+	redacted.ExtraTagInfo = maps.Clone(data.ExtraTagInfo)
+	redacted.ExtraFieldInfo = maps.Clone(data.ExtraFieldInfo)
+	for _, key := range sensitiveLogKeys {
+		if redacted.ExtraTagInfo[key] != "" {
+			redacted.ExtraTagInfo[key] = "REDACTED"
+		}
+		if value, ok := redacted.ExtraFieldInfo[key]; ok && value != "" {
+			redacted.ExtraFieldInfo[key] = "REDACTED"
+		}
+	}
+	return &redacted
+}
+
+// collectionMode returns the configured collection mode, defaulting to "recommended".
+func collectionMode() string {
+	if mode := os.Getenv("SECURITY_RESPONDER_MODE"); mode != "" {
+		return mode
+	}
+	return "recommended"
+}
+
+// logAcknowledgement confirms how the endpoint classified the submission, so
+// integration tests can check dev-build filtering end to end. Endpoints that
+// don't echo a classification are skipped.
+func logAcknowledgement(ack *telemetry.Acknowledgement, dev bool) {
+	if ack == nil {
+		return
+	}
+	fields := logrus.Fields{"dev": dev, "acknowledgedDev": ack.Dev}
+	if ack.Dev != dev {
+		logrus.WithFields(fields).Warn("endpoint classified the submission differently than flagged")
+		return
+	}
+	logrus.WithFields(fields).Info("endpoint acknowledged submission")
+}
+
+// checkCollectorVersion warns when the endpoint requires a newer collector than
+// the running build. Endpoints that don't send a minimum, and builds without a
+// release version (dev builds, bare commit hashes), are skipped. Versions are
+// compared leniently so a git describe suffix like -5-gabcdef0 doesn't make a
+// build older than its tag.
+func checkCollectorVersion(current, minimum string) {
+	if minimum == "" {
+		return
+	}
+	fields := logrus.Fields{"version": current, "minCollectorVersion": minimum}
+	currentVersion, err := version.ParseGeneric(current)
+	if err != nil {
+		logrus.WithFields(fields).Debug("build has no release version, skipping collector version check")
+		return
+	}
+	minimumVersion, err := version.ParseGeneric(minimum)
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Warn("endpoint sent an invalid minimum collector version")
+		return
+	}
+	if currentVersion.LessThan(minimumVersion) {
+		logrus.WithFields(fields).Warn("security responder is older than the endpoint supports; upgrade RKE2 to get a newer collector")
+	}
+}
+
+// collectorOptions turns --collectors and --collectors-exclude into Collect
+// options. Unknown names, and excluding a mandatory collector, are errors.
+func collectorOptions() ([]telemetry.CollectOption, error) {
+	var opts []telemetry.CollectOption
+	if *collectors != "" {
+		names, err := telemetry.ParseCollectors(*collectors)
+		if err != nil {
+			return nil, fmt.Errorf("--collectors: %w", err)
+		}
+		opts = append(opts, telemetry.WithCollectors(names...))
+	}
+	if *collectorsExclude != "" {
+		names, err := telemetry.ParseCollectors(*collectorsExclude)
+		if err != nil {
+			return nil, fmt.Errorf("--collectors-exclude: %w", err)
+		}
+		for _, name := range names {
+			if slices.Contains(telemetry.MandatoryCollectors, name) {
+				return nil, fmt.Errorf("--collectors-exclude: collector %q is mandatory", name)
+			}
+		}
+		opts = append(opts, telemetry.WithoutCollectors(names...))
+	}
+	return opts, nil
+}
+
+// sendEndpoint returns the configured endpoint, defaulting to telemetry.DefaultEndpoint.
+func sendEndpoint() string {
+	if endpoint := os.Getenv("SECURITY_RESPONDER_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return telemetry.DefaultEndpoint
+}
+
+// payloadTransformers returns the transformers selected by flags and env vars.
+func payloadTransformers() []telemetry.Transformer {
+	var transformers []telemetry.Transformer
+	if *noUUID || os.Getenv("SECURITY_RESPONDER_NO_UUID") == "true" {
+		transformers = append(transformers, telemetry.RedactUUID)
+	}
+	if *bucketCounts || os.Getenv("SECURITY_RESPONDER_BUCKET_COUNTS") == "true" {
+		transformers = append(transformers, telemetry.BucketCounts)
+	}
+	return transformers
+}
+
+// webhookPrefix marks an endpoint whose payload is wrapped for a relay before
+// it is sent, e.g. webhook+https://kafka-rest.example.com/topics/telemetry.
+const webhookPrefix = "webhook+"
+
+// newSender picks the sender for endpoint by its URL scheme. http, https and
+// unix endpoints get the JSON payload as is; with the webhookPrefix it is
+// wrapped as a Kafka REST Proxy record first.
+func newSender(endpoint string, opts []telemetry.SendOption) (telemetry.Sender, error) {
+	target, webhook := strings.CutPrefix(endpoint, webhookPrefix)
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+	}
+	if webhook {
+		opts = append(opts[:len(opts):len(opts)], telemetry.WithWebhookFormat(telemetry.KafkaRESTFormat))
+	}
+	return telemetry.NewHTTPSender(target, opts...), nil
+}
+
+// baseSendOptions returns the send options shared by regular runs and replays.
+func baseSendOptions(endpoint string) []telemetry.SendOption {
+	opts := []telemetry.SendOption{telemetry.WithTransformers(payloadTransformers()...)}
+	if *maxPayload > 0 {
+		opts = append(opts, telemetry.WithMaxPayloadBytes(*maxPayload))
+	}
+	if *dumpRequest != "" {
+		opts = append(opts, telemetry.WithRequestDump(*dumpRequest))
+	}
+	if *insecure || os.Getenv("SECURITY_RESPONDER_INSECURE") == "true" {
+		logrus.WithField("endpoint", endpoint).Warn("INSECURE: TLS certificate verification is disabled; do not use outside lab environments")
+		opts = append(opts, telemetry.WithInsecureSkipVerify(true))
+	}
+	return opts
+}
+
+// jitterDelay returns a uniformly random duration in [0, limit], so clusters
+// sharing a CronJob schedule don't all hit the endpoint at the same moment.
+func jitterDelay(rng *rand.Rand, limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rng.Int64N(int64(limit) + 1))
+}
+
+// loadConfig prefers the in-cluster config. Only when that is unavailable and a
+// kubeconfig path was explicitly given does it fall back to the kubeconfig, so
+// out-of-cluster runs are always a deliberate choice.
+func loadConfig(kubeconfigPath string) (*rest.Config, error) {
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		return config, nil
+	}
+	if kubeconfigPath == "" {
+		return nil, fmt.Errorf("in-cluster config: %w", err)
+	}
+	logrus.WithError(err).WithField("kubeconfig", kubeconfigPath).Info("not running in-cluster, using kubeconfig")
+	config, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig %s: %w", kubeconfigPath, err)
+	}
+	return config, nil
+}
+
 // releaseVersionRe matches clean release tags: v1.2.3, v1.2.3-rc1, v1.2.3+rke2r1
 // but NOT git describe output like v1.2.3-5-gabcdef or v1.2.3-dirty
 var releaseVersionRe = regexp.MustCompile(`^v\d+\.\d+\.\d+([+-][a-zA-Z][a-zA-Z0-9]*)?$`)
 
+// isDevBuild reports whether submissions from this build are flagged dev.
+// Clean tags: v1.2.3, v1.2.3-rc1, v1.2.3+rke2r1
+// Non-clean: v1.2.3-5-gabcdef (commits after tag), v1.2.3-dirty, abcdef (no tag), dev
+// SECURITY_RESPONDER_FORCE_RELEASE=true lets forks building from untagged
+// commits count as releases; SECURITY_RESPONDER_DEV=true wins over it, since
+// wrongly flagging dev is the safer mistake.
+func isDevBuild(version string) bool {
+	if os.Getenv("SECURITY_RESPONDER_DEV") == "true" {
+		return true
+	}
+	if os.Getenv("SECURITY_RESPONDER_FORCE_RELEASE") == "true" {
+		return false
+	}
+	return !isReleaseVersion(version)
+}
+
 func isReleaseVersion(version string) bool {
 	v := strings.ToLower(version)
 	// Explicit exclusions for git describe non-release patterns
@@ -1,9 +1,59 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/rancher/rke2-security-responder/telemetry"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
+func TestIsDevBuild(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		dev          string
+		forceRelease string
+		want         bool
+	}{
+		{name: "release", version: "v1.2.3", want: false},
+		{name: "untagged", version: "v1.2.3-5-gabcdef0", want: true},
+		{name: "force release on untagged", version: "v1.2.3-5-gabcdef0", forceRelease: "true", want: false},
+		{name: "force release on dev fallback", version: "dev", forceRelease: "true", want: false},
+		{name: "dev on release", version: "v1.2.3", dev: "true", want: true},
+		{name: "dev wins over force release", version: "v1.2.3", dev: "true", forceRelease: "true", want: true},
+		{name: "force release not true", version: "abcdef0", forceRelease: "1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SECURITY_RESPONDER_DEV", tt.dev)
+			t.Setenv("SECURITY_RESPONDER_FORCE_RELEASE", tt.forceRelease)
+			if got := isDevBuild(tt.version); got != tt.want {
+				t.Errorf("isDevBuild(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsReleaseVersion(t *testing.T) {
 	tests := []struct {
 		version string
@@ -46,3 +96,635 @@ func TestRun_OutsideCluster(t *testing.T) {
 		t.Error("run() outside k8s cluster should return error")
 	}
 }
+
+func TestJitterDelay(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	limit := 5 * time.Minute
+	for range 10000 {
+		if d := jitterDelay(rng, limit); d < 0 || d > limit {
+			t.Fatalf("jitterDelay() = %v, want within [0, %v]", d, limit)
+		}
+	}
+	if d := jitterDelay(rng, 0); d != 0 {
+		t.Errorf("jitterDelay() with no limit = %v, want 0", d)
+	}
+}
+
+func TestRunWithClientset_JitterCancelled(t *testing.T) {
+	*startupJitter = time.Hour
+	t.Cleanup(func() { *startupJitter = 0 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runWithClientset(ctx, fake.NewClientset())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runWithClientset() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	kubeconfigData := `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test-token
+`
+	if err := os.WriteFile(kubeconfigPath, []byte(kubeconfigData), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantErr  bool
+		wantHost string
+	}{
+		{"no kubeconfig", "", true, ""},
+		{"kubeconfig fallback", kubeconfigPath, false, "https://127.0.0.1:6443"},
+		{"missing kubeconfig", filepath.Join(t.TempDir(), "missing"), true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadConfig(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && config.Host != tt.wantHost {
+				t.Errorf("loadConfig() host = %q, want %q", config.Host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestRunWithClientset(t *testing.T) {
+	received := make(chan telemetry.Data, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data telemetry.Data
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- data
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(telemetry.Response{})
+	}))
+	defer server.Close()
+
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+	t.Setenv("SECURITY_RESPONDER_MODE", "minimal")
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	data := <-received
+	if data.ExtraTagInfo["clusteruuid"] != "test-cluster-uuid" {
+		t.Errorf("clusteruuid = %q, want test-cluster-uuid", data.ExtraTagInfo["clusteruuid"])
+	}
+	if data.ExtraFieldInfo["mode"] != "minimal" {
+		t.Errorf("mode = %v, want minimal", data.ExtraFieldInfo["mode"])
+	}
+	if data.ExtraFieldInfo["dev"] != true {
+		t.Errorf("dev = %v, want true for version %q", data.ExtraFieldInfo["dev"], Version)
+	}
+}
+
+func TestRunWithClientset_NoUUID(t *testing.T) {
+	received := make(chan telemetry.Data, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data telemetry.Data
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- data
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(telemetry.Response{})
+	}))
+	defer server.Close()
+
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+	t.Setenv("SECURITY_RESPONDER_NO_UUID", "true")
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	data := <-received
+	if uuid, ok := data.ExtraTagInfo["clusteruuid"]; ok {
+		t.Errorf("clusteruuid = %q, want it omitted", uuid)
+	}
+	if identity, ok := data.ExtraTagInfo["clusterIdentity"]; ok {
+		t.Errorf("clusterIdentity = %q, want it omitted", identity)
+	}
+	if data.ExtraFieldInfo["anonymous"] != true {
+		t.Errorf("anonymous = %v, want true", data.ExtraFieldInfo["anonymous"])
+	}
+}
+
+func TestRunWithClientset_BucketCounts(t *testing.T) {
+	received := make(chan telemetry.Data, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data telemetry.Data
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- data
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(telemetry.Response{})
+	}))
+	defer server.Close()
+
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+	t.Setenv("SECURITY_RESPONDER_BUCKET_COUNTS", "true")
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "server-1", Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"}}},
+	)
+
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	data := <-received
+	if data.ExtraFieldInfo["serverNodeCount"] != "1" {
+		t.Errorf("serverNodeCount = %v, want \"1\"", data.ExtraFieldInfo["serverNodeCount"])
+	}
+	if data.ExtraFieldInfo["bucketed-counts"] != true {
+		t.Errorf("bucketed-counts = %v, want true", data.ExtraFieldInfo["bucketed-counts"])
+	}
+}
+
+func TestRunWithClientset_Tags(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected map[string]string
+	}{
+		{name: "valid tags", spec: "env=prod,region=eu-west", expected: map[string]string{"env": "prod", "region": "eu-west"}},
+		{name: "reserved key ignores all", spec: "env=prod,clusteruuid=spoofed", expected: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan telemetry.Data, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var data telemetry.Data
+				if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				received <- data
+				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(telemetry.Response{})
+			}))
+			defer server.Close()
+
+			t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+			t.Setenv("SECURITY_RESPONDER_TAGS", tt.spec)
+
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+			)
+
+			if err := runWithClientset(context.Background(), clientset); err != nil {
+				t.Fatalf("runWithClientset() error = %v", err)
+			}
+
+			data := <-received
+			if data.ExtraTagInfo["clusteruuid"] != "test-cluster-uuid" {
+				t.Errorf("clusteruuid = %q, want test-cluster-uuid", data.ExtraTagInfo["clusteruuid"])
+			}
+			for _, key := range []string{"env", "region"} {
+				if got, want := data.ExtraTagInfo[key], tt.expected[key]; got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWithClientset_CollectorFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		wantErr string
+	}{
+		{name: "unknown include", include: "cni,bogus", wantErr: `--collectors: unknown collector "bogus"`},
+		{name: "unknown exclude", exclude: "typo", wantErr: `--collectors-exclude: unknown collector "typo"`},
+		{name: "mandatory exclude", exclude: "nodes", wantErr: `--collectors-exclude: collector "nodes" is mandatory`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*collectors, *collectorsExclude = tt.include, tt.exclude
+			t.Cleanup(func() { *collectors, *collectorsExclude = "", "" })
+
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+			)
+			err := runWithClientset(context.Background(), clientset)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("runWithClientset() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunWithClientset_Acknowledgement(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantLog  string
+		wantLvl  logrus.Level
+	}{
+		{
+			name:     "dev acknowledged",
+			response: `{"versions":[],"acknowledged":{"dev":true}}`,
+			wantLog:  "endpoint acknowledged submission",
+			wantLvl:  logrus.InfoLevel,
+		},
+		{
+			name:     "classification mismatch",
+			response: `{"versions":[],"acknowledged":{"dev":false}}`,
+			wantLog:  "endpoint classified the submission differently than flagged",
+			wantLvl:  logrus.WarnLevel,
+		},
+		{
+			name:     "older endpoint without acknowledgement",
+			response: `{"versions":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+			t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+
+			hook := logtest.NewGlobal()
+			t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+			)
+			// Version is "dev" in tests, so the payload is flagged as dev
+			if err := runWithClientset(context.Background(), clientset); err != nil {
+				t.Fatalf("runWithClientset() error = %v", err)
+			}
+
+			var found *logrus.Entry
+			for _, entry := range hook.AllEntries() {
+				if _, ok := entry.Data["acknowledgedDev"]; ok {
+					found = entry
+				}
+			}
+			if tt.wantLog == "" {
+				if found != nil {
+					t.Errorf("unexpected acknowledgement log %q", found.Message)
+				}
+				return
+			}
+			if found == nil {
+				t.Fatalf("no acknowledgement log, want %q", tt.wantLog)
+			}
+			if found.Message != tt.wantLog || found.Level != tt.wantLvl {
+				t.Errorf("log = %s %q, want %s %q", found.Level, found.Message, tt.wantLvl, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestRunWithClientset_MinCollectorVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"versions":[],"minCollectorVersion":"v0.3.0"}`))
+	}))
+	defer server.Close()
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+
+	oldVersion := Version
+	Version = "v0.2.1"
+	t.Cleanup(func() { Version = oldVersion })
+
+	hook := logtest.NewGlobal()
+	t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["minCollectorVersion"] == "v0.3.0" && entry.Level == logrus.WarnLevel {
+			return
+		}
+	}
+	t.Error("no warning about the outdated collector")
+}
+
+func TestCheckCollectorVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		minimum  string
+		wantWarn bool
+	}{
+		{"older release", "v0.2.1", "v0.3.0", true},
+		{"same release", "v0.3.0", "v0.3.0", false},
+		{"commits after tag", "v0.3.0-5-gabcdef0", "v0.3.0", false},
+		{"newer release", "v0.4.0", "v0.3.0", false},
+		{"dev build", "dev", "v0.3.0", false},
+		{"no minimum", "v0.2.1", "", false},
+		{"invalid minimum", "v0.2.1", "latest", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := logtest.NewGlobal()
+			t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+			checkCollectorVersion(tt.current, tt.minimum)
+
+			warned := false
+			for _, entry := range hook.AllEntries() {
+				if entry.Level == logrus.WarnLevel {
+					warned = true
+				}
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v", warned, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestRunWithClientset_DebugRedactsUUIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		verbose    bool
+		wantMasked bool
+	}{
+		{"default", false, true},
+		{"verbose", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*debug, *verbose = true, tt.verbose
+			t.Cleanup(func() { *debug, *verbose = false, false })
+
+			hook := logtest.NewGlobal()
+			t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+			)
+			if err := runWithClientset(context.Background(), clientset); err != nil {
+				t.Fatalf("runWithClientset() error = %v", err)
+			}
+
+			var payload string
+			for _, entry := range hook.AllEntries() {
+				if p, ok := entry.Data["payload"].(string); ok {
+					payload = p
+				}
+			}
+			if payload == "" {
+				t.Fatal("no debug payload logged")
+			}
+			if masked := !strings.Contains(payload, "test-cluster-uuid"); masked != tt.wantMasked {
+				t.Errorf("uuid masked = %v, want %v:\n%s", masked, tt.wantMasked, payload)
+			}
+			if tt.wantMasked && !strings.Contains(payload, `"clusteruuid": "REDACTED"`) {
+				t.Errorf("payload missing redacted clusteruuid:\n%s", payload)
+			}
+		})
+	}
+}
+
+func TestRedactForLog(t *testing.T) {
+	data := &telemetry.Data{
+		ExtraTagInfo:   map[string]string{"clusteruuid": "uuid", "clusterIdentity": "uuid/install", "kubernetesVersion": "v1.32.2"},
+		ExtraFieldInfo: map[string]interface{}{"rancher-install-uuid": "install", "mode": "recommended"},
+	}
+
+	redacted := redactForLog(data)
+
+	if redacted.ExtraTagInfo["clusteruuid"] != "REDACTED" || redacted.ExtraTagInfo["clusterIdentity"] != "REDACTED" {
+		t.Errorf("tags = %v, want uuid and identity redacted", redacted.ExtraTagInfo)
+	}
+	if redacted.ExtraFieldInfo["rancher-install-uuid"] != "REDACTED" {
+		t.Errorf("rancher-install-uuid = %v, want REDACTED", redacted.ExtraFieldInfo["rancher-install-uuid"])
+	}
+	if redacted.ExtraTagInfo["kubernetesVersion"] != "v1.32.2" || redacted.ExtraFieldInfo["mode"] != "recommended" {
+		t.Error("non-sensitive keys were changed")
+	}
+	if data.ExtraTagInfo["clusteruuid"] != "uuid" || data.ExtraFieldInfo["rancher-install-uuid"] != "install" {
+		t.Error("redactForLog modified its input")
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	tests := []struct {
+		endpoint   string
+		wantTarget string
+		wantErr    bool
+	}{
+		{endpoint: "https://telemetry.example.com/v1/checkupgrade", wantTarget: "https://telemetry.example.com/v1/checkupgrade"},
+		{endpoint: "http://relay.local:8080", wantTarget: "http://relay.local:8080"},
+		{endpoint: "unix:///run/relay.sock", wantTarget: "unix:///run/relay.sock"},
+		{endpoint: "webhook+https://kafka-rest.example.com/topics/telemetry", wantTarget: "https://kafka-rest.example.com/topics/telemetry"},
+		{endpoint: "ftp://telemetry.example.com", wantErr: true},
+		{endpoint: "webhook+kafka://broker:9092", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			sender, err := newSender(tt.endpoint, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSender() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			httpSender, ok := sender.(*telemetry.HTTPSender)
+			if !ok {
+				t.Fatalf("newSender() = %T, want *telemetry.HTTPSender", sender)
+			}
+			if httpSender.Endpoint != tt.wantTarget {
+				t.Errorf("Endpoint = %q, want %q", httpSender.Endpoint, tt.wantTarget)
+			}
+			// Only webhook endpoints get the relay format option
+			wantOpts := 0
+			if strings.HasPrefix(tt.endpoint, webhookPrefix) {
+				wantOpts = 1
+			}
+			if len(httpSender.Options) != wantOpts {
+				t.Errorf("len(Options) = %d, want %d", len(httpSender.Options), wantOpts)
+			}
+		})
+	}
+}
+
+func TestRunWithClientset_CollectOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("--collect-only must not send")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+
+	var out bytes.Buffer
+	stdout = &out
+	*collectOnly = true
+	t.Cleanup(func() {
+		stdout = os.Stdout
+		*collectOnly = false
+	})
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	var data telemetry.Data
+	if err := json.Unmarshal(out.Bytes(), &data); err != nil {
+		t.Fatalf("stdout is not pure JSON: %v\n%s", err, out.String())
+	}
+	if data.ExtraTagInfo["clusteruuid"] != "test-cluster-uuid" {
+		t.Errorf("clusteruuid = %q, want test-cluster-uuid", data.ExtraTagInfo["clusteruuid"])
+	}
+}
+
+func TestRunWithClientset_CollectErrors(t *testing.T) {
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", "http://127.0.0.1:0")
+
+	t.Run("node list failure exits as collection failure", func(t *testing.T) {
+		clientset := fake.NewClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+		)
+		clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("connection refused")
+		})
+
+		err := runWithClientset(context.Background(), clientset)
+		if !errors.Is(err, telemetry.ErrNodeListFailed) || exitCode(err) != exitCollectFailed {
+			t.Errorf("runWithClientset() error = %v (exit %d), want ErrNodeListFailed with exit %d", err, exitCode(err), exitCollectFailed)
+		}
+	})
+
+	t.Run("workload list failure exits as collection failure", func(t *testing.T) {
+		clientset := fake.NewClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+		)
+		clientset.PrependReactor("list", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("connection refused")
+		})
+
+		err := runWithClientset(context.Background(), clientset)
+		if !errors.Is(err, telemetry.ErrWorkloadListFailed) || exitCode(err) != exitCollectFailed {
+			t.Errorf("runWithClientset() error = %v (exit %d), want ErrWorkloadListFailed with exit %d", err, exitCode(err), exitCollectFailed)
+		}
+	})
+
+	t.Run("missing kube-system is fatal", func(t *testing.T) {
+		err := runWithClientset(context.Background(), fake.NewClientset())
+		if !errors.Is(err, telemetry.ErrMissingKubeSystem) {
+			t.Errorf("runWithClientset() error = %v, want ErrMissingKubeSystem", err)
+		}
+	})
+}
+
+func TestRunWithClientset_DryRunSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("the real endpoint was contacted")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+
+	var out bytes.Buffer
+	stdout = &out
+	*dryRunSend = true
+	t.Cleanup(func() {
+		stdout = os.Stdout
+		*dryRunSend = false
+	})
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("runWithClientset() error = %v", err)
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(&out))
+	if err != nil {
+		t.Fatalf("stdout is not an HTTP request: %v\n%s", err, out.String())
+	}
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	if req.Header.Get(telemetry.IdempotencyKeyHeader) == "" {
+		t.Errorf("%s header missing", telemetry.IdempotencyKeyHeader)
+	}
+	var data telemetry.Data
+	if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+		t.Fatalf("decode echoed body: %v", err)
+	}
+	if data.ExtraTagInfo["clusteruuid"] != "test-cluster-uuid" {
+		t.Errorf("clusteruuid = %q, want test-cluster-uuid", data.ExtraTagInfo["clusteruuid"])
+	}
+}
+
+func TestCountRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	counter := &atomic.Int64{}
+	client := &http.Client{Transport: countRequests(counter)(http.DefaultTransport)}
+	for range 3 {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	if got := counter.Load(); got != 3 {
+		t.Errorf("counter = %d, want 3", got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sendThrottle caps how often runs sharing a file send, e.g. every node of a
+// fleet behind one egress NAT with the file on a shared volume. The file holds
+// the time of the last send and is locked while it is read and updated, so
+// runs starting together cannot both claim the slot.
+type sendThrottle struct {
+	path     string
+	interval time.Duration
+	now      func() time.Time
+}
+
+func newSendThrottle(path string, interval time.Duration) *sendThrottle {
+	return &sendThrottle{path: path, interval: interval, now: time.Now}
+}
+
+// acquire reports whether this run may send, and if so records the send
+// before returning. When it may not, next is when the next send is allowed.
+// An unreadable timestamp counts as no previous send.
+func (t *sendThrottle) acquire() (ok bool, next time.Time, err error) {
+	f, err := os.OpenFile(t.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("open rate limit file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return false, time.Time{}, fmt.Errorf("lock rate limit file %s: %w", t.path, err)
+	}
+	defer func() { _ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN) }()
+
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("read rate limit file %s: %w", t.path, err)
+	}
+	now := t.now()
+	if last, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(raw))); err == nil {
+		if next := last.Add(t.interval); now.Before(next) {
+			return false, next, nil
+		}
+	} else if len(raw) > 0 {
+		logrus.WithError(err).WithField("path", t.path).Warn("invalid rate limit timestamp, treating as no previous send")
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return false, time.Time{}, fmt.Errorf("write rate limit file %s: %w", t.path, err)
+	}
+	if _, err := f.WriteAt([]byte(now.UTC().Format(time.RFC3339Nano)+"\n"), 0); err != nil {
+		return false, time.Time{}, fmt.Errorf("write rate limit file %s: %w", t.path, err)
+	}
+	return true, time.Time{}, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSendThrottle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate-limit")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	acquire := func() (bool, time.Time) {
+		t.Helper()
+		throttle := newSendThrottle(path, time.Hour)
+		throttle.now = func() time.Time { return now }
+		ok, next, err := throttle.acquire()
+		if err != nil {
+			t.Fatalf("acquire() error = %v", err)
+		}
+		return ok, next
+	}
+
+	if ok, _ := acquire(); !ok {
+		t.Fatal("first send should be allowed")
+	}
+
+	// Another run within the interval is throttled until the interval passes
+	now = now.Add(30 * time.Minute)
+	ok, next := acquire()
+	if ok {
+		t.Fatal("send within the interval should be throttled")
+	}
+	if want := now.Add(30 * time.Minute); !next.Equal(want) {
+		t.Errorf("next = %v, want %v", next, want)
+	}
+
+	// A throttled run does not move the slot
+	now = now.Add(30 * time.Minute)
+	if ok, _ := acquire(); !ok {
+		t.Fatal("send after the interval should be allowed")
+	}
+	if ok, _ := acquire(); ok {
+		t.Fatal("send right after an allowed send should be throttled")
+	}
+}
+
+func TestSendThrottle_InvalidTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate-limit")
+	if err := os.WriteFile(path, []byte("not a time"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	ok, _, err := newSendThrottle(path, time.Hour).acquire()
+	if err != nil || !ok {
+		t.Errorf("acquire() = %v, %v, want allowed", ok, err)
+	}
+}
+
+func TestRunWithClientset_RateLimited(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	t.Setenv("SECURITY_RESPONDER_ENDPOINT", server.URL)
+	t.Setenv("SECURITY_RESPONDER_RATE_LIMIT_FILE", filepath.Join(t.TempDir(), "rate-limit"))
+	*requireSend = true
+	t.Cleanup(func() { *requireSend = false })
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "test-cluster-uuid"}},
+	)
+	if err := runWithClientset(context.Background(), clientset); err != nil {
+		t.Fatalf("first runWithClientset() error = %v", err)
+	}
+
+	err := runWithClientset(context.Background(), clientset)
+	if exitCode(err) != exitSendFailed {
+		t.Errorf("second run exit code = %d, want %d (err %v)", exitCode(err), exitSendFailed, err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("endpoint got %d requests, want 1", got)
+	}
+}
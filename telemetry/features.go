@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"slices"
+	"strings"
+)
+
+// featureRule derives one entry of the "features" rollup from the collected
+// fields. A field left out because its collector was skipped, timed out or
+// found nothing counts as the feature being off.
+type featureRule struct {
+	name    string
+	enabled func(fields map[string]interface{}) bool
+}
+
+// featureRules are the high-level features listed in the "features" field, so
+// the endpoint can apply per-feature rules without re-deriving them from raw
+// fields. Adding a feature only takes a rule here.
+var featureRules = []featureRule{
+	{"apf", fieldIn("apf-enabled", true)},
+	{"cni-encryption", fieldIn("cni-encryption", "wireguard", "ipsec")},
+	{"etcd-s3-snapshots", fieldIn("etcd-s3-snapshots", true)},
+	{"etcd-tls", fieldIn("etcd-tls", "enabled")},
+	{"external-auth", fieldDetected("external-auth")},
+	{"fips", fieldIn("fips-mode", "fips")},
+	{"ingress-waf", fieldIn("ingress-waf", "modsecurity")},
+	{"mesh-mtls", fieldIn("mesh-mtls", "strict", "enabled")},
+	{"mtls-strict", fieldIn("mesh-mtls", "strict")},
+	{"secret-manager", fieldDetected("secret-manager")},
+	{"selinux", fieldIn("selinux", "enabled")},
+	{"vap", fieldPositive("vap-binding-count")},
+	{"workload-identity", fieldDetected("workload-identity")},
+}
+
+// featureRollup returns the enabled features among featureRules as a sorted,
+// comma-separated list.
+func featureRollup(fields map[string]interface{}) string {
+	var enabled []string
+	for _, rule := range featureRules {
+		if rule.enabled(fields) {
+			enabled = append(enabled, rule.name)
+		}
+	}
+	slices.Sort(enabled)
+	return strings.Join(enabled, ",")
+}
+
+// fieldIn matches when the field equals one of values.
+func fieldIn(key string, values ...interface{}) func(map[string]interface{}) bool {
+	return func(fields map[string]interface{}) bool {
+		value, ok := fields[key]
+		return ok && slices.Contains(values, value)
+	}
+}
+
+// fieldDetected matches a detector field naming what it found, as opposed to
+// "none" or "unknown".
+func fieldDetected(key string) func(map[string]interface{}) bool {
+	return func(fields map[string]interface{}) bool {
+		value, _ := fields[key].(string)
+		return value != "" && value != "none" && value != "unknown"
+	}
+}
+
+// fieldPositive matches a count above zero. The -1 sentinel of minimal mode
+// and failed lookups does not match.
+func fieldPositive(key string) func(map[string]interface{}) bool {
+	return func(fields map[string]interface{}) bool {
+		count, _ := fields[key].(int)
+		return count > 0
+	}
+}
@@ -0,0 +1,86 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFeatureRollup(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string]interface{}
+		expected string
+	}{
+		{
+			name: "hardened cluster",
+			fields: map[string]interface{}{
+				"selinux":           "enabled",
+				"mesh-mtls":         "strict",
+				"fips-mode":         "fips",
+				"cni-encryption":    "wireguard",
+				"vap-binding-count": 2,
+				"workload-identity": "spire",
+			},
+			expected: "cni-encryption,fips,mesh-mtls,mtls-strict,selinux,vap,workload-identity",
+		},
+		{
+			name: "nothing enabled",
+			fields: map[string]interface{}{
+				"selinux":           "disabled",
+				"mesh-mtls":         "permissive",
+				"fips-mode":         "standard",
+				"cni-encryption":    "none",
+				"vap-binding-count": 0,
+				"workload-identity": "none",
+				"external-auth":     "unknown",
+			},
+			expected: "",
+		},
+		{
+			name:     "minimal mode counts",
+			fields:   map[string]interface{}{"vap-binding-count": -1, "apf-enabled": true},
+			expected: "apf",
+		},
+		{
+			name:     "no fields",
+			fields:   map[string]interface{}{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := featureRollup(tt.fields); got != tt.expected {
+				t.Errorf("featureRollup() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_Features(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "server-1",
+			Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true", "security.alpha.kubernetes.io/selinux": "enabled"},
+		}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "linkerd-destination", Namespace: "linkerd"}},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["selinux"] != "enabled" || data.ExtraFieldInfo["mesh-mtls"] != "enabled" {
+		t.Fatalf("selinux = %v, mesh-mtls = %v, want both enabled", data.ExtraFieldInfo["selinux"], data.ExtraFieldInfo["mesh-mtls"])
+	}
+	if got := data.ExtraFieldInfo["features"]; got != "mesh-mtls,selinux" {
+		t.Errorf("features = %v, want mesh-mtls,selinux", got)
+	}
+}
@@ -0,0 +1,175 @@
+package telemetry
+
+import (
+	"net/netip"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// NodeSummary holds every field Collect derives from the node list.
+type NodeSummary struct {
+	ServerNodeCount, AgentNodeCount, GPUNodeCount  int
+	ServerCPU, AgentCPU, ServerMemory, AgentMemory int64
+	// OperatingSystem, OSImage, KernelVersion and Arch come from the first node.
+	OperatingSystem, OSImage, KernelVersion, Arch string
+	// NodeInfoConsistent is false when any node differs from the first one
+	// in the fields above.
+	NodeInfoConsistent bool
+	// SELinux is the status of the first node.
+	SELinux       string
+	GPUVendor     string
+	CgroupVersion string
+	// InvalidSkew is set when any kubelet is newer than the apiserver.
+	InvalidSkew bool
+	ZoneCount   int
+	// ControlPlaneMultizone and ControlPlaneIsolated are false without
+	// control plane nodes.
+	ControlPlaneMultizone bool
+	ControlPlaneIsolated  bool
+	// HostedControlPlane is set when agents are visible but no control plane
+	// node is, so the control plane runs outside the cluster.
+	HostedControlPlane        bool
+	EOLOSNodeCount            int
+	VulnerableKernelNodeCount int
+	// KernelRisk is kernelRiskVulnerable if any node is, kernelRiskOK if every
+	// node is judged ok, and kernelRiskUnknown otherwise or without nodes.
+	KernelRisk string
+	// IPFamily is the nodeIPFamily of the nodes' InternalIPs.
+	IPFamily string
+	PodCIDRs []string
+}
+
+// detectNodeSummary summarizes nodes. apiserverVersion may be nil, which
+// disables the kubelet skew check.
+func detectNodeSummary(nodes []corev1.Node, apiserverVersion *version.Version, gpuResources []GPUResource) NodeSummary {
+	builder := newNodeSummaryBuilder(apiserverVersion, gpuResources)
+	for i := range nodes {
+		builder.add(&nodes[i])
+	}
+	return builder.summary()
+}
+
+// nodeSummaryBuilder accumulates a NodeSummary one node at a time, so Collect
+// can summarize paged node lists without holding every page.
+type nodeSummaryBuilder struct {
+	apiserverVersion *version.Version
+	gpuResources     []GPUResource
+
+	s                      NodeSummary
+	controlPlaneIsolated   bool
+	unknownKernelNodeCount int
+	hasIPv4, hasIPv6       bool
+	zones                  map[string]bool
+	controlPlaneZones      map[string]bool
+	cgroupVersions         map[string]bool
+}
+
+func newNodeSummaryBuilder(apiserverVersion *version.Version, gpuResources []GPUResource) *nodeSummaryBuilder {
+	return &nodeSummaryBuilder{
+		apiserverVersion:     apiserverVersion,
+		gpuResources:         gpuResources,
+		s:                    NodeSummary{NodeInfoConsistent: true},
+		controlPlaneIsolated: true,
+		zones:                map[string]bool{},
+		controlPlaneZones:    map[string]bool{},
+		cgroupVersions:       map[string]bool{},
+	}
+}
+
+func (b *nodeSummaryBuilder) add(node *corev1.Node) {
+	s := &b.s
+	cpu := node.Status.Allocatable.Cpu().MilliValue()
+	mem := node.Status.Allocatable.Memory().Value()
+	zone := node.Labels[corev1.LabelTopologyZone]
+	if zone != "" {
+		b.zones[zone] = true
+	}
+	if isControlPlaneNode(node) {
+		s.ServerNodeCount++
+		s.ServerCPU += cpu
+		s.ServerMemory += mem
+		if zone != "" {
+			b.controlPlaneZones[zone] = true
+		}
+		if !hasIsolationTaint(node) {
+			b.controlPlaneIsolated = false
+		}
+	} else {
+		s.AgentNodeCount++
+		s.AgentCPU += cpu
+		s.AgentMemory += mem
+	}
+	info := node.Status.NodeInfo
+	if s.OSImage == "" {
+		s.OperatingSystem = info.OperatingSystem
+		s.OSImage = info.OSImage
+		s.KernelVersion = info.KernelVersion
+		s.Arch = info.Architecture
+	} else if info.OperatingSystem != s.OperatingSystem ||
+		info.OSImage != s.OSImage ||
+		info.KernelVersion != s.KernelVersion ||
+		info.Architecture != s.Arch {
+		s.NodeInfoConsistent = false
+	}
+	if isEOLOSImage(info.OSImage) {
+		s.EOLOSNodeCount++
+	}
+	switch kernelRisk(info.KernelVersion) {
+	case kernelRiskVulnerable:
+		s.VulnerableKernelNodeCount++
+	case kernelRiskUnknown:
+		b.unknownKernelNodeCount++
+	}
+	s.PodCIDRs = append(s.PodCIDRs, node.Spec.PodCIDRs...)
+	for _, addr := range node.Status.Addresses {
+		if addr.Type != corev1.NodeInternalIP {
+			continue
+		}
+		if ip, err := netip.ParseAddr(addr.Address); err == nil {
+			if ip.Unmap().Is4() {
+				b.hasIPv4 = true
+			} else {
+				b.hasIPv6 = true
+			}
+		}
+	}
+	if cgroup := nodeCgroupVersion(node); cgroup != "" {
+		b.cgroupVersions[cgroup] = true
+	}
+	if isKubeletAhead(b.apiserverVersion, info.KubeletVersion) {
+		s.InvalidSkew = true
+	}
+	if s.SELinux == "" {
+		s.SELinux = getSELinuxStatus(node)
+	}
+	for _, res := range b.gpuResources {
+		if qty, ok := node.Status.Allocatable[res.Name]; ok {
+			if count, _ := qty.AsInt64(); count > 0 {
+				s.GPUNodeCount++
+				if s.GPUVendor == "" {
+					s.GPUVendor = res.Vendor
+				}
+				break
+			}
+		}
+	}
+}
+
+func (b *nodeSummaryBuilder) summary() NodeSummary {
+	s := b.s
+	s.ZoneCount = len(b.zones)
+	s.ControlPlaneMultizone = len(b.controlPlaneZones) > 1
+	s.ControlPlaneIsolated = b.controlPlaneIsolated && s.ServerNodeCount > 0
+	s.HostedControlPlane = s.ServerNodeCount == 0 && s.AgentNodeCount > 0
+	s.CgroupVersion = cgroupVersion(b.cgroupVersions)
+	s.IPFamily = nodeIPFamily(b.hasIPv4, b.hasIPv6)
+	// One unjudged node keeps the cluster unknown unless another is known vulnerable
+	s.KernelRisk = kernelRiskUnknown
+	if s.VulnerableKernelNodeCount > 0 {
+		s.KernelRisk = kernelRiskVulnerable
+	} else if b.unknownKernelNodeCount == 0 && s.ServerNodeCount+s.AgentNodeCount > 0 {
+		s.KernelRisk = kernelRiskOK
+	}
+	return s
+}
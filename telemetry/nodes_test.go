@@ -0,0 +1,113 @@
+package telemetry
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestDetectNodeSummary(t *testing.T) {
+	node := func(name string, controlPlane bool, zone, kernel string) corev1.Node {
+		n := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+				NodeInfo: corev1.NodeSystemInfo{
+					OperatingSystem: "linux",
+					OSImage:         "SLE Micro 6.1",
+					KernelVersion:   kernel,
+					Architecture:    "amd64",
+					KubeletVersion:  "v1.32.2+rke2r1",
+				},
+			},
+		}
+		if controlPlane {
+			n.Labels["node-role.kubernetes.io/control-plane"] = "true"
+			n.Spec.Taints = []corev1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}}
+		}
+		if zone != "" {
+			n.Labels[corev1.LabelTopologyZone] = zone
+		}
+		return n
+	}
+	apiserver := version.MustParseSemantic("v1.32.2+rke2r1")
+
+	t.Run("mixed cluster", func(t *testing.T) {
+		gpu := node("gpu-1", false, "zone-a", "6.8.0")
+		gpu.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("2")
+		gpu.Status.NodeInfo.Architecture = "arm64"
+		gpu.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "fd00::10"}}
+		gpu.Spec.PodCIDRs = []string{"10.42.2.0/24"}
+		server := node("server-1", true, "zone-a", "6.8.0")
+		server.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}
+		server.Spec.PodCIDRs = []string{"10.42.0.0/24"}
+		nodes := []corev1.Node{server, node("server-2", true, "zone-b", "6.8.0"), gpu}
+
+		got := detectNodeSummary(nodes, apiserver, DefaultGPUResources)
+
+		if got.ServerNodeCount != 2 || got.AgentNodeCount != 1 || got.GPUNodeCount != 1 {
+			t.Errorf("counts = %d/%d/%d, want 2/1/1", got.ServerNodeCount, got.AgentNodeCount, got.GPUNodeCount)
+		}
+		if got.ServerCPU != 8000 || got.AgentCPU != 4000 {
+			t.Errorf("CPU = %d/%d, want 8000/4000", got.ServerCPU, got.AgentCPU)
+		}
+		if got.GPUVendor != "nvidia" {
+			t.Errorf("GPUVendor = %q, want nvidia", got.GPUVendor)
+		}
+		if got.Arch != "amd64" || got.NodeInfoConsistent {
+			t.Errorf("Arch = %q, NodeInfoConsistent = %v, want amd64 and false", got.Arch, got.NodeInfoConsistent)
+		}
+		if got.ZoneCount != 2 || !got.ControlPlaneMultizone || !got.ControlPlaneIsolated {
+			t.Errorf("ZoneCount = %d, multizone = %v, isolated = %v, want 2, true, true", got.ZoneCount, got.ControlPlaneMultizone, got.ControlPlaneIsolated)
+		}
+		if got.HostedControlPlane {
+			t.Error("HostedControlPlane = true, want false")
+		}
+		if got.KernelRisk != kernelRiskOK {
+			t.Errorf("KernelRisk = %q, want %q", got.KernelRisk, kernelRiskOK)
+		}
+		if got.IPFamily != "dual" {
+			t.Errorf("IPFamily = %q, want dual", got.IPFamily)
+		}
+		if want := []string{"10.42.0.0/24", "10.42.2.0/24"}; !slices.Equal(got.PodCIDRs, want) {
+			t.Errorf("PodCIDRs = %v, want %v", got.PodCIDRs, want)
+		}
+	})
+
+	t.Run("hosted control plane", func(t *testing.T) {
+		got := detectNodeSummary([]corev1.Node{node("agent-1", false, "", "6.8.0")}, apiserver, nil)
+
+		if !got.HostedControlPlane || got.ControlPlaneIsolated {
+			t.Errorf("hosted = %v, isolated = %v, want true, false", got.HostedControlPlane, got.ControlPlaneIsolated)
+		}
+	})
+
+	t.Run("kubelet ahead and vulnerable kernel", func(t *testing.T) {
+		ahead := node("agent-1", false, "", "5.10.50")
+		ahead.Status.NodeInfo.KubeletVersion = "v1.33.0+rke2r1"
+
+		got := detectNodeSummary([]corev1.Node{ahead, node("agent-2", false, "", "5.14.21-150500.55.39-default")}, apiserver, nil)
+
+		if !got.InvalidSkew {
+			t.Error("InvalidSkew = false, want true")
+		}
+		if got.VulnerableKernelNodeCount != 1 || got.KernelRisk != kernelRiskVulnerable {
+			t.Errorf("vulnerable = %d, KernelRisk = %q, want 1, %q", got.VulnerableKernelNodeCount, got.KernelRisk, kernelRiskVulnerable)
+		}
+	})
+
+	t.Run("no nodes", func(t *testing.T) {
+		got := detectNodeSummary(nil, nil, nil)
+
+		if got.KernelRisk != kernelRiskUnknown || got.IPFamily != "unknown" || got.HostedControlPlane || !got.NodeInfoConsistent {
+			t.Errorf("summary = %+v, want unknown kernel risk and IP family, not hosted, consistent", got)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// essentialFields are never dropped when trimming a payload to fit a size limit.
+var essentialFields = map[string]bool{
+	"mode":                true,
+	"dev":                 true,
+	"anonymous":           true,
+	"bucketed-counts":     true,
+	"truncated":           true,
+	"dropped-field-count": true,
+	"serverNodeCount":     true,
+	"agentNodeCount":      true,
+	"gpuNodeCount":        true,
+	"serverCPU":           true,
+	"agentCPU":            true,
+	"serverMemory":        true,
+	"agentMemory":         true,
+}
+
+// dropFirstFields are the least essential fields, dropped in this order before
+// any other non-essential field.
+var dropFirstFields = []string{
+	"nondefault-apis",
+	"cni-detected",
+	"rancher-install-uuid",
+	"rancher-webhook-version",
+	"kubernetes-dashboard-version",
+	"monitoring-stack-version",
+	"secret-manager-version",
+	"virtualization-version",
+	"volcano-version",
+	"yunikorn-version",
+	"snapshot-controller-version",
+	"gpu-operator-version",
+	"ingress-version",
+	"cni-version",
+}
+
+// Transformer modifies the payload before it is marshaled, e.g. to redact or
+// enrich fields without forking the collector.
+type Transformer func(*Data)
+
+// RedactUUID removes the cluster UUID and the identity derived from it, and
+// marks the payload anonymous so the endpoint knows it cannot deduplicate runs.
+func RedactUUID(data *Data) {
+	delete(data.ExtraTagInfo, "clusteruuid")
+	delete(data.ExtraTagInfo, "clusterIdentity")
+	if data.ExtraFieldInfo == nil {
+		data.ExtraFieldInfo = make(map[string]interface{})
+	}
+	data.ExtraFieldInfo["anonymous"] = true
+}
+
+// bucketedFields are the ExtraFieldInfo keys whose exact values reveal the
+// size of a cluster, mapped to the unit the value is divided by before it is
+// bucketed: CPU is collected in millicores and memory in bytes, so they are
+// bucketed as cores and GiB. The CIDR capacities are left out since the CIDRs
+// themselves are sent.
+var bucketedFields = map[string]int64{
+	"serverNodeCount":                 1,
+	"agentNodeCount":                  1,
+	"gpuNodeCount":                    1,
+	"serverCPU":                       1000,
+	"agentCPU":                        1000,
+	"serverMemory":                    1 << 30,
+	"agentMemory":                     1 << 30,
+	"zone-count":                      1,
+	"eol-os-node-count":               1,
+	"vulnerable-kernel-node-count":    1,
+	"namespace-count":                 1,
+	"namespaces-with-quota":           1,
+	"namespaces-with-limitrange":      1,
+	"ingress-count":                   1,
+	"ingress-with-tls":                1,
+	"pdb-count":                       1,
+	"priorityclass-count":             1,
+	"custom-priorityclass-count":      1,
+	"flowschema-count":                1,
+	"vap-count":                       1,
+	"vap-binding-count":               1,
+	"cluster-admin-subject-count":     1,
+	"default-sa-overprivileged-count": 1,
+	"hostprocess-pod-count":           1,
+	"hostport-pod-count":              1,
+	"hostipc-pod-count":               1,
+	"unsafe-sysctl-pod-count":         1,
+	"root-pod-count":                  1,
+	"privileged-init-pod-count":       1,
+	"evicted-pod-count":               1,
+	"cis-pass-count":                  1,
+	"cis-fail-count":                  1,
+	"api-group-count":                 1,
+	"api-calls":                       1,
+	"rbac-denied-count":               1,
+}
+
+// BucketCounts replaces the exact values of bucketedFields with the range they
+// fall in, such as "6-20", so node, pod and capacity numbers cannot fingerprint
+// a cluster. The -1 sentinel is left as is. The payload is marked
+// "bucketed-counts".
+func BucketCounts(data *Data) {
+	if data.ExtraFieldInfo == nil {
+		data.ExtraFieldInfo = make(map[string]interface{})
+	}
+	for key, unit := range bucketedFields {
+		var n int64
+		switch value := data.ExtraFieldInfo[key].(type) {
+		case int:
+			n = int64(value)
+		case int64:
+			n = value
+		default:
+			continue
+		}
+		if n < 0 {
+			continue
+		}
+		data.ExtraFieldInfo[key] = bucketCount(n / unit)
+	}
+	data.ExtraFieldInfo["bucketed-counts"] = true
+}
+
+// bucketCount returns the range n falls in: "0", "1", "2-5", "6-20", "21-100"
+// or "100+".
+func bucketCount(n int64) string {
+	switch {
+	case n <= 1:
+		return strconv.FormatInt(n, 10)
+	case n <= 5:
+		return "2-5"
+	case n <= 20:
+		return "6-20"
+	case n <= 100:
+		return "21-100"
+	default:
+		return "100+"
+	}
+}
+
+// ApplyTransformers runs transformers on data in order.
+func ApplyTransformers(data *Data, transformers ...Transformer) {
+	for _, transform := range transformers {
+		transform(data)
+	}
+}
+
+// fitPayload marshals data and, if the result exceeds maxBytes, drops
+// non-essential ExtraFieldInfo keys until it fits. Trimmed payloads are marked
+// with "truncated" and "dropped-field-count". data itself is never modified.
+// A maxBytes of zero or less disables the limit. If the payload still does not
+// fit once every non-essential field is gone, the smallest payload is returned.
+func fitPayload(data *Data, maxBytes int) ([]byte, int, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	if maxBytes <= 0 || len(jsonData) <= maxBytes {
+		return jsonData, 0, nil
+	}
+
+	trimmed := *data
+	trimmed.ExtraFieldInfo = make(map[string]interface{}, len(data.ExtraFieldInfo)+2)
+	for k, v := range data.ExtraFieldInfo {
+		trimmed.ExtraFieldInfo[k] = v
+	}
+	trimmed.ExtraFieldInfo["truncated"] = true
+
+	dropped := 0
+	for _, key := range dropCandidates(trimmed.ExtraFieldInfo) {
+		delete(trimmed.ExtraFieldInfo, key)
+		dropped++
+		trimmed.ExtraFieldInfo["dropped-field-count"] = dropped
+		jsonData, err = json.Marshal(&trimmed)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to marshal data: %w", err)
+		}
+		if len(jsonData) <= maxBytes {
+			break
+		}
+	}
+	return jsonData, dropped, nil
+}
+
+// dropCandidates orders the non-essential keys of fields for dropping:
+// dropFirstFields first, then the remaining keys alphabetically.
+func dropCandidates(fields map[string]interface{}) []string {
+	var candidates []string
+	listed := make(map[string]bool, len(dropFirstFields))
+	for _, key := range dropFirstFields {
+		listed[key] = true
+		if _, ok := fields[key]; ok {
+			candidates = append(candidates, key)
+		}
+	}
+	var rest []string
+	for key := range fields {
+		if !essentialFields[key] && !listed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(candidates, rest...)
+}
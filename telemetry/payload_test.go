@@ -0,0 +1,252 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testPayload() *Data {
+	return &Data{
+		AppVersion:   "v1.32.2+rke2r1",
+		ExtraTagInfo: map[string]string{"clusteruuid": "53741f60-f208-48fc-ae81-8a969510a598", "kubernetesVersion": "v1.32.2+rke2r1"},
+		ExtraFieldInfo: map[string]interface{}{
+			"mode":            "recommended",
+			"serverNodeCount": 3,
+			"agentNodeCount":  2,
+			"cni-plugin":      "canal",
+			"cni-version":     "v3.26.0",
+			"nondefault-apis": "resource.k8s.io/v1beta1,storage.k8s.io/v1beta1",
+			"os":              "SLE Micro 6.1",
+			"kernel":          "6.4.0-150600.23.47-default",
+		},
+	}
+}
+
+func TestFitPayload(t *testing.T) {
+	full, err := json.Marshal(testPayload())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		maxBytes      int
+		wantDropped   int
+		wantTruncated bool
+		wantAbsent    []string
+		wantPresent   []string
+	}{
+		{"no limit", 0, 0, false, nil, []string{"nondefault-apis", "cni-version"}},
+		{"fits", len(full), 0, false, nil, []string{"nondefault-apis", "cni-version"}},
+		{"drop least essential first", len(full) - 20, 1, true, []string{"nondefault-apis"}, []string{"cni-version", "cni-plugin"}},
+		{"keep essentials", 1, 5, true, []string{"nondefault-apis", "cni-version", "cni-plugin", "kernel", "os"}, []string{"mode", "serverNodeCount", "agentNodeCount"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testPayload()
+			jsonData, dropped, err := fitPayload(data, tt.maxBytes)
+			if err != nil {
+				t.Fatalf("fitPayload() error = %v", err)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+			if tt.maxBytes > 1 && len(jsonData) > tt.maxBytes {
+				t.Errorf("payload size = %d, want <= %d", len(jsonData), tt.maxBytes)
+			}
+
+			var got Data
+			if err := json.Unmarshal(jsonData, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got.ExtraTagInfo["clusteruuid"] != "53741f60-f208-48fc-ae81-8a969510a598" {
+				t.Errorf("clusteruuid = %q, want it preserved", got.ExtraTagInfo["clusteruuid"])
+			}
+			if (got.ExtraFieldInfo["truncated"] == true) != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", got.ExtraFieldInfo["truncated"], tt.wantTruncated)
+			}
+			if tt.wantTruncated && got.ExtraFieldInfo["dropped-field-count"] != float64(tt.wantDropped) {
+				t.Errorf("dropped-field-count = %v, want %d", got.ExtraFieldInfo["dropped-field-count"], tt.wantDropped)
+			}
+			for _, key := range tt.wantAbsent {
+				if _, ok := got.ExtraFieldInfo[key]; ok {
+					t.Errorf("%s present, want dropped", key)
+				}
+			}
+			for _, key := range tt.wantPresent {
+				if _, ok := got.ExtraFieldInfo[key]; !ok {
+					t.Errorf("%s dropped, want present", key)
+				}
+			}
+
+			if len(data.ExtraFieldInfo) != len(testPayload().ExtraFieldInfo) {
+				t.Error("fitPayload() modified the input data")
+			}
+		})
+	}
+}
+
+func TestPayloadDeterministic(t *testing.T) {
+	first := testPayload()
+
+	// Same contents, different insertion order
+	second := &Data{AppVersion: first.AppVersion, ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+	tagKeys := slices.Sorted(maps.Keys(first.ExtraTagInfo))
+	for _, k := range slices.Backward(tagKeys) {
+		second.ExtraTagInfo[k] = first.ExtraTagInfo[k]
+	}
+	fieldKeys := slices.Sorted(maps.Keys(first.ExtraFieldInfo))
+	for _, k := range slices.Backward(fieldKeys) {
+		second.ExtraFieldInfo[k] = first.ExtraFieldInfo[k]
+	}
+
+	want, err := json.Marshal(first)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for range 10 {
+		got, err := json.Marshal(second)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("Marshal() is not deterministic:\n got %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestRedactUUID(t *testing.T) {
+	data := &Data{
+		ExtraTagInfo: map[string]string{
+			"kubernetesVersion": "v1.32.2",
+			"clusteruuid":       "uuid",
+			"clusterIdentity":   "uuid/install",
+		},
+	}
+
+	RedactUUID(data)
+
+	if want := map[string]string{"kubernetesVersion": "v1.32.2"}; !maps.Equal(data.ExtraTagInfo, want) {
+		t.Errorf("ExtraTagInfo = %v, want %v", data.ExtraTagInfo, want)
+	}
+	if data.ExtraFieldInfo["anonymous"] != true {
+		t.Errorf("anonymous = %v, want true", data.ExtraFieldInfo["anonymous"])
+	}
+}
+
+func TestBucketCount(t *testing.T) {
+	tests := map[int64]string{
+		0:    "0",
+		1:    "1",
+		2:    "2-5",
+		5:    "2-5",
+		6:    "6-20",
+		20:   "6-20",
+		21:   "21-100",
+		100:  "21-100",
+		101:  "100+",
+		5000: "100+",
+	}
+
+	for n, expected := range tests {
+		if got := bucketCount(n); got != expected {
+			t.Errorf("bucketCount(%d) = %q, want %q", n, got, expected)
+		}
+	}
+}
+
+func TestBucketCounts(t *testing.T) {
+	data := &Data{
+		ExtraFieldInfo: map[string]interface{}{
+			"serverNodeCount":         3,
+			"agentNodeCount":          250,
+			"hostprocess-pod-count":   0,
+			"root-pod-count":          -1,
+			"ingress-with-tls":        7,
+			"serverCPU":               int64(12000),
+			"agentMemory":             int64(64 << 30),
+			"agentCPU":                int64(-1),
+			"encryption-key-age-days": 42,
+			"cni-plugin":              "canal",
+		},
+	}
+
+	BucketCounts(data)
+
+	want := map[string]interface{}{
+		"serverNodeCount":         "2-5",
+		"agentNodeCount":          "100+",
+		"hostprocess-pod-count":   "0",
+		"root-pod-count":          -1,
+		"ingress-with-tls":        "6-20",
+		"serverCPU":               "6-20",
+		"agentMemory":             "21-100",
+		"agentCPU":                int64(-1),
+		"encryption-key-age-days": 42,
+		"cni-plugin":              "canal",
+		"bucketed-counts":         true,
+	}
+	if !maps.Equal(data.ExtraFieldInfo, want) {
+		t.Errorf("ExtraFieldInfo = %v, want %v", data.ExtraFieldInfo, want)
+	}
+}
+
+func TestBucketCounts_CollectedPayload(t *testing.T) {
+	// Numeric fields that do not reveal the size of the cluster
+	exact := map[string]bool{
+		"pod-cidr-capacity":       true,
+		"service-cidr-capacity":   true,
+		"collect-duration-ms":     true,
+		"encryption-key-age-days": true,
+	}
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "server-1", Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"}},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+			}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent-1"},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("8"),
+				corev1.ResourceMemory: resource.MustParse("32Gi"),
+			}},
+		},
+	)
+	data, err := Collect(context.Background(), clientset, "recommended", WithAPICallCounter(&atomic.Int64{}))
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	BucketCounts(data)
+
+	for key, value := range data.ExtraFieldInfo {
+		var n int64
+		switch v := value.(type) {
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		default:
+			continue
+		}
+		if n != -1 && !exact[key] {
+			t.Errorf("%s = %v, want it bucketed", key, value)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Sender delivers a payload and returns the endpoint's response. Fleets that
+// aggregate telemetry into their own pipeline can implement it instead of
+// forking the HTTP client.
+type Sender interface {
+	Send(ctx context.Context, data *Data) (*Response, error)
+}
+
+// ResultSender is a Sender that also reports delivery details such as the
+// number of attempts and the last status code.
+type ResultSender interface {
+	Sender
+	SendWithResult(ctx context.Context, data *Data) (*SendResult, error)
+}
+
+// HTTPSender posts the payload as JSON over HTTP(S) or a Unix socket, with
+// retries. It is the Sender behind the package-level Send.
+type HTTPSender struct {
+	Endpoint string
+	Options  []SendOption
+}
+
+// NewHTTPSender returns an HTTPSender for endpoint.
+func NewHTTPSender(endpoint string, opts ...SendOption) *HTTPSender {
+	return &HTTPSender{Endpoint: endpoint, Options: opts}
+}
+
+// Send implements Sender.
+func (s *HTTPSender) Send(ctx context.Context, data *Data) (*Response, error) {
+	result, err := s.SendWithResult(ctx, data)
+	return result.Response, err
+}
+
+// SendWithResult implements ResultSender.
+func (s *HTTPSender) SendWithResult(ctx context.Context, data *Data) (*SendResult, error) {
+	return Send(ctx, data, s.Endpoint, s.Options...)
+}
+
+// Deliver sends data with sender. Senders that are not ResultSenders get a
+// SendResult with only Success and Response filled in.
+func Deliver(ctx context.Context, sender Sender, data *Data) (*SendResult, error) {
+	if rs, ok := sender.(ResultSender); ok {
+		return rs.SendWithResult(ctx, data)
+	}
+	response, err := sender.Send(ctx, data)
+	return &SendResult{Success: err == nil, Response: response}, err
+}
+
+// WebhookFormat wraps the JSON payload in the envelope a relay expects and
+// returns the request body and its content type.
+type WebhookFormat func(payload []byte) (body []byte, contentType string, err error)
+
+// KafkaRESTFormat wraps the payload as a single record for the v2 API of the
+// Kafka REST Proxy.
+func KafkaRESTFormat(payload []byte) ([]byte, string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]json.RawMessage{{"value": payload}},
+	})
+	return body, "application/vnd.kafka.json.v2+json", err
+}
+
+// WithWebhookFormat wraps the payload with format right before it is sent.
+// The size limit of WithMaxPayloadBytes applies to the unwrapped payload.
+func WithWebhookFormat(format WebhookFormat) SendOption {
+	return func(c *sendConfig) {
+		c.webhookFormat = format
+	}
+}
@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeSender records the payloads it is given, like a relay client would.
+type fakeSender struct {
+	sent     []*Data
+	response *Response
+	err      error
+}
+
+func (f *fakeSender) Send(_ context.Context, data *Data) (*Response, error) {
+	f.sent = append(f.sent, data)
+	return f.response, f.err
+}
+
+func TestDeliver_FakeSender(t *testing.T) {
+	tests := []struct {
+		name        string
+		sender      *fakeSender
+		wantSuccess bool
+	}{
+		{name: "accepted", sender: &fakeSender{response: &Response{RequestIntervalInMinutes: 480}}, wantSuccess: true},
+		{name: "failed", sender: &fakeSender{err: errors.New("broker unavailable")}, wantSuccess: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+			result, err := Deliver(context.Background(), tt.sender, data)
+			if (err != nil) == tt.wantSuccess {
+				t.Fatalf("Deliver() error = %v, wantSuccess %v", err, tt.wantSuccess)
+			}
+			if len(tt.sender.sent) != 1 || tt.sender.sent[0] != data {
+				t.Errorf("sender got %d payloads, want the one passed to Deliver", len(tt.sender.sent))
+			}
+			if result.Success != tt.wantSuccess || result.Response != tt.sender.response {
+				t.Errorf("result = %+v, want Success %v and the sender's response", result, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestHTTPSender(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []SendOption
+		wantContentType string
+		wantRecords     bool
+	}{
+		{name: "json", wantContentType: "application/json"},
+		{name: "kafka rest webhook", opts: []SendOption{WithWebhookFormat(KafkaRESTFormat)}, wantContentType: "application/vnd.kafka.json.v2+json", wantRecords: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]json.RawMessage
+			var contentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				_ = json.NewEncoder(w).Encode(Response{RequestIntervalInMinutes: 480})
+			}))
+			defer server.Close()
+
+			data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+			var sender Sender = NewHTTPSender(server.URL, tt.opts...)
+			result, err := Deliver(context.Background(), sender, data)
+			if err != nil {
+				t.Fatalf("Deliver() error = %v", err)
+			}
+			if result.Attempts != 1 || result.StatusCode != http.StatusOK {
+				t.Errorf("result = %+v, want the HTTP details of one attempt", result)
+			}
+			if result.Response == nil || result.Response.RequestIntervalInMinutes != 480 {
+				t.Errorf("Response = %+v, want the endpoint's response", result.Response)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.wantContentType)
+			}
+
+			payload := body
+			if tt.wantRecords {
+				var records []map[string]map[string]json.RawMessage
+				if err := json.Unmarshal(body["records"], &records); err != nil || len(records) != 1 {
+					t.Fatalf("records = %s, want one record (err %v)", body["records"], err)
+				}
+				payload = records[0]["value"]
+			}
+			if string(payload["appVersion"]) != `"test"` {
+				t.Errorf("appVersion = %s, want \"test\"", payload["appVersion"])
+			}
+		})
+	}
+}
@@ -2,30 +2,92 @@ package telemetry
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
 )
 
 const (
 	DefaultEndpoint = "https://security-responder.rke2.io/v1/checkupgrade"
-	defaultTimeout  = 30 * time.Second
-	maxRetries      = 3
+	// DefaultTimeout bounds each HTTP request attempt made by Send.
+	DefaultTimeout = 30 * time.Second
+	// DefaultOverallTimeout bounds a whole Send, including every attempt and
+	// the backoff between them.
+	DefaultOverallTimeout = 2 * time.Minute
+	maxRetries            = 3
+	// nodeListPageSize bounds how many Node objects are held in memory at once
+	// on large clusters.
+	nodeListPageSize = 100
+	// podListPageSize bounds how many Pod objects are held in memory at once.
+	podListPageSize = 500
 	retryDelay      = 2 * time.Second
 )
 
+// Errors returned by Collect, wrapped around the underlying API error so
+// callers can tell them apart with errors.Is.
+var (
+	// ErrServerVersion means the apiserver version could not be read.
+	ErrServerVersion = errors.New("failed to get server version")
+	// ErrMissingKubeSystem means the kube-system namespace, and with it the
+	// cluster UUID, could not be read. No meaningful payload can be built.
+	ErrMissingKubeSystem = errors.New("failed to get kube-system namespace")
+	// ErrNodeListFailed means listing nodes failed, often transiently.
+	ErrNodeListFailed = errors.New("failed to list nodes")
+	// ErrWorkloadListFailed means listing kube-system workloads failed, often transiently.
+	ErrWorkloadListFailed = errors.New("failed to list kube-system workloads")
+)
+
+// SchemaVersion identifies the collector schema of the payload. Bump it when
+// the meaning of an existing field changes, e.g. a count starts excluding
+// system namespaces; adding a field does not need a bump.
+const SchemaVersion = 1
+
+// Data is the payload sent to the endpoint. encoding/json writes map keys in
+// sorted order, so the same Data always marshals to identical bytes, which
+// downstream diffing and payload signing rely on.
 type Data struct {
+	// SchemaVersion is the collector SchemaVersion that produced the payload.
+	// It is unrelated to AppVersion, the Kubernetes version, and to the build
+	// version of the collector.
+	SchemaVersion  int                    `json:"schemaVersion"`
 	AppVersion     string                 `json:"appVersion"`
 	ExtraTagInfo   map[string]string      `json:"extraTagInfo"`
 	ExtraFieldInfo map[string]interface{} `json:"extraFieldInfo"`
@@ -34,6 +96,18 @@ type Data struct {
 type Response struct {
 	Versions                 []Version `json:"versions"`
 	RequestIntervalInMinutes int       `json:"requestIntervalInMinutes"`
+	// Acknowledged echoes how the endpoint classified the submission. Older
+	// endpoints omit it, leaving it nil.
+	Acknowledged *Acknowledgement `json:"acknowledged,omitempty"`
+	// MinCollectorVersion is the oldest collector release the endpoint still
+	// fully supports. Older endpoints omit it.
+	MinCollectorVersion string `json:"minCollectorVersion,omitempty"`
+}
+
+// Acknowledgement is the endpoint's classification of a submission.
+type Acknowledgement struct {
+	// Dev is true when the submission was filtered as coming from a dev build.
+	Dev bool `json:"dev"`
 }
 
 type Version struct {
@@ -44,89 +118,361 @@ type Version struct {
 	ExtraInfo            map[string]string `json:"extraInfo,omitempty"`
 }
 
-func Collect(ctx context.Context, clientset kubernetes.Interface, mode string) (*Data, error) {
+// GPUResource maps an extended resource advertised by a GPU device plugin to
+// the vendor reported as gpu-vendor.
+type GPUResource struct {
+	Name   corev1.ResourceName
+	Vendor string
+}
+
+// DefaultGPUResources are the GPU resources counted by Collect, matched in order.
+var DefaultGPUResources = []GPUResource{
+	{"nvidia.com/gpu", "nvidia"},
+	{"nvidia.com/gpu.shared", "nvidia"},
+	{"amd.com/gpu", "amd"},
+	{"intel.com/gpu", "intel"},
+	{"gpu.intel.com/i915", "intel"},
+	{"gpu.intel.com/xe", "intel"},
+	{"habana.ai/gaudi", "habana"},
+}
+
+// ParseGPUResources parses comma-separated resource=vendor pairs, as in
+// "habana.ai/gaudi=habana,example.com/gpu=example".
+func ParseGPUResources(spec string) ([]GPUResource, error) {
+	var resources []GPUResource
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, vendor, ok := strings.Cut(pair, "=")
+		name, vendor = strings.TrimSpace(name), strings.TrimSpace(vendor)
+		if !ok || name == "" || vendor == "" {
+			return nil, fmt.Errorf("invalid GPU resource %q: want resource=vendor", pair)
+		}
+		resources = append(resources, GPUResource{Name: corev1.ResourceName(name), Vendor: vendor})
+	}
+	return resources, nil
+}
+
+// ReservedTags are the extraTagInfo keys Collect sets itself. Operator tags
+// may not use them.
+var ReservedTags = []string{"kubernetesVersion", "clusteruuid", "clusteruuid-source", "clusterIdentity"}
+
+// ParseTags parses comma-separated key=value pairs, as in
+// "env=prod,region=eu-west", into operator tags for WithTags. Reserved keys
+// are rejected.
+func ParseTags(spec string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag %q: want key=value", pair)
+		}
+		if slices.Contains(ReservedTags, key) {
+			return nil, fmt.Errorf("tag %q is reserved", key)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+// Collectors are the optional detectors Collect runs, selectable with
+// WithCollectors and WithoutCollectors. Server version, cluster UUID and node
+// information (MandatoryCollectors) are always collected.
+var Collectors = []string{
+	"api-surface",
+	"apf",
+	"batch-schedulers",
+	"cidrs",
+	"cluster-admin",
+	"cni",
+	"dashboard",
+	"data-dir",
+	"default-sa",
+	"dns",
+	"etcd-snapshots",
+	"etcd-tls",
+	"external-auth",
+	"fips",
+	"gpu-operator",
+	"ingress",
+	"ip-stack",
+	"kube-bench",
+	"monitoring",
+	"namespaces",
+	"pdb",
+	"pods",
+	"priorityclasses",
+	"pull-policy",
+	"rancher",
+	"secret-manager",
+	"secrets-encryption",
+	"service-mesh",
+	"snapshot",
+	"system-workloads",
+	"vap",
+	"virtualization",
+	"workload-identity",
+}
+
+// MandatoryCollectors always run. They may be named in an include list but
+// cannot be excluded.
+var MandatoryCollectors = []string{"version", "uuid", "nodes"}
+
+// ParseCollectors parses a comma-separated list of collector names, rejecting
+// names that are neither in Collectors nor in MandatoryCollectors.
+func ParseCollectors(spec string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(Collectors, name) && !slices.Contains(MandatoryCollectors, name) {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// UUIDSources are the sources of clusteruuid in their default priority: the
+// kube-system namespace UID, the operator-provided ID (WithClusterID), and the
+// Rancher install UUID. The first source with a value wins.
+var UUIDSources = []string{"kube-system", "cluster-id", "rancher"}
+
+// ParseUUIDSources parses a comma-separated priority list of UUIDSources for
+// WithUUIDSources, rejecting unknown and repeated names.
+func ParseUUIDSources(spec string) ([]string, error) {
+	var sources []string
+	for _, source := range strings.Split(spec, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		if !slices.Contains(UUIDSources, source) {
+			return nil, fmt.Errorf("unknown UUID source %q", source)
+		}
+		if slices.Contains(sources, source) {
+			return nil, fmt.Errorf("UUID source %q listed twice", source)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+type collectConfig struct {
+	gpuResources    []GPUResource
+	tags            map[string]string
+	clusterID       string
+	uuidSources     []string
+	dynamicClient   dynamic.Interface
+	detectorTimeout time.Duration
+	include         map[string]bool
+	exclude         map[string]bool
+	apiCalls        *atomic.Int64
+}
+
+// enabled reports whether the optional collector name should run.
+func (c *collectConfig) enabled(name string) bool {
+	if c.include != nil && !c.include[name] {
+		return false
+	}
+	return !c.exclude[name]
+}
+
+// CollectOption customizes what Collect gathers.
+type CollectOption func(*collectConfig)
+
+// WithGPUResources adds resources to DefaultGPUResources. A resource that is
+// already listed takes the vendor given here.
+func WithGPUResources(resources []GPUResource) CollectOption {
+	return func(c *collectConfig) {
+		c.gpuResources = mergeGPUResources(c.gpuResources, resources)
+	}
+}
+
+// WithTags adds operator tags to extraTagInfo. Reserved keys are ignored so
+// they cannot overwrite what Collect reports.
+func WithTags(tags map[string]string) CollectOption {
+	return func(c *collectConfig) {
+		if c.tags == nil {
+			c.tags = make(map[string]string)
+		}
+		maps.Copy(c.tags, tags)
+	}
+}
+
+// WithClusterID sets the operator-provided cluster ID, the "cluster-id" UUID
+// source. It keeps a cluster's identity stable when kube-system is recreated.
+func WithClusterID(id string) CollectOption {
+	return func(c *collectConfig) {
+		c.clusterID = id
+	}
+}
+
+// WithUUIDSources changes the priority of UUIDSources. Sources left out are
+// not consulted.
+func WithUUIDSources(sources ...string) CollectOption {
+	return func(c *collectConfig) {
+		c.uuidSources = sources
+	}
+}
+
+// WithDynamicClient lets collectors read custom resources such as RKE2's
+// ETCDSnapshotFiles. Without it they fall back to built-in resources.
+func WithDynamicClient(client dynamic.Interface) CollectOption {
+	return func(c *collectConfig) {
+		c.dynamicClient = client
+	}
+}
+
+// WithAPICallCounter reports in api-calls how far counter advanced while
+// collecting. The caller increments it for every request its clients make,
+// e.g. from a wrapping transport. Without it api-calls is -1.
+func WithAPICallCounter(counter *atomic.Int64) CollectOption {
+	return func(c *collectConfig) {
+		c.apiCalls = counter
+	}
+}
+
+// DefaultDetectorTimeout bounds each optional collector, so a hanging API
+// (such as a broken aggregated API) costs one collector instead of the run.
+const DefaultDetectorTimeout = 15 * time.Second
+
+// WithDetectorTimeout changes DefaultDetectorTimeout. Zero or less disables it.
+func WithDetectorTimeout(timeout time.Duration) CollectOption {
+	return func(c *collectConfig) {
+		c.detectorTimeout = timeout
+	}
+}
+
+// WithCollectors runs only the named optional collectors. Mandatory collection
+// always happens.
+func WithCollectors(names ...string) CollectOption {
+	return func(c *collectConfig) {
+		if c.include == nil {
+			c.include = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.include[name] = true
+		}
+	}
+}
+
+// WithoutCollectors skips the named optional collectors.
+func WithoutCollectors(names ...string) CollectOption {
+	return func(c *collectConfig) {
+		if c.exclude == nil {
+			c.exclude = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.exclude[name] = true
+		}
+	}
+}
+
+func mergeGPUResources(base, extra []GPUResource) []GPUResource {
+	merged := slices.Clone(base)
+	for _, res := range extra {
+		i := slices.IndexFunc(merged, func(r GPUResource) bool { return r.Name == res.Name })
+		if i >= 0 {
+			merged[i].Vendor = res.Vendor
+		} else {
+			merged = append(merged, res)
+		}
+	}
+	return merged
+}
+
+// Collect gathers the payload from the cluster. The run and each collector are
+// traced as spans.
+func Collect(ctx context.Context, clientset kubernetes.Interface, mode string, opts ...CollectOption) (*Data, error) {
+	ctx, span := tracer().Start(ctx, "Collect", trace.WithAttributes(attribute.String("mode", mode)))
+	defer span.End()
+	data, err := collect(ctx, clientset, mode, opts...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return data, err
+}
+
+func collect(ctx context.Context, clientset kubernetes.Interface, mode string, opts ...CollectOption) (*Data, error) {
+	cfg := collectConfig{gpuResources: DefaultGPUResources, uuidSources: UUIDSources, detectorTimeout: DefaultDetectorTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	start := time.Now()
+	var callsBefore int64
+	if cfg.apiCalls != nil {
+		callsBefore = cfg.apiCalls.Load()
+	}
+
 	data := &Data{
+		SchemaVersion:  SchemaVersion,
 		ExtraTagInfo:   make(map[string]string),
 		ExtraFieldInfo: make(map[string]interface{}),
 	}
 	data.ExtraFieldInfo["mode"] = mode
 	isMinimal := mode == "minimal"
+	ctx, rbacDenied := withDenialCounter(ctx)
+	collectors := &collectorRegistry{cfg: &cfg, fields: data.ExtraFieldInfo}
 
 	logrus.Debug("collecting server version")
 	versionInfo, err := clientset.Discovery().ServerVersion()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get server version: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrServerVersion, err)
 	}
 	data.AppVersion = versionInfo.GitVersion
 	data.ExtraTagInfo["kubernetesVersion"] = versionInfo.GitVersion
+	collectors.add("version")
 	logrus.WithField("version", versionInfo.GitVersion).Debug("collected version")
 
+	collectors.run(ctx, "api-surface", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("collecting API groups")
+		apiGroupCount, alphaAPIs, nonDefaultAPIs := detectAPISurface(ctx, clientset)
+		fields["api-group-count"] = apiGroupCount
+		fields["alpha-apis-enabled"] = alphaAPIs
+		fields["nondefault-apis"] = strings.Join(nonDefaultAPIs, ",")
+		logrus.WithFields(logrus.Fields{"groups": apiGroupCount, "alpha": alphaAPIs}).Debug("collected API groups")
+	})
+
 	logrus.Debug("collecting cluster UUID from kube-system namespace")
 	namespace, err := clientset.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get kube-system namespace: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrMissingKubeSystem, err)
 	}
-	data.ExtraTagInfo["clusteruuid"] = string(namespace.UID)
-	logrus.WithField("uuid", namespace.UID).Debug("collected cluster UUID")
+	collectors.add("uuid")
+	logrus.WithField("uuid", namespace.UID).Debug("collected kube-system UID")
 
 	logrus.Debug("collecting node information")
-	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	apiserverVersion, err := version.ParseSemantic(versionInfo.GitVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list nodes: %w", err)
-	}
-
-	var serverNodeCount, agentNodeCount, gpuNodeCount int
-	var serverCPU, agentCPU, serverMemory, agentMemory int64
-	var operatingSystem, osImage, kernelVersion, arch, selinuxInfo, gpuVendor string
-
-	gpuResources := []corev1.ResourceName{"nvidia.com/gpu", "amd.com/gpu", "intel.com/gpu"}
-	gpuVendorMap := map[corev1.ResourceName]string{
-		"nvidia.com/gpu": "nvidia",
-		"amd.com/gpu":    "amd",
-		"intel.com/gpu":  "intel",
+		logrus.WithError(err).Debug("failed to parse server version, skipping kubelet skew check")
 	}
-
-	nodeInfoConsistent := true
-	for _, node := range nodes.Items {
-		cpu := node.Status.Allocatable.Cpu().MilliValue()
-		mem := node.Status.Allocatable.Memory().Value()
-		if isControlPlaneNode(&node) {
-			serverNodeCount++
-			serverCPU += cpu
-			serverMemory += mem
-		} else {
-			agentNodeCount++
-			agentCPU += cpu
-			agentMemory += mem
-		}
-		if osImage == "" {
-			operatingSystem = node.Status.NodeInfo.OperatingSystem
-			osImage = node.Status.NodeInfo.OSImage
-			kernelVersion = node.Status.NodeInfo.KernelVersion
-			arch = node.Status.NodeInfo.Architecture
-		} else if node.Status.NodeInfo.OperatingSystem != operatingSystem ||
-			node.Status.NodeInfo.OSImage != osImage ||
-			node.Status.NodeInfo.KernelVersion != kernelVersion ||
-			node.Status.NodeInfo.Architecture != arch {
-			nodeInfoConsistent = false
-		}
-		if selinuxInfo == "" {
-			selinuxInfo = getSELinuxStatus(&node)
-		}
-		for _, res := range gpuResources {
-			if qty, ok := node.Status.Allocatable[res]; ok {
-				if count, _ := qty.AsInt64(); count > 0 {
-					gpuNodeCount++
-					if gpuVendor == "" {
-						gpuVendor = gpuVendorMap[res]
-					}
-					break
-				}
-			}
+	nodeSummary := newNodeSummaryBuilder(apiserverVersion, cfg.gpuResources)
+	err = forEachNode(ctx, clientset, nodeSummary.add)
+	nodesDenied := false
+	if err != nil {
+		if !recordDenial(ctx, err) {
+			return nil, fmt.Errorf("%w: %w", ErrNodeListFailed, err)
 		}
+		logrus.WithError(err).Warn("not allowed to list nodes, node fields will be -1")
+		nodesDenied = true
+	} else {
+		collectors.add("nodes")
 	}
+	nodes := nodeSummary.summary()
 
-	if isMinimal {
+	if isMinimal || nodesDenied {
 		data.ExtraFieldInfo["serverNodeCount"] = -1
 		data.ExtraFieldInfo["agentNodeCount"] = -1
 		data.ExtraFieldInfo["gpuNodeCount"] = -1
@@ -134,258 +480,1936 @@ func Collect(ctx context.Context, clientset kubernetes.Interface, mode string) (
 		data.ExtraFieldInfo["agentCPU"] = int64(-1)
 		data.ExtraFieldInfo["serverMemory"] = int64(-1)
 		data.ExtraFieldInfo["agentMemory"] = int64(-1)
+		data.ExtraFieldInfo["zone-count"] = -1
+		data.ExtraFieldInfo["eol-os-node-count"] = -1
+		data.ExtraFieldInfo["vulnerable-kernel-node-count"] = -1
 	} else {
-		data.ExtraFieldInfo["serverNodeCount"] = serverNodeCount
-		data.ExtraFieldInfo["agentNodeCount"] = agentNodeCount
-		data.ExtraFieldInfo["serverCPU"] = serverCPU
-		data.ExtraFieldInfo["agentCPU"] = agentCPU
-		data.ExtraFieldInfo["serverMemory"] = serverMemory
-		data.ExtraFieldInfo["agentMemory"] = agentMemory
-		data.ExtraFieldInfo["gpuNodeCount"] = gpuNodeCount
-	}
-	data.ExtraFieldInfo["operating-system"] = operatingSystem
-	data.ExtraFieldInfo["os"] = osImage
-	data.ExtraFieldInfo["kernel"] = kernelVersion
-	data.ExtraFieldInfo["arch"] = arch
-	data.ExtraFieldInfo["selinux"] = selinuxInfo
-	data.ExtraFieldInfo["node-info-consistent"] = nodeInfoConsistent
-	if gpuVendor != "" {
-		data.ExtraFieldInfo["gpu-vendor"] = gpuVendor
+		data.ExtraFieldInfo["serverNodeCount"] = nodes.ServerNodeCount
+		data.ExtraFieldInfo["agentNodeCount"] = nodes.AgentNodeCount
+		data.ExtraFieldInfo["serverCPU"] = nodes.ServerCPU
+		data.ExtraFieldInfo["agentCPU"] = nodes.AgentCPU
+		data.ExtraFieldInfo["serverMemory"] = nodes.ServerMemory
+		data.ExtraFieldInfo["agentMemory"] = nodes.AgentMemory
+		data.ExtraFieldInfo["gpuNodeCount"] = nodes.GPUNodeCount
+		data.ExtraFieldInfo["zone-count"] = nodes.ZoneCount
+		data.ExtraFieldInfo["eol-os-node-count"] = nodes.EOLOSNodeCount
+		data.ExtraFieldInfo["vulnerable-kernel-node-count"] = nodes.VulnerableKernelNodeCount
+	}
+	data.ExtraFieldInfo["has-eol-os"] = nodes.EOLOSNodeCount > 0
+	data.ExtraFieldInfo["kernel-risk"] = nodes.KernelRisk
+	data.ExtraFieldInfo["control-plane-multizone"] = nodes.ControlPlaneMultizone
+	data.ExtraFieldInfo["control-plane-isolated"] = nodes.ControlPlaneIsolated
+	data.ExtraFieldInfo["hosted-control-plane"] = nodes.HostedControlPlane
+	if nodes.HostedControlPlane {
+		logrus.Info("no control plane nodes visible, assuming a hosted control plane")
+	}
+	data.ExtraFieldInfo["invalid-skew"] = nodes.InvalidSkew
+	data.ExtraFieldInfo["operating-system"] = nodes.OperatingSystem
+	data.ExtraFieldInfo["os"] = nodes.OSImage
+	data.ExtraFieldInfo["kernel"] = nodes.KernelVersion
+	data.ExtraFieldInfo["arch"] = nodes.Arch
+	data.ExtraFieldInfo["selinux"] = nodes.SELinux
+	data.ExtraFieldInfo["cgroup-version"] = nodes.CgroupVersion
+	data.ExtraFieldInfo["node-info-consistent"] = nodes.NodeInfoConsistent
+	if nodes.GPUVendor != "" {
+		data.ExtraFieldInfo["gpu-vendor"] = nodes.GPUVendor
 	}
 	logrus.WithFields(logrus.Fields{
-		"server":       serverNodeCount,
-		"agent":        agentNodeCount,
-		"serverCPU":    serverCPU,
-		"agentCPU":     agentCPU,
-		"serverMemory": serverMemory,
-		"agentMemory":  agentMemory,
-		"gpuNodeCount": gpuNodeCount,
+		"server":       nodes.ServerNodeCount,
+		"agent":        nodes.AgentNodeCount,
+		"serverCPU":    nodes.ServerCPU,
+		"agentCPU":     nodes.AgentCPU,
+		"serverMemory": nodes.ServerMemory,
+		"agentMemory":  nodes.AgentMemory,
+		"gpuNodeCount": nodes.GPUNodeCount,
 	}).Debug("collected nodes")
 
 	logrus.Debug("collecting kube-system workloads")
-	kubeSystemDS, err := clientset.AppsV1().DaemonSets("kube-system").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list kube-system daemonsets: %w", err)
+	workloads := newWorkloadCache(clientset)
+	// RBAC denials are already counted by the cache; detectors then see no
+	// workloads and, reading the cache again, fail their collectors-run entry
+	if _, err := workloads.daemonSets(ctx, "kube-system"); err != nil && !apierrors.IsForbidden(err) {
+		return nil, fmt.Errorf("%w: daemonsets: %w", ErrWorkloadListFailed, err)
 	}
-	kubeSystemDeploy, err := clientset.AppsV1().Deployments("kube-system").List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list kube-system deployments: %w", err)
+	if _, err := workloads.deployments(ctx, "kube-system"); err != nil && !apierrors.IsForbidden(err) {
+		return nil, fmt.Errorf("%w: deployments: %w", ErrWorkloadListFailed, err)
 	}
 
-	logrus.Debug("detecting CNI plugin")
-	cniPlugin, cniVersion := detectCNIPlugin(kubeSystemDS.Items)
-	data.ExtraFieldInfo["cni-plugin"] = cniPlugin
-	if cniVersion != "" {
-		data.ExtraFieldInfo["cni-version"] = cniVersion
-	}
-	logrus.WithFields(logrus.Fields{"plugin": cniPlugin, "version": cniVersion}).Debug("detected CNI")
+	collectors.run(ctx, "cni", func(ctx context.Context, fields map[string]interface{}) {
+		kubeSystemDS, _ := workloads.daemonSets(ctx, "kube-system")
+		logrus.Debug("detecting CNI plugin")
+		cniPlugin, cniVersion, cniDetected := detectCNIPlugin(kubeSystemDS)
+		fields["cni-plugin"] = cniPlugin
+		if cniVersion != "" {
+			fields["cni-version"] = cniVersion
+		}
+		cniConflict := len(cniDetected) > 1
+		fields["cni-conflict"] = cniConflict
+		if cniConflict {
+			fields["cni-detected"] = cniDetected
+			logrus.WithField("detected", cniDetected).Warn("multiple CNI plugins detected")
+		}
+		logrus.WithFields(logrus.Fields{"plugin": cniPlugin, "version": cniVersion}).Debug("detected CNI")
 
-	logrus.Debug("detecting ingress controller")
-	ingressController, ingressVersion := detectIngressController(kubeSystemDeploy.Items, kubeSystemDS.Items)
-	data.ExtraFieldInfo["ingress-controller"] = ingressController
-	if ingressVersion != "" {
-		data.ExtraFieldInfo["ingress-version"] = ingressVersion
-	}
-	logrus.WithFields(logrus.Fields{"controller": ingressController, "version": ingressVersion}).Debug("detected ingress")
+		logrus.Debug("detecting CNI encryption")
+		cniEncryption := detectCNIEncryption(ctx, clientset, cniPlugin, kubeSystemDS)
+		fields["cni-encryption"] = cniEncryption
+		logrus.WithField("encryption", cniEncryption).Debug("detected CNI encryption")
+	})
 
-	logrus.Debug("detecting GPU operator")
-	gpuOperator, gpuOperatorVersion := detectGPUOperator(ctx, clientset)
-	if gpuOperator != "none" {
-		data.ExtraFieldInfo["gpu-operator"] = gpuOperator
-		if gpuOperatorVersion != "" {
-			data.ExtraFieldInfo["gpu-operator-version"] = gpuOperatorVersion
+	collectors.run(ctx, "data-dir", func(ctx context.Context, fields map[string]interface{}) {
+		kubeSystemDS, _ := workloads.daemonSets(ctx, "kube-system")
+		logrus.Debug("detecting RKE2 data directory")
+		customDataDir := detectCustomDataDir(kubeSystemDS)
+		fields["custom-data-dir"] = customDataDir
+		logrus.WithField("custom", customDataDir).Debug("detected RKE2 data directory")
+	})
+
+	collectors.run(ctx, "ingress", func(ctx context.Context, fields map[string]interface{}) {
+		kubeSystemDS, _ := workloads.daemonSets(ctx, "kube-system")
+		kubeSystemDeploy, _ := workloads.deployments(ctx, "kube-system")
+		logrus.Debug("detecting ingress controller")
+		ingressController, ingressVersion := detectIngressController(kubeSystemDeploy, kubeSystemDS)
+		fields["ingress-controller"] = ingressController
+		if ingressVersion != "" {
+			fields["ingress-version"] = ingressVersion
 		}
-	}
-	logrus.WithFields(logrus.Fields{"operator": gpuOperator, "version": gpuOperatorVersion}).Debug("detected GPU operator")
+		logrus.WithFields(logrus.Fields{"controller": ingressController, "version": ingressVersion}).Debug("detected ingress")
+		if ingressController == "rke2-ingress-nginx" {
+			ingressWAF := detectIngressWAF(ctx, clientset)
+			fields["ingress-waf"] = ingressWAF
+			logrus.WithField("waf", ingressWAF).Debug("detected ingress WAF")
+		}
+		ingressCount, ingressWithTLS := detectIngressTLS(ctx, clientset)
+		if isMinimal {
+			fields["ingress-count"] = -1
+			fields["ingress-with-tls"] = -1
+		} else {
+			fields["ingress-count"] = ingressCount
+			fields["ingress-with-tls"] = ingressWithTLS
+		}
+		logrus.WithFields(logrus.Fields{"count": ingressCount, "tls": ingressWithTLS}).Debug("counted ingresses")
+	})
 
-	logrus.Debug("detecting Rancher Manager")
-	rancherManaged, rancherVersion, rancherInstallUUID := detectRancherManager(ctx, clientset)
-	data.ExtraFieldInfo["rancher-managed"] = rancherManaged
-	if isMinimal {
-		data.ExtraFieldInfo["rancher-version"] = ""
-		data.ExtraFieldInfo["rancher-install-uuid"] = ""
-	} else {
-		if rancherVersion != "" {
-			data.ExtraFieldInfo["rancher-version"] = rancherVersion
+	collectors.run(ctx, "pull-policy", func(ctx context.Context, fields map[string]interface{}) {
+		kubeSystemDS, _ := workloads.daemonSets(ctx, "kube-system")
+		kubeSystemDeploy, _ := workloads.deployments(ctx, "kube-system")
+		logrus.Debug("detecting system image pull policy")
+		systemPullPolicy := detectSystemPullPolicy(kubeSystemDeploy, kubeSystemDS)
+		fields["system-pull-policy"] = systemPullPolicy
+		logrus.WithField("policy", systemPullPolicy).Debug("detected system image pull policy")
+	})
+
+	collectors.run(ctx, "fips", func(ctx context.Context, fields map[string]interface{}) {
+		kubeSystemDS, _ := workloads.daemonSets(ctx, "kube-system")
+		kubeSystemDeploy, _ := workloads.deployments(ctx, "kube-system")
+		logrus.Debug("detecting FIPS mode")
+		fipsMode := detectFIPSMode(kubeSystemDeploy, kubeSystemDS)
+		fields["fips-mode"] = fipsMode
+		logrus.WithField("fips-mode", fipsMode).Debug("detected FIPS mode")
+	})
+
+	collectors.run(ctx, "system-workloads", func(ctx context.Context, fields map[string]interface{}) {
+		if isMinimal {
+			return
 		}
-		if rancherInstallUUID != "" {
-			data.ExtraFieldInfo["rancher-install-uuid"] = rancherInstallUUID
+		kubeSystemDS, _ := workloads.daemonSets(ctx, "kube-system")
+		kubeSystemDeploy, _ := workloads.deployments(ctx, "kube-system")
+		logrus.Debug("detecting degraded system workloads")
+		degraded := detectDegradedWorkloads(kubeSystemDeploy, kubeSystemDS)
+		fields["degraded-system-workloads"] = strings.Join(degraded, ",")
+		logrus.WithField("degraded", degraded).Debug("detected degraded system workloads")
+	})
+
+	collectors.run(ctx, "snapshot", func(ctx context.Context, fields map[string]interface{}) {
+		kubeSystemDeploy, _ := workloads.deployments(ctx, "kube-system")
+		logrus.Debug("detecting snapshot controller")
+		snapshotController, snapshotControllerVersion := detectSnapshotController(kubeSystemDeploy)
+		fields["snapshot-controller"] = snapshotController
+		if snapshotControllerVersion != "" {
+			fields["snapshot-controller-version"] = snapshotControllerVersion
 		}
-	}
-	logrus.WithFields(logrus.Fields{"managed": rancherManaged, "version": rancherVersion, "installUUID": rancherInstallUUID}).Debug("detected Rancher")
+		volumeSnapshotClassAPI := hasAPIResource(clientset, "snapshot.storage.k8s.io/v1", "volumesnapshotclasses")
+		fields["volumesnapshotclass-crd"] = volumeSnapshotClassAPI
+		logrus.WithFields(logrus.Fields{"installed": snapshotController, "version": snapshotControllerVersion, "crd": volumeSnapshotClassAPI}).Debug("detected snapshot controller")
+	})
 
-	logrus.Debug("detecting IP stack configuration")
-	ipStack := detectIPStack(ctx, clientset)
-	data.ExtraFieldInfo["ip-stack"] = ipStack
-	logrus.WithField("ip-stack", ipStack).Debug("detected IP stack")
+	collectors.run(ctx, "gpu-operator", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting GPU operator")
+		gpuOperator, gpuOperatorVersion := detectGPUOperator(ctx, workloads)
+		if gpuOperator != "none" {
+			fields["gpu-operator"] = gpuOperator
+			if gpuOperatorVersion != "" {
+				fields["gpu-operator-version"] = gpuOperatorVersion
+			}
+		}
+		logrus.WithFields(logrus.Fields{"operator": gpuOperator, "version": gpuOperatorVersion}).Debug("detected GPU operator")
+	})
 
-	return data, nil
-}
+	rancherFields := collectors.run(ctx, "rancher", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting Rancher Manager")
+		rancherManaged, rancherVersion, rancherInstallUUID, rancherRole := detectRancherManager(ctx, clientset)
+		var webhookVersion string
+		fields["rancher-managed"] = rancherManaged
+		if rancherManaged {
+			fields["rancher-cluster-role"] = rancherRole
+			webhookVersion = detectRancherWebhook(ctx, clientset)
+		}
+		if isMinimal {
+			fields["rancher-version"] = ""
+			fields["rancher-install-uuid"] = ""
+			fields["rancher-webhook-version"] = ""
+		} else {
+			if rancherVersion != "" {
+				fields["rancher-version"] = rancherVersion
+			}
+			if rancherInstallUUID != "" {
+				fields["rancher-install-uuid"] = rancherInstallUUID
+			}
+			if webhookVersion != "" {
+				fields["rancher-webhook-version"] = webhookVersion
+			}
+		}
+		logrus.WithFields(logrus.Fields{"managed": rancherManaged, "version": rancherVersion, "installUUID": rancherInstallUUID, "role": rancherRole, "webhookVersion": webhookVersion}).Debug("detected Rancher")
+	})
 
-func Send(ctx context.Context, data *Data, endpoint string) (*Response, error) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
-	}
+	// Minimal mode blanks the install UUID field, so it cannot leak through here
+	identityInstallUUID, _ := rancherFields["rancher-install-uuid"].(string)
+	clusterUUID, uuidSource := resolveClusterUUID(cfg.uuidSources, map[string]string{
+		"kube-system": string(namespace.UID),
+		"cluster-id":  cfg.clusterID,
+		"rancher":     identityInstallUUID,
+	})
+	data.ExtraTagInfo["clusteruuid"] = clusterUUID
+	data.ExtraTagInfo["clusteruuid-source"] = uuidSource
+	logrus.WithFields(logrus.Fields{"uuid": clusterUUID, "source": uuidSource}).Debug("resolved cluster UUID")
+	data.ExtraTagInfo["clusterIdentity"] = clusterIdentity(clusterUUID, identityInstallUUID)
 
-	logrus.WithField("endpoint", endpoint).Info("sending data")
-	logrus.WithField("size", len(jsonData)).Debug("request payload")
+	collectors.run(ctx, "external-auth", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting external authentication")
+		externalAuth := detectExternalAuth(ctx, workloads)
+		fields["external-auth"] = externalAuth
+		logrus.WithField("external-auth", externalAuth).Debug("detected external authentication")
+	})
 
-	client := &http.Client{Timeout: defaultTimeout}
+	collectors.run(ctx, "dashboard", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting Kubernetes Dashboard")
+		dashboardInstalled, dashboardVersion := detectKubernetesDashboard(ctx, clientset, workloads)
+		fields["kubernetes-dashboard"] = dashboardInstalled
+		if dashboardVersion != "" {
+			fields["kubernetes-dashboard-version"] = dashboardVersion
+		}
+		logrus.WithFields(logrus.Fields{"installed": dashboardInstalled, "version": dashboardVersion}).Debug("detected Kubernetes Dashboard")
+	})
 
-	var lastErr error
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			delay := time.Duration(attempt-1) * retryDelay
-			logrus.WithFields(logrus.Fields{"attempt": attempt, "max": maxRetries, "delay": delay}).Info("retrying")
-			time.Sleep(delay)
+	collectors.run(ctx, "monitoring", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting monitoring stack")
+		monitoringStack, monitoringVersion := detectMonitoringStack(ctx, workloads)
+		fields["monitoring-stack"] = monitoringStack
+		if monitoringVersion != "" {
+			fields["monitoring-stack-version"] = monitoringVersion
 		}
+		logrus.WithFields(logrus.Fields{"stack": monitoringStack, "version": monitoringVersion}).Debug("detected monitoring stack")
+	})
 
-		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+	collectors.run(ctx, "secret-manager", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting secret manager")
+		secretManager, secretManagerVersion := detectSecretManager(ctx, workloads)
+		fields["secret-manager"] = secretManager
+		if secretManagerVersion != "" {
+			fields["secret-manager-version"] = secretManagerVersion
 		}
-		req.Header.Set("Content-Type", "application/json")
+		logrus.WithFields(logrus.Fields{"manager": secretManager, "version": secretManagerVersion}).Debug("detected secret manager")
+	})
 
-		resp, err := client.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to send request: %w", err)
-			logrus.WithField("attempt", attempt).WithError(lastErr).Warn("attempt failed")
-			continue
+	collectors.run(ctx, "batch-schedulers", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting batch schedulers")
+		for name, version := range detectBatchSchedulers(ctx, workloads) {
+			fields[name+"-version"] = version
+			logrus.WithFields(logrus.Fields{"scheduler": name, "version": version}).Debug("detected batch scheduler")
 		}
+	})
 
-		body, err := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response: %w", err)
-			logrus.WithField("attempt", attempt).WithError(lastErr).Warn("attempt failed")
-			continue
+	collectors.run(ctx, "virtualization", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting virtualization")
+		virtualization, virtualizationVersion := detectVirtualization(ctx, workloads)
+		fields["virtualization"] = virtualization
+		if virtualizationVersion != "" {
+			fields["virtualization-version"] = virtualizationVersion
 		}
+		logrus.WithFields(logrus.Fields{"virtualization": virtualization, "version": virtualizationVersion}).Debug("detected virtualization")
+	})
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-			logrus.WithField("attempt", attempt).WithError(lastErr).Warn("attempt failed")
-			continue
+	collectors.run(ctx, "workload-identity", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting workload identity")
+		workloadIdentity, workloadIdentityVersion := detectWorkloadIdentity(ctx, workloads)
+		fields["workload-identity"] = workloadIdentity
+		if workloadIdentityVersion != "" {
+			fields["workload-identity-version"] = workloadIdentityVersion
 		}
+		logrus.WithFields(logrus.Fields{"identity": workloadIdentity, "version": workloadIdentityVersion}).Debug("detected workload identity")
+	})
 
-		var response Response
-		if err := json.Unmarshal(body, &response); err != nil {
-			logrus.WithError(err).Warn("failed to parse response")
-			logrus.WithField("attempt", attempt).Info("data sent")
-			return nil, nil
+	collectors.run(ctx, "service-mesh", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting service mesh mTLS")
+		if meshMTLS := detectMeshMTLS(ctx, clientset, cfg.dynamicClient, workloads); meshMTLS != "" {
+			fields["mesh-mtls"] = meshMTLS
+			logrus.WithField("mtls", meshMTLS).Debug("detected service mesh mTLS")
 		}
+	})
 
-		logrus.WithFields(logrus.Fields{"versions": len(response.Versions), "intervalMinutes": response.RequestIntervalInMinutes}).Info("response received")
-		for _, v := range response.Versions {
-			logrus.WithFields(logrus.Fields{"name": v.Name, "releaseDate": v.ReleaseDate, "tags": v.Tags}).Info("available version")
-			if len(v.ExtraInfo) > 0 {
-				logrus.WithField("extraInfo", v.ExtraInfo).Info("version extra info")
+	collectors.run(ctx, "pdb", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting PodDisruptionBudgets")
+		pdbCount, systemPDBCoverage := detectPodDisruptionBudgets(ctx, clientset)
+		if isMinimal {
+			fields["pdb-count"] = -1
+		} else {
+			fields["pdb-count"] = pdbCount
+		}
+		fields["system-pdb-coverage"] = systemPDBCoverage
+		logrus.WithFields(logrus.Fields{"count": pdbCount, "systemCoverage": systemPDBCoverage}).Debug("detected PodDisruptionBudgets")
+	})
+
+	collectors.run(ctx, "priorityclasses", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting PriorityClasses")
+		priorityClasses, systemPriorityClasses, customPriorityClasses := detectPriorityClasses(ctx, clientset)
+		if isMinimal {
+			fields["priorityclass-count"] = -1
+			fields["custom-priorityclass-count"] = -1
+		} else {
+			fields["priorityclass-count"] = priorityClasses
+			fields["custom-priorityclass-count"] = customPriorityClasses
+		}
+		fields["system-priorityclasses"] = systemPriorityClasses
+		logrus.WithFields(logrus.Fields{"count": priorityClasses, "system": systemPriorityClasses, "custom": customPriorityClasses}).Debug("detected PriorityClasses")
+	})
+
+	collectors.run(ctx, "apf", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting API Priority and Fairness")
+		apfEnabled, flowSchemas := detectAPF(ctx, clientset)
+		fields["apf-enabled"] = apfEnabled
+		if isMinimal {
+			fields["flowschema-count"] = -1
+		} else {
+			fields["flowschema-count"] = flowSchemas
+		}
+		logrus.WithFields(logrus.Fields{"enabled": apfEnabled, "flowSchemas": flowSchemas}).Debug("detected API Priority and Fairness")
+	})
+
+	collectors.run(ctx, "vap", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting ValidatingAdmissionPolicies")
+		if served, vapCount, vapBindingCount := detectValidatingAdmissionPolicies(ctx, clientset); served {
+			if isMinimal {
+				vapCount, vapBindingCount = -1, -1
 			}
+			fields["vap-count"] = vapCount
+			fields["vap-binding-count"] = vapBindingCount
+			logrus.WithFields(logrus.Fields{"policies": vapCount, "bindings": vapBindingCount}).Debug("detected ValidatingAdmissionPolicies")
 		}
+	})
 
-		logrus.WithField("attempt", attempt).Info("data sent")
-		return &response, nil
-	}
+	collectors.run(ctx, "namespaces", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("counting namespaces")
+		namespaceCount, tenancy := detectTenancy(ctx, clientset)
+		if isMinimal {
+			fields["namespace-count"] = -1
+		} else {
+			fields["namespace-count"] = namespaceCount
+		}
+		fields["tenancy"] = tenancy
+		withQuota, withLimitRange := detectNamespaceLimits(ctx, clientset)
+		if isMinimal {
+			withQuota, withLimitRange = -1, -1
+		}
+		fields["namespaces-with-quota"] = withQuota
+		fields["namespaces-with-limitrange"] = withLimitRange
+		logrus.WithFields(logrus.Fields{"count": namespaceCount, "tenancy": tenancy, "withQuota": withQuota, "withLimitRange": withLimitRange}).Debug("counted namespaces")
+	})
 
-	return nil, lastErr
-}
+	collectors.run(ctx, "kube-bench", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting kube-bench results")
+		if found, cisPass, cisFail := detectKubeBench(ctx, clientset); found {
+			if isMinimal {
+				cisPass, cisFail = -1, -1
+			}
+			fields["cis-pass-count"] = cisPass
+			fields["cis-fail-count"] = cisFail
+			logrus.WithFields(logrus.Fields{"pass": cisPass, "fail": cisFail}).Debug("detected kube-bench results")
+		}
+	})
 
-func isControlPlaneNode(node *corev1.Node) bool {
-	_, hasControlPlaneLabel := node.Labels["node-role.kubernetes.io/control-plane"]
-	_, hasMasterLabel := node.Labels["node-role.kubernetes.io/master"]
-	return hasControlPlaneLabel || hasMasterLabel
-}
+	collectors.run(ctx, "secrets-encryption", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting secrets-encryption key rotation")
+		if rotatedAt, ok := detectEncryptionRotation(ctx, clientset, namespace); ok {
+			ageDays := int(time.Since(rotatedAt).Hours() / 24)
+			fields["encryption-key-age-days"] = ageDays
+			logrus.WithFields(logrus.Fields{"rotatedAt": rotatedAt, "ageDays": ageDays}).Debug("detected secrets-encryption key rotation")
+		}
+	})
 
-// getSELinuxStatus determines SELinux status from node labels.
-// SELinux detection is limited from within containers; this is a best-effort
-// approach. Returns "unknown" if not determinable.
-func getSELinuxStatus(node *corev1.Node) string {
-	if selinux, ok := node.Labels["security.alpha.kubernetes.io/selinux"]; ok {
-		if selinux == "enabled" {
-			return "enabled"
+	collectors.run(ctx, "cluster-admin", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting cluster-admin bindings")
+		clusterAdminSubjects := detectClusterAdminBindings(ctx, clientset)
+		if isMinimal {
+			fields["cluster-admin-subject-count"] = -1
+		} else {
+			fields["cluster-admin-subject-count"] = clusterAdminSubjects
 		}
-		return "disabled"
-	}
-	return "unknown"
-}
+		logrus.WithField("subjects", clusterAdminSubjects).Debug("detected cluster-admin bindings")
+	})
 
-func extractImageVersion(image string) string {
-	if idx := strings.LastIndex(image, ":"); idx != -1 {
-		tag := image[idx+1:]
-		if atIdx := strings.Index(tag, "@"); atIdx != -1 {
-			tag = tag[:atIdx]
+	collectors.run(ctx, "default-sa", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting overprivileged default service accounts")
+		overprivileged := detectOverprivilegedDefaultSAs(ctx, clientset)
+		if isMinimal {
+			fields["default-sa-overprivileged-count"] = -1
+		} else {
+			fields["default-sa-overprivileged-count"] = overprivileged
 		}
-		return tag
-	}
-	return ""
-}
+		logrus.WithField("count", overprivileged).Debug("detected overprivileged default service accounts")
+	})
 
-func detectCNIPlugin(daemonSets []appsv1.DaemonSet) (string, string) {
-	cniPatterns := map[string]string{
-		"canal":   "canal",
-		"flannel": "flannel",
-		"calico":  "calico",
-		"cilium":  "cilium",
-		"weave":   "weave",
-	}
+	collectors.run(ctx, "dns", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting cluster DNS domain")
+		clusterDomain := detectClusterDomain(ctx, clientset)
+		fields["cluster-domain"] = clusterDomain
+		logrus.WithField("domain", clusterDomain).Debug("detected cluster DNS domain")
+	})
 
-	for _, ds := range daemonSets {
-		name := strings.ToLower(ds.Name)
-		for pattern, cniName := range cniPatterns {
-			if strings.Contains(name, pattern) {
-				version := ""
-				if len(ds.Spec.Template.Spec.Containers) > 0 {
-					version = extractImageVersion(ds.Spec.Template.Spec.Containers[0].Image)
-				}
-				return cniName, version
+	// One pass over pods serves both collectors. Its results may only be read
+	// if it completed: an abandoned scan may still be writing them.
+	var scan podScan
+	scanned := false
+	if cfg.enabled("pods") || cfg.enabled("cidrs") {
+		var dependents []string
+		for _, name := range []string{"pods", "cidrs"} {
+			if cfg.enabled(name) {
+				dependents = append(dependents, name)
 			}
 		}
+		scanned = collectors.call(ctx, "pod-scan", func(ctx context.Context) {
+			logrus.Debug("scanning pods")
+			scan = scanClusterPods(ctx, clientset)
+		}, dependents...)
 	}
+	scanFailed := !scanned || scan.err != nil
 
-	return "unknown", ""
-}
+	collectors.run(ctx, "pods", func(ctx context.Context, fields map[string]interface{}) {
+		hostProcessPods, hostPortPods, hostIPCPods, rootPods := -1, -1, -1, -1
+		unsafeSysctlPods, evictedPods, privilegedInitPods := -1, -1, -1
+		ephemeralUsed := false
+		macInUse, schedulers := "unknown", "unknown"
+		if scanFailed {
+			recordFailure(ctx)
+		} else {
+			hostProcessPods, hostPortPods, hostIPCPods, rootPods = scan.hostProcess, scan.hostPort, scan.hostIPC, scan.root
+			unsafeSysctlPods, evictedPods = scan.unsafeSysctl, scan.evicted
+			privilegedInitPods, ephemeralUsed = scan.privilegedInit, scan.ephemeral > 0
+			macInUse = macFromPodCounts(scan.seLinux, scan.appArmor)
+			schedulers = strings.Join(slices.Sorted(maps.Keys(scan.schedulers)), ",")
+		}
+		fields["mac-in-use"] = macInUse
+		if isMinimal {
+			fields["custom-schedulers"] = ""
+			fields["hostprocess-pod-count"] = -1
+			fields["hostport-pod-count"] = -1
+			fields["hostipc-pod-count"] = -1
+			fields["root-pod-count"] = -1
+			fields["unsafe-sysctl-pod-count"] = -1
+			fields["evicted-pod-count"] = -1
+			fields["privileged-init-pod-count"] = -1
+			fields["ephemeral-containers-used"] = false
+		} else {
+			fields["custom-schedulers"] = schedulers
+			fields["hostprocess-pod-count"] = hostProcessPods
+			fields["hostport-pod-count"] = hostPortPods
+			fields["hostipc-pod-count"] = hostIPCPods
+			fields["root-pod-count"] = rootPods
+			fields["unsafe-sysctl-pod-count"] = unsafeSysctlPods
+			fields["evicted-pod-count"] = evictedPods
+			fields["privileged-init-pod-count"] = privilegedInitPods
+			fields["ephemeral-containers-used"] = ephemeralUsed
+		}
+		logrus.WithFields(logrus.Fields{"hostProcess": hostProcessPods, "hostPort": hostPortPods, "hostIPC": hostIPCPods, "root": rootPods, "unsafeSysctl": unsafeSysctlPods, "evicted": evictedPods, "privilegedInit": privilegedInitPods, "ephemeral": ephemeralUsed, "mac": macInUse, "schedulers": schedulers}).Debug("scanned pods")
+	})
 
-func detectIngressController(deployments []appsv1.Deployment, daemonSets []appsv1.DaemonSet) (string, string) {
-	for _, deploy := range deployments {
-		name := strings.ToLower(deploy.Name)
-		var ingressName string
-		switch {
-		case strings.Contains(name, "nginx-ingress"), strings.Contains(name, "rke2-ingress-nginx"):
-			ingressName = "rke2-ingress-nginx"
-		case strings.Contains(name, "traefik"):
-			ingressName = "traefik"
+	collectors.run(ctx, "cidrs", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting pod and service CIDRs")
+		var podCIDR, serviceCIDR string
+		if scanFailed {
+			recordFailure(ctx)
+		} else {
+			podCIDR, serviceCIDR = scan.podCIDR, scan.serviceCIDR
 		}
-		if ingressName != "" {
-			version := ""
-			if len(deploy.Spec.Template.Spec.Containers) > 0 {
-				version = extractImageVersion(deploy.Spec.Template.Spec.Containers[0].Image)
-			}
-			return ingressName, version
+		if podCIDR == "" {
+			podCIDR = aggregateCIDRs(nodes.PodCIDRs)
 		}
-	}
+		podCIDRCapacity, serviceCIDRCapacity := cidrCapacity(podCIDR), cidrCapacity(serviceCIDR)
+		if podCIDR == "" {
+			podCIDR = "unknown"
+		}
+		if serviceCIDR == "" {
+			serviceCIDR = "unknown"
+		}
+		if isMinimal {
+			podCIDRCapacity, serviceCIDRCapacity = -1, -1
+		}
+		fields["pod-cidr"] = podCIDR
+		fields["service-cidr"] = serviceCIDR
+		fields["pod-cidr-capacity"] = podCIDRCapacity
+		fields["service-cidr-capacity"] = serviceCIDRCapacity
+		logrus.WithFields(logrus.Fields{"pod": podCIDR, "service": serviceCIDR}).Debug("detected CIDRs")
+	})
 
-	for _, ds := range daemonSets {
-		name := strings.ToLower(ds.Name)
-		var ingressName string
-		switch {
-		case strings.Contains(name, "nginx-ingress"), strings.Contains(name, "rke2-ingress-nginx"):
-			ingressName = "rke2-ingress-nginx"
-		case strings.Contains(name, "traefik"):
-			ingressName = "traefik"
+	collectors.run(ctx, "etcd-snapshots", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting etcd S3 snapshots")
+		if found, s3Snapshots := detectEtcdS3Snapshots(ctx, clientset, cfg.dynamicClient); found {
+			fields["etcd-s3-snapshots"] = s3Snapshots
+			logrus.WithField("s3", s3Snapshots).Debug("detected etcd S3 snapshots")
 		}
-		if ingressName != "" {
-			version := ""
-			if len(ds.Spec.Template.Spec.Containers) > 0 {
-				version = extractImageVersion(ds.Spec.Template.Spec.Containers[0].Image)
-			}
-			return ingressName, version
+	})
+
+	collectors.run(ctx, "etcd-tls", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting etcd TLS")
+		etcdTLS := detectEtcdTLS(ctx, clientset)
+		fields["etcd-tls"] = etcdTLS
+		logrus.WithField("etcd-tls", etcdTLS).Debug("detected etcd TLS")
+	})
+
+	collectors.run(ctx, "ip-stack", func(ctx context.Context, fields map[string]interface{}) {
+		logrus.Debug("detecting IP stack configuration")
+		ipStack, exposure := "unknown", "unknown"
+		if services, err := clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{}); err != nil {
+			warnAPIError(ctx, err, "failed to list services for IP stack detection")
+		} else {
+			ipStack = detectIPStack(services.Items)
+			exposure = detectAPIServerExposure(services.Items)
+		}
+		nodeFamily := nodes.IPFamily
+		mismatch := ipStackMismatch(ipStack, nodeFamily)
+		fields["ip-stack"] = ipStack
+		fields["node-ip-family"] = nodeFamily
+		fields["ip-stack-mismatch"] = mismatch
+		fields["apiserver-exposure"] = exposure
+		logrus.WithFields(logrus.Fields{"ip-stack": ipStack, "node-ip-family": nodeFamily, "mismatch": mismatch, "apiserver-exposure": exposure}).Debug("detected IP stack")
+	})
+
+	for key, value := range cfg.tags {
+		if slices.Contains(ReservedTags, key) {
+			continue
 		}
+		data.ExtraTagInfo[key] = value
 	}
 
-	return "none", ""
-}
+	data.ExtraFieldInfo["features"] = featureRollup(data.ExtraFieldInfo)
+	data.ExtraFieldInfo["collectors-run"] = strings.Join(slices.Sorted(slices.Values(collectors.ran)), ",")
+	data.ExtraFieldInfo["collectors-timed-out"] = strings.Join(slices.Sorted(slices.Values(collectors.timedOut)), ",")
+	data.ExtraFieldInfo["rbac-denied-count"] = int(rbacDenied.Load())
+	if n := rbacDenied.Load(); n > 0 {
+		logrus.WithField("count", n).Warn("some API calls were denied by RBAC; the ClusterRole may be outdated")
+	}
 
-func detectGPUOperator(ctx context.Context, clientset kubernetes.Interface) (string, string) {
+	apiCalls := int64(-1)
+	if cfg.apiCalls != nil {
+		apiCalls = cfg.apiCalls.Load() - callsBefore
+	}
+	data.ExtraFieldInfo["api-calls"] = apiCalls
+	data.ExtraFieldInfo["collect-duration-ms"] = time.Since(start).Milliseconds()
+	logrus.WithFields(logrus.Fields{"apiCalls": apiCalls, "duration": time.Since(start)}).Debug("collection finished")
+
+	return data, nil
+}
+
+type denialCounterKey struct{}
+
+// withDenialCounter returns a context carrying a counter of API calls rejected
+// as Forbidden. Detectors report denials through recordDenial, so a missing
+// ClusterRole rule degrades a single field instead of aborting the run.
+func withDenialCounter(ctx context.Context) (context.Context, *atomic.Int32) {
+	counter := &atomic.Int32{}
+	return context.WithValue(ctx, denialCounterKey{}, counter), counter
+}
+
+type failureCounterKey struct{}
+
+// withFailureCounter returns a context carrying a counter of failed API calls,
+// denied or not, so collectorRegistry can tell whether a collector degraded.
+func withFailureCounter(ctx context.Context) (context.Context, *atomic.Int32) {
+	counter := &atomic.Int32{}
+	return context.WithValue(ctx, failureCounterKey{}, counter), counter
+}
+
+// recordFailure counts a failed API call against the context's failure counter.
+func recordFailure(ctx context.Context) {
+	if counter, ok := ctx.Value(failureCounterKey{}).(*atomic.Int32); ok {
+		counter.Add(1)
+	}
+}
+
+// recordDenial counts err against the context's denial counter if it is a
+// Forbidden API error, and reports whether it was.
+func recordDenial(ctx context.Context, err error) bool {
+	if !apierrors.IsForbidden(err) {
+		return false
+	}
+	if counter, ok := ctx.Value(denialCounterKey{}).(*atomic.Int32); ok {
+		counter.Add(1)
+	}
+	recordFailure(ctx)
+	return true
+}
+
+// warnAPIError logs a failed API call that a detector can recover from,
+// counting it if it was denied by RBAC.
+func warnAPIError(ctx context.Context, err error, msg string) {
+	if recordDenial(ctx, err) {
+		msg += " (forbidden)"
+	} else {
+		recordFailure(ctx)
+	}
+	logrus.WithError(err).Warn(msg)
+}
+
+// collectorRegistry runs the optional collectors one at a time, each under
+// the detector timeout, and tracks which of them completed without a failed
+// API call (collectors-run) and which were abandoned (collectors-timed-out).
+type collectorRegistry struct {
+	cfg      *collectConfig
+	fields   map[string]interface{}
+	ran      []string
+	timedOut []string
+}
+
+// run runs the collector name if it is enabled. fn writes its fields to its
+// own map, which is merged into the payload and returned only if fn completes
+// in time; otherwise nil is returned and the collector's fields are left out.
+func (r *collectorRegistry) run(ctx context.Context, name string, fn func(ctx context.Context, fields map[string]interface{})) map[string]interface{} {
+	if !r.cfg.enabled(name) {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	var failures *atomic.Int32
+	completed := r.call(ctx, name, func(ctx context.Context) {
+		ctx, failures = withFailureCounter(ctx)
+		fn(ctx, fields)
+		if failures.Load() > 0 {
+			trace.SpanFromContext(ctx).SetStatus(codes.Error, "API call failed")
+		}
+	}, name)
+	if !completed {
+		return nil
+	}
+	maps.Copy(r.fields, fields)
+	if failures.Load() == 0 {
+		r.add(name)
+	}
+	return fields
+}
+
+// call runs fn in a span, with a context limited by the detector timeout, and
+// reports whether fn returned in time. Client calls normally return once the
+// context expires, but a hung call may not, so fn is abandoned at the deadline
+// and names are recorded as timed out. An abandoned fn may still be running:
+// anything it writes must not be read unless call returned true.
+func (r *collectorRegistry) call(ctx context.Context, spanName string, fn func(ctx context.Context), names ...string) bool {
+	ctx, span := tracer().Start(ctx, spanName)
+	defer span.End()
+	cancel := context.CancelFunc(func() {})
+	if r.cfg.detectorTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.detectorTimeout)
+	}
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+	}
+	// Prefer a result that arrived together with the deadline
+	select {
+	case <-done:
+		return true
+	default:
+	}
+	span.SetStatus(codes.Error, "timed out")
+	logrus.WithFields(logrus.Fields{"detector": spanName, "timeout": r.cfg.detectorTimeout}).Warn("detector timed out, abandoning it")
+	r.timedOut = append(r.timedOut, names...)
+	return false
+}
+
+// add records name as completed unconditionally.
+func (r *collectorRegistry) add(name string) {
+	r.ran = append(r.ran, name)
+}
+
+// IdempotencyKeyHeader carries a per-run key that stays the same across retries
+// so the endpoint can drop duplicate submissions.
+const IdempotencyKeyHeader = "X-Idempotency-Key"
+
+type sendConfig struct {
+	insecureSkipVerify bool
+	idempotencyKey     string
+	dumpPath           string
+	maxPayloadBytes    int
+	transformers       []Transformer
+	attemptTimeout     time.Duration
+	overallTimeout     time.Duration
+	webhookFormat      WebhookFormat
+}
+
+// SendOption customizes how Send delivers the payload.
+type SendOption func(*sendConfig)
+
+// WithInsecureSkipVerify disables TLS certificate verification. It exists only
+// for lab environments behind self-signed relays and must never be the default.
+func WithInsecureSkipVerify(skip bool) SendOption {
+	return func(c *sendConfig) {
+		c.insecureSkipVerify = skip
+	}
+}
+
+// WithIdempotencyKey attaches key as the IdempotencyKeyHeader on every attempt.
+func WithIdempotencyKey(key string) SendOption {
+	return func(c *sendConfig) {
+		c.idempotencyKey = key
+	}
+}
+
+// WithMaxPayloadBytes limits the serialized payload size. Payloads above the
+// limit have their least essential fields dropped until they fit.
+func WithMaxPayloadBytes(n int) SendOption {
+	return func(c *sendConfig) {
+		c.maxPayloadBytes = n
+	}
+}
+
+// WithAttemptTimeout bounds each HTTP request attempt, from dialing to reading
+// the response body. Zero or less disables the limit.
+func WithAttemptTimeout(d time.Duration) SendOption {
+	return func(c *sendConfig) {
+		c.attemptTimeout = d
+	}
+}
+
+// WithOverallTimeout bounds the whole send, so retries stop once the deadline
+// passes even if attempts remain. Zero or less disables the limit.
+func WithOverallTimeout(d time.Duration) SendOption {
+	return func(c *sendConfig) {
+		c.overallTimeout = d
+	}
+}
+
+// WithTransformers applies transformers to the payload in order right before it
+// is marshaled. They modify the Data passed to Send.
+func WithTransformers(transformers ...Transformer) SendOption {
+	return func(c *sendConfig) {
+		c.transformers = append(c.transformers, transformers...)
+	}
+}
+
+// WithRequestDump writes each outgoing request (request line, headers and body)
+// to path just before it is sent, with credential headers redacted.
+func WithRequestDump(path string) SendOption {
+	return func(c *sendConfig) {
+		c.dumpPath = path
+	}
+}
+
+// sensitiveHeaderFragments mark header names whose values are never dumped.
+var sensitiveHeaderFragments = []string{"authorization", "token", "cookie", "secret", "signature"}
+
+// dumpRequest writes req as it will appear on the wire to path, replacing the
+// values of credential-bearing headers. The request body is left intact.
+func dumpRequest(req *http.Request, path string) error {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("failed to copy request body: %w", err)
+		}
+		clone.Body = body
+	}
+	for name := range clone.Header {
+		lower := strings.ToLower(name)
+		for _, fragment := range sensitiveHeaderFragments {
+			if strings.Contains(lower, fragment) {
+				clone.Header.Set(name, "REDACTED")
+				break
+			}
+		}
+	}
+	dump, err := httputil.DumpRequestOut(clone, true)
+	if err != nil {
+		return fmt.Errorf("failed to dump request: %w", err)
+	}
+	if err := os.WriteFile(path, dump, 0o600); err != nil {
+		return fmt.Errorf("failed to write request dump: %w", err)
+	}
+	return nil
+}
+
+// Clients are shared across Send calls so repeated sends reuse keep-alive
+// connections instead of dialing and handshaking every time. They carry no
+// Timeout of their own; send bounds each attempt through its context.
+var (
+	sharedClient   = &http.Client{Transport: newTransport(false)}
+	insecureClient = sync.OnceValue(func() *http.Client {
+		return &http.Client{Transport: newTransport(true)}
+	})
+)
+
+func newTransport(insecureSkipVerify bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Sends go to one or a few endpoints, so a small idle pool is enough
+	transport.MaxIdleConns = 10
+	transport.MaxIdleConnsPerHost = 2
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.ForceAttemptHTTP2 = true
+	if insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true, //nolint:gosec // explicit opt-in via --insecure
+		}
+	}
+	return transport
+}
+
+func httpClient(cfg *sendConfig) *http.Client {
+	if cfg.insecureSkipVerify {
+		return insecureClient()
+	}
+	return sharedClient
+}
+
+// unixClients holds one client per socket path, shared like sharedClient.
+var unixClients sync.Map
+
+// unixSocketClient returns a client that dials socketPath for every request
+// while still speaking plain HTTP over it.
+func unixSocketClient(socketPath string) *http.Client {
+	if client, ok := unixClients.Load(socketPath); ok {
+		return client.(*http.Client)
+	}
+	transport := newTransport(false)
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socketPath)
+	}
+	client, _ := unixClients.LoadOrStore(socketPath, &http.Client{Transport: transport})
+	return client.(*http.Client)
+}
+
+// resolveEndpoint returns the request URL and client for endpoint. A
+// unix:///path/to.sock endpoint is sent to a local forwarding agent on that
+// socket, using the path of DefaultEndpoint so the agent can relay it as-is.
+// Any other endpoint is used unchanged.
+func resolveEndpoint(endpoint string, cfg *sendConfig) (string, *http.Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "unix" {
+		return endpoint, httpClient(cfg), nil
+	}
+	if u.Path == "" {
+		return "", nil, fmt.Errorf("unix endpoint %q has no socket path", endpoint)
+	}
+	defaultURL, _ := url.Parse(DefaultEndpoint)
+	return "http://localhost" + defaultURL.Path, unixSocketClient(u.Path), nil
+}
+
+// SendResult describes the outcome of a Send call. It is always returned, even
+// when Send fails, so callers can distinguish a rejected request (non-zero
+// StatusCode) from an unreachable endpoint (StatusCode 0).
+type SendResult struct {
+	Success    bool
+	Attempts   int
+	StatusCode int
+	Endpoint   string
+	// Response is nil when the endpoint accepted the payload but returned a
+	// body that could not be parsed.
+	Response *Response
+}
+
+// isRetryableStatus reports whether a failed response may succeed when resent:
+// request timeouts, rate limiting and server errors.
+func isRetryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// maxResponseSnippet bounds how much of a rejection body ends up in the error.
+const maxResponseSnippet = 200
+
+// responseSnippet returns the start of a response body for error messages.
+func responseSnippet(body []byte) string {
+	snippet := strings.TrimSpace(string(body))
+	if len(snippet) > maxResponseSnippet {
+		snippet = snippet[:maxResponseSnippet] + "..."
+	}
+	if snippet == "" {
+		return "empty response body"
+	}
+	return snippet
+}
+
+// Send posts data to endpoint, retrying failures that may be transient. The
+// delivery is traced as a span.
+func Send(ctx context.Context, data *Data, endpoint string, opts ...SendOption) (*SendResult, error) {
+	ctx, span := tracer().Start(ctx, "Send", trace.WithAttributes(attribute.String("endpoint", endpoint)))
+	defer span.End()
+	result, err := send(ctx, data, endpoint, opts...)
+	span.SetAttributes(
+		attribute.Bool("success", result.Success),
+		attribute.Int("attempts", result.Attempts),
+		attribute.Int("http.response.status_code", result.StatusCode),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
+func send(ctx context.Context, data *Data, endpoint string, opts ...SendOption) (*SendResult, error) {
+	cfg := &sendConfig{attemptTimeout: DefaultTimeout, overallTimeout: DefaultOverallTimeout}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.overallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.overallTimeout)
+		defer cancel()
+	}
+
+	result := &SendResult{Endpoint: endpoint}
+
+	ApplyTransformers(data, cfg.transformers...)
+	jsonData, dropped, err := fitPayload(data, cfg.maxPayloadBytes)
+	if err != nil {
+		return result, err
+	}
+	if dropped > 0 {
+		logrus.WithFields(logrus.Fields{"dropped": dropped, "size": len(jsonData), "max": cfg.maxPayloadBytes}).Warn("payload trimmed to fit size limit")
+	}
+
+	contentType := "application/json"
+	if cfg.webhookFormat != nil {
+		if jsonData, contentType, err = cfg.webhookFormat(jsonData); err != nil {
+			return result, fmt.Errorf("failed to format webhook payload: %w", err)
+		}
+	}
+
+	logrus.WithField("endpoint", endpoint).Info("sending data")
+	logrus.WithField("size", len(jsonData)).Debug("request payload")
+
+	target, client, err := resolveEndpoint(endpoint, cfg)
+	if err != nil {
+		return result, err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			delay := time.Duration(attempt-1) * retryDelay
+			logrus.WithFields(logrus.Fields{"attempt": attempt, "max": maxRetries, "delay": delay}).Info("retrying")
+			select {
+			case <-ctx.Done():
+				if lastErr != nil {
+					return result, fmt.Errorf("send cancelled: %w (last attempt: %v)", ctx.Err(), lastErr)
+				}
+				return result, fmt.Errorf("send cancelled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		result.Attempts = attempt
+
+		attemptCtx, cancelAttempt := ctx, context.CancelFunc(func() {})
+		if cfg.attemptTimeout > 0 {
+			attemptCtx, cancelAttempt = context.WithTimeout(ctx, cfg.attemptTimeout)
+		}
+		req, err := http.NewRequestWithContext(attemptCtx, "POST", target, bytes.NewBuffer(jsonData))
+		if err != nil {
+			cancelAttempt()
+			return result, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		// Asking for gzip explicitly keeps decompression in decodeResponseBody
+		// for every transport, including the Unix socket one
+		req.Header.Set("Accept-Encoding", "gzip")
+		if cfg.idempotencyKey != "" {
+			req.Header.Set(IdempotencyKeyHeader, cfg.idempotencyKey)
+		}
+		if cfg.dumpPath != "" {
+			if err := dumpRequest(req, cfg.dumpPath); err != nil {
+				logrus.WithError(err).Warn("failed to dump request")
+			} else {
+				logrus.WithField("path", cfg.dumpPath).Debug("request dumped")
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			cancelAttempt()
+			lastErr = fmt.Errorf("failed to send request: %w", err)
+			logrus.WithField("attempt", attempt).WithError(lastErr).Warn("attempt failed")
+			continue
+		}
+		result.StatusCode = resp.StatusCode
+
+		// Reading the body to EOF before closing lets the connection be reused
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		cancelAttempt()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response: %w", err)
+			logrus.WithField("attempt", attempt).WithError(lastErr).Warn("attempt failed")
+			continue
+		}
+		if decoded, err := decodeResponseBody(resp.Header, body); err != nil {
+			// The body stays compressed, so parsing it below degrades gracefully
+			logrus.WithError(err).Warn("failed to decompress response")
+		} else {
+			body = decoded
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			if !isRetryableStatus(resp.StatusCode) {
+				// Resending the same payload cannot fix a client error
+				return result, fmt.Errorf("endpoint rejected payload with %d %s: %s",
+					resp.StatusCode, http.StatusText(resp.StatusCode), responseSnippet(body))
+			}
+			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			logrus.WithField("attempt", attempt).WithError(lastErr).Warn("attempt failed")
+			continue
+		}
+
+		result.Success = true
+
+		var response Response
+		if err := json.Unmarshal(body, &response); err != nil {
+			logrus.WithError(err).Warn("failed to parse response")
+			logrus.WithField("attempt", attempt).Info("data sent")
+			return result, nil
+		}
+
+		logrus.WithFields(logrus.Fields{"versions": len(response.Versions), "intervalMinutes": response.RequestIntervalInMinutes}).Info("response received")
+		for _, v := range response.Versions {
+			logrus.WithFields(logrus.Fields{"name": v.Name, "releaseDate": v.ReleaseDate, "tags": v.Tags}).Info("available version")
+			if len(v.ExtraInfo) > 0 {
+				logrus.WithField("extraInfo", v.ExtraInfo).Info("version extra info")
+			}
+		}
+
+		logrus.WithField("attempt", attempt).Info("data sent")
+		result.Response = &response
+		return result, nil
+	}
+
+	return result, lastErr
+}
+
+// decodeResponseBody undoes a gzip Content-Encoding of a response body. Other
+// bodies are returned unchanged.
+func decodeResponseBody(header http.Header, body []byte) ([]byte, error) {
+	if !strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+		return body, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("gzip response: %w", err)
+	}
+	defer zr.Close()
+	decoded, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("gzip response: %w", err)
+	}
+	return decoded, nil
+}
+
+// workloadCache lists DaemonSets and Deployments at most once per namespace so
+// that detectors matching against the same namespace share a single API call.
+// Forbidden list errors are cached as well, so a denied namespace is not
+// retried by every detector. The cache is shared by collectors, including
+// abandoned ones that may still be listing, so its maps are guarded by mu.
+type workloadCache struct {
+	clientset       kubernetes.Interface
+	mu              sync.Mutex
+	daemonSetsByNS  map[string]workloadList[appsv1.DaemonSet]
+	deploymentsByNS map[string]workloadList[appsv1.Deployment]
+}
+
+type workloadList[T any] struct {
+	items []T
+	err   error
+}
+
+func newWorkloadCache(clientset kubernetes.Interface) *workloadCache {
+	return &workloadCache{
+		clientset:       clientset,
+		daemonSetsByNS:  make(map[string]workloadList[appsv1.DaemonSet]),
+		deploymentsByNS: make(map[string]workloadList[appsv1.Deployment]),
+	}
+}
+
+func (c *workloadCache) daemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	c.mu.Lock()
+	cached, ok := c.daemonSetsByNS[namespace]
+	c.mu.Unlock()
+	if ok {
+		if cached.err != nil {
+			recordFailure(ctx)
+		}
+		return cached.items, cached.err
+	}
+	list, err := c.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		recordDenial(ctx, err)
+		cached.err = err
+		if !apierrors.IsForbidden(err) {
+			// Not cached, so a later collector can retry after a timeout or transient failure
+			recordFailure(ctx)
+			return nil, err
+		}
+	} else {
+		cached.items = list.Items
+	}
+	c.mu.Lock()
+	c.daemonSetsByNS[namespace] = cached
+	c.mu.Unlock()
+	return cached.items, cached.err
+}
+
+func (c *workloadCache) deployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+	c.mu.Lock()
+	cached, ok := c.deploymentsByNS[namespace]
+	c.mu.Unlock()
+	if ok {
+		if cached.err != nil {
+			recordFailure(ctx)
+		}
+		return cached.items, cached.err
+	}
+	list, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		recordDenial(ctx, err)
+		cached.err = err
+		if !apierrors.IsForbidden(err) {
+			// Not cached, so a later collector can retry after a timeout or transient failure
+			recordFailure(ctx)
+			return nil, err
+		}
+	} else {
+		cached.items = list.Items
+	}
+	c.mu.Lock()
+	c.deploymentsByNS[namespace] = cached
+	c.mu.Unlock()
+	return cached.items, cached.err
+}
+
+// forEachNode lists nodes in pages of nodeListPageSize and calls fn for each one,
+// so only a single page of Node objects is held in memory at a time.
+func forEachNode(ctx context.Context, clientset kubernetes.Interface, fn func(*corev1.Node)) error {
+	opts := metav1.ListOptions{Limit: nodeListPageSize}
+	for {
+		nodes, err := clientset.CoreV1().Nodes().List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for i := range nodes.Items {
+			fn(&nodes.Items[i])
+		}
+		if nodes.Continue == "" {
+			return nil
+		}
+		opts.Continue = nodes.Continue
+	}
+}
+
+// eolOSImages are OSImage prefixes (as reported by the kubelet from
+// /etc/os-release PRETTY_NAME) of operating systems past their end of
+// standard support.
+var eolOSImages = []string{
+	"Ubuntu 16.04",
+	"Ubuntu 18.04",
+	"Ubuntu 20.04",
+	"CentOS Linux 7",
+	"CentOS Linux 8",
+	"CentOS Stream 8",
+	"Red Hat Enterprise Linux Server 7",
+	"SUSE Linux Enterprise Server 12",
+	"Debian GNU/Linux 9",
+	"Debian GNU/Linux 10",
+	"Amazon Linux 2018",
+}
+
+func isEOLOSImage(osImage string) bool {
+	for _, prefix := range eolOSImages {
+		if strings.HasPrefix(osImage, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Values of kernel-risk.
+const (
+	kernelRiskVulnerable = "known-vulnerable"
+	kernelRiskOK         = "ok"
+	kernelRiskUnknown    = "unknown"
+)
+
+// vulnerableKernel is an upstream kernel version range [introduced, fixed)
+// affected by a container-escape CVE. Each stable branch that received the fix
+// gets its own range.
+type vulnerableKernel struct {
+	cve        string
+	introduced *version.Version
+	fixed      *version.Version
+}
+
+// vulnerableKernels lists notable container-escape CVEs by upstream version.
+var vulnerableKernels = []vulnerableKernel{
+	// Dirty Pipe: overwriting read-only files, including host binaries
+	{"CVE-2022-0847", version.MustParseGeneric("5.8.0"), version.MustParseGeneric("5.10.102")},
+	{"CVE-2022-0847", version.MustParseGeneric("5.11.0"), version.MustParseGeneric("5.15.25")},
+	{"CVE-2022-0847", version.MustParseGeneric("5.16.0"), version.MustParseGeneric("5.16.11")},
+	// fsconfig heap overflow, exploitable from an unprivileged user namespace
+	{"CVE-2022-0185", version.MustParseGeneric("5.1.0"), version.MustParseGeneric("5.4.173")},
+	{"CVE-2022-0185", version.MustParseGeneric("5.5.0"), version.MustParseGeneric("5.10.93")},
+	{"CVE-2022-0185", version.MustParseGeneric("5.11.0"), version.MustParseGeneric("5.15.16")},
+	{"CVE-2022-0185", version.MustParseGeneric("5.16.0"), version.MustParseGeneric("5.16.2")},
+}
+
+// kernelReleaseRe splits a kernel release into its upstream version and the
+// rest, e.g. "5.15.25" and "-flatcar".
+var kernelReleaseRe = regexp.MustCompile(`^v?(\d+\.\d+(?:\.\d+)?)(.*)$`)
+
+// distroBuildRe matches the package build number distributions append, as in
+// 5.15.0-91-generic, 4.18.0-477.10.1.el8_8.x86_64 or 5.14.21-150400.24.46-default.
+var distroBuildRe = regexp.MustCompile(`^-\d`)
+
+// kernelRisk classifies a node's kernel release against vulnerableKernels.
+// Distribution kernels backport fixes without changing the upstream version,
+// so a release carrying a distro build number cannot be judged and is
+// unknown; only upstream-versioned kernels (vanilla, Flatcar, Talos, ...) are.
+func kernelRisk(release string) string {
+	m := kernelReleaseRe.FindStringSubmatch(strings.TrimSpace(release))
+	if m == nil || distroBuildRe.MatchString(m[2]) {
+		return kernelRiskUnknown
+	}
+	v, err := version.ParseGeneric(m[1])
+	if err != nil {
+		return kernelRiskUnknown
+	}
+	for _, k := range vulnerableKernels {
+		if v.AtLeast(k.introduced) && v.LessThan(k.fixed) {
+			logrus.WithFields(logrus.Fields{"kernel": release, "cve": k.cve}).Debug("kernel in a known-vulnerable range")
+			return kernelRiskVulnerable
+		}
+	}
+	return kernelRiskOK
+}
+
+func isControlPlaneNode(node *corev1.Node) bool {
+	_, hasControlPlaneLabel := node.Labels["node-role.kubernetes.io/control-plane"]
+	_, hasMasterLabel := node.Labels["node-role.kubernetes.io/master"]
+	return hasControlPlaneLabel || hasMasterLabel
+}
+
+// isolationTaintKeys keep workloads off control-plane nodes: the upstream role
+// taints, and the CriticalAddonsOnly taint the RKE2 docs recommend for
+// dedicated servers.
+var isolationTaintKeys = map[string]bool{
+	"node-role.kubernetes.io/control-plane": true,
+	"node-role.kubernetes.io/master":        true,
+	"CriticalAddonsOnly":                    true,
+}
+
+// hasIsolationTaint reports whether node repels ordinary workloads with a
+// NoSchedule or NoExecute taint on one of isolationTaintKeys.
+func hasIsolationTaint(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if isolationTaintKeys[taint.Key] &&
+			(taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKubeletAhead reports whether kubelet is newer than the apiserver, which the
+// version skew policy never allows. Build metadata such as "+rke2r1" is
+// ignored; unparsable versions are not treated as skew.
+func isKubeletAhead(apiserver *version.Version, kubelet string) bool {
+	if apiserver == nil || kubelet == "" {
+		return false
+	}
+	kubeletVersion, err := version.ParseSemantic(kubelet)
+	if err != nil {
+		logrus.WithError(err).WithField("kubelet", kubelet).Debug("failed to parse kubelet version")
+		return false
+	}
+	return kubeletVersion.GreaterThan(apiserver)
+}
+
+// cgroupLabels may carry a node's cgroup version. Neither the kubelet nor
+// NodeInfo expose it, so only nodes labeled by the provisioner or an operator
+// are recognized.
+var cgroupLabels = []string{"node.kubernetes.io/cgroup", "node.kubernetes.io/cgroup-version"}
+
+// nodeCgroupVersion returns "v1" or "v2" from node's cgroupLabels, accepting
+// values like "v2", "2" or "cgroupv2", or "" if the node carries no hint.
+func nodeCgroupVersion(node *corev1.Node) string {
+	for _, label := range cgroupLabels {
+		value := strings.ToLower(strings.TrimSpace(node.Labels[label]))
+		value = strings.TrimPrefix(strings.TrimPrefix(value, "cgroup"), "v")
+		switch value {
+		case "1":
+			return "v1"
+		case "2":
+			return "v2"
+		}
+	}
+	return ""
+}
+
+// cgroupVersion summarizes the labeled nodes' cgroup versions. Any v1 node
+// makes the cluster "v1", since it lacks the v2-only isolation features.
+func cgroupVersion(versions map[string]bool) string {
+	switch {
+	case versions["v1"]:
+		return "v1"
+	case versions["v2"]:
+		return "v2"
+	default:
+		return "unknown"
+	}
+}
+
+// getSELinuxStatus determines SELinux status from node labels.
+// SELinux detection is limited from within containers; this is a best-effort
+// approach. Returns "unknown" if not determinable.
+func getSELinuxStatus(node *corev1.Node) string {
+	if selinux, ok := node.Labels["security.alpha.kubernetes.io/selinux"]; ok {
+		if selinux == "enabled" {
+			return "enabled"
+		}
+		return "disabled"
+	}
+	return "unknown"
+}
+
+func extractImageVersion(image string) string {
+	if idx := strings.LastIndex(image, ":"); idx != -1 {
+		tag := image[idx+1:]
+		if atIdx := strings.Index(tag, "@"); atIdx != -1 {
+			tag = tag[:atIdx]
+		}
+		return tag
+	}
+	return ""
+}
+
+// containerImageVersion returns the image version of the first container whose
+// image contains one of imageFragments, falling back to the first container.
+// This keeps injected sidecars from being reported as the workload's version.
+func containerImageVersion(containers []corev1.Container, imageFragments ...string) string {
+	for _, c := range containers {
+		image := strings.ToLower(c.Image)
+		for _, fragment := range imageFragments {
+			if strings.Contains(image, fragment) {
+				return extractImageVersion(c.Image)
+			}
+		}
+	}
+	if len(containers) > 0 {
+		return extractImageVersion(containers[0].Image)
+	}
+	return ""
+}
+
+// cniPatterns maps DaemonSet name fragments to CNI plugin names, in match order.
+// image identifies the container whose tag is reported as the plugin version.
+var cniPatterns = []struct {
+	pattern string
+	name    string
+	image   string
+}{
+	{"canal", "canal", "calico"},
+	{"flannel", "flannel", "flannel"},
+	{"calico", "calico", "calico"},
+	{"cilium", "cilium", "cilium"},
+	{"weave", "weave", "weave"},
+}
+
+// Default RKE2 host directories. A hostPath below an rke2 directory anywhere
+// else points at a relocated data-dir.
+const (
+	defaultRKE2DataDir   = "/var/lib/rancher/rke2"
+	defaultRKE2ConfigDir = "/etc/rancher/rke2"
+)
+
+// detectCustomDataDir reports whether a CNI or kube-proxy DaemonSet mounts a
+// hostPath below an "rke2" directory other than defaultRKE2DataDir (or the
+// config directory), which is how a relocated --data-dir shows up without host
+// access. Finding no such mount is not proof of the default: most DaemonSets
+// only mount /opt/cni or /etc/cni.
+func detectCustomDataDir(daemonSets []appsv1.DaemonSet) bool {
+	for _, ds := range daemonSets {
+		name := strings.ToLower(ds.Name)
+		relevant := strings.Contains(name, "kube-proxy")
+		for _, p := range cniPatterns {
+			relevant = relevant || strings.Contains(name, p.pattern)
+		}
+		if !relevant {
+			continue
+		}
+		for _, volume := range ds.Spec.Template.Spec.Volumes {
+			if volume.HostPath == nil {
+				continue
+			}
+			if dir, ok := rke2HostDir(volume.HostPath.Path); ok && dir != defaultRKE2DataDir && dir != defaultRKE2ConfigDir {
+				logrus.WithFields(logrus.Fields{"daemonset": ds.Name, "path": volume.HostPath.Path}).Debug("hostPath below a non-default rke2 directory")
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rke2HostDir returns the leading part of hostPath up to its first "rke2"
+// component, e.g. "/data/rke2" for "/data/rke2/agent/etc".
+func rke2HostDir(hostPath string) (string, bool) {
+	parts := strings.Split(path.Clean(hostPath), "/")
+	for i, part := range parts {
+		if part == "rke2" {
+			return strings.Join(parts[:i+1], "/"), true
+		}
+	}
+	return "", false
+}
+
+// detectCNIPlugin scans all DaemonSets for known CNI plugins. The first match is
+// reported as the primary plugin along with its image version; detected lists
+// every distinct plugin found so leftovers from a botched migration can be flagged.
+func detectCNIPlugin(daemonSets []appsv1.DaemonSet) (plugin, version string, detected []string) {
+	plugin = "unknown"
+	seen := make(map[string]bool)
+	for _, ds := range daemonSets {
+		name := strings.ToLower(ds.Name)
+		for _, p := range cniPatterns {
+			if !strings.Contains(name, p.pattern) {
+				continue
+			}
+			if len(detected) == 0 {
+				plugin = p.name
+				version = containerImageVersion(ds.Spec.Template.Spec.Containers, p.image)
+			}
+			if !seen[p.name] {
+				seen[p.name] = true
+				detected = append(detected, p.name)
+			}
+			break
+		}
+	}
+
+	return plugin, version, detected
+}
+
+// flannelConfigMaps hold the flannel net-conf.json used by canal and flannel.
+var flannelConfigMaps = []string{"rke2-canal-config", "kube-flannel-cfg"}
+
+// corednsConfigMaps hold the CoreDNS Corefile, as named by the rke2-coredns
+// chart and by upstream installs.
+var corednsConfigMaps = []string{"rke2-coredns-rke2-coredns", "rke2-coredns", "coredns"}
+
+// defaultClusterDomain is the cluster domain RKE2 configures unless
+// --cluster-domain is set.
+const defaultClusterDomain = "cluster.local"
+
+// detectClusterDomain returns the cluster domain served by CoreDNS's
+// kubernetes plugin. Returns defaultClusterDomain when no Corefile can be
+// read or it names no domain.
+func detectClusterDomain(ctx context.Context, clientset kubernetes.Interface) string {
+	for _, name := range corednsConfigMaps {
+		cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			warnAPIError(ctx, err, "failed to get coredns configmap")
+			return defaultClusterDomain
+		}
+		if domain := corefileClusterDomain(cm.Data["Corefile"]); domain != "" {
+			return domain
+		}
+		return defaultClusterDomain
+	}
+	return defaultClusterDomain
+}
+
+// corefileClusterDomain returns the first zone of the kubernetes plugin in
+// corefile that is not a reverse zone, e.g. cluster.local in
+// "kubernetes cluster.local in-addr.arpa ip6.arpa {".
+func corefileClusterDomain(corefile string) string {
+	for _, line := range strings.Split(corefile, "\n") {
+		args := strings.Fields(line)
+		if len(args) == 0 || args[0] != "kubernetes" {
+			continue
+		}
+		for _, zone := range args[1:] {
+			if zone == "{" {
+				break
+			}
+			zone = strings.TrimSuffix(zone, ".")
+			if zone != "" && !strings.HasSuffix(zone, ".arpa") {
+				return zone
+			}
+		}
+	}
+	return ""
+}
+
+// detectCNIEncryption reports whether the primary CNI plugin encrypts traffic
+// between nodes: "wireguard", "ipsec" or "none". Returns "unknown" when the
+// plugin's configuration cannot be read or the plugin is not supported.
+func detectCNIEncryption(ctx context.Context, clientset kubernetes.Interface, plugin string, daemonSets []appsv1.DaemonSet) string {
+	switch plugin {
+	case "cilium":
+		cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(ctx, "cilium-config", metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				warnAPIError(ctx, err, "failed to get cilium configmap")
+			}
+			return "unknown"
+		}
+		switch {
+		case strings.EqualFold(cm.Data["enable-wireguard"], "true"):
+			return "wireguard"
+		case strings.EqualFold(cm.Data["enable-ipsec"], "true"):
+			return "ipsec"
+		}
+		return "none"
+	case "canal", "calico":
+		if felixWireguardEnabled(daemonSets) {
+			return "wireguard"
+		}
+		if plugin == "calico" {
+			// Felix is usually configured through FelixConfiguration resources,
+			// which are not readable with the core clientset.
+			return "unknown"
+		}
+		fallthrough
+	case "flannel":
+		for _, name := range flannelConfigMaps {
+			cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				warnAPIError(ctx, err, "failed to get flannel configmap")
+				return "unknown"
+			}
+			return flannelBackendEncryption(cm.Data["net-conf.json"])
+		}
+	}
+	return "unknown"
+}
+
+// felixWireguardEnabled reports whether a Calico node DaemonSet enables
+// WireGuard through the FELIX_WIREGUARDENABLED environment variable.
+func felixWireguardEnabled(daemonSets []appsv1.DaemonSet) bool {
+	for _, ds := range daemonSets {
+		name := strings.ToLower(ds.Name)
+		if !strings.Contains(name, "calico") && !strings.Contains(name, "canal") {
+			continue
+		}
+		for _, c := range ds.Spec.Template.Spec.Containers {
+			for _, env := range c.Env {
+				if env.Name == "FELIX_WIREGUARDENABLED" && strings.EqualFold(env.Value, "true") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// flannelBackendEncryption maps the backend type in a flannel net-conf.json to
+// the encryption it provides.
+func flannelBackendEncryption(netConf string) string {
+	var conf struct {
+		Backend struct {
+			Type string
+		}
+	}
+	if err := json.Unmarshal([]byte(netConf), &conf); err != nil {
+		return "unknown"
+	}
+	switch strings.ToLower(conf.Backend.Type) {
+	case "wireguard":
+		return "wireguard"
+	case "ipsec":
+		return "ipsec"
+	}
+	return "none"
+}
+
+// ingressImages identifies the controller container of each ingress controller.
+var ingressImages = map[string][]string{
+	"rke2-ingress-nginx": {"nginx-ingress", "ingress-nginx"},
+	"traefik":            {"traefik"},
+}
+
+func detectIngressController(deployments []appsv1.Deployment, daemonSets []appsv1.DaemonSet) (string, string) {
+	for _, deploy := range deployments {
+		name := strings.ToLower(deploy.Name)
+		var ingressName string
+		switch {
+		case strings.Contains(name, "nginx-ingress"), strings.Contains(name, "rke2-ingress-nginx"):
+			ingressName = "rke2-ingress-nginx"
+		case strings.Contains(name, "traefik"):
+			ingressName = "traefik"
+		}
+		if ingressName != "" {
+			return ingressName, containerImageVersion(deploy.Spec.Template.Spec.Containers, ingressImages[ingressName]...)
+		}
+	}
+
+	for _, ds := range daemonSets {
+		name := strings.ToLower(ds.Name)
+		var ingressName string
+		switch {
+		case strings.Contains(name, "nginx-ingress"), strings.Contains(name, "rke2-ingress-nginx"):
+			ingressName = "rke2-ingress-nginx"
+		case strings.Contains(name, "traefik"):
+			ingressName = "traefik"
+		}
+		if ingressName != "" {
+			return ingressName, containerImageVersion(ds.Spec.Template.Spec.Containers, ingressImages[ingressName]...)
+		}
+	}
+
+	return "none", ""
+}
+
+// detectIngressTLS counts Ingresses cluster-wide and how many of them configure
+// TLS termination in a tls block. Returns -1 counts if Ingresses cannot be listed.
+func detectIngressTLS(ctx context.Context, clientset kubernetes.Interface) (count, withTLS int) {
+	ingresses, err := clientset.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list ingresses")
+		return -1, -1
+	}
+	for _, ingress := range ingresses.Items {
+		if len(ingress.Spec.TLS) > 0 {
+			withTLS++
+		}
+	}
+	return len(ingresses.Items), withTLS
+}
+
+// detectIngressWAF reports whether ModSecurity is enabled on rke2-ingress-nginx
+// through its controller ConfigMap. Returns "none" if the ConfigMap is absent or
+// does not enable it, and "unknown" if the ConfigMap cannot be read.
+func detectIngressWAF(ctx context.Context, clientset kubernetes.Interface) string {
+	cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(ctx, "rke2-ingress-nginx-controller", metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "none"
+	}
+	if err != nil {
+		warnAPIError(ctx, err, "failed to get ingress-nginx configmap")
+		return "unknown"
+	}
+	for _, key := range []string{"enable-modsecurity", "enable-owasp-modsecurity-crs"} {
+		if strings.EqualFold(cm.Data[key], "true") {
+			return "modsecurity"
+		}
+	}
+	return "none"
+}
+
+// RKE2 does not record when secrets-encryption keys were last rotated, so the
+// key age is only known when rotation automation stamps an RFC 3339 timestamp
+// in the encryptionRotatedAtAnnotation of the kube-system namespace, or in the
+// rotated-at key of the encryptionRotationConfigMap in kube-system.
+const (
+	encryptionRotatedAtAnnotation = "rke2.cattle.io/secrets-encryption-rotated-at"
+	encryptionRotationConfigMap   = "rke2-secrets-encryption-rotation"
+)
+
+// detectEncryptionRotation returns the most recent recorded secrets-encryption
+// key rotation. ok is false when no indicator exists or none parses.
+func detectEncryptionRotation(ctx context.Context, clientset kubernetes.Interface, kubeSystem *corev1.Namespace) (rotatedAt time.Time, ok bool) {
+	candidates := []string{kubeSystem.Annotations[encryptionRotatedAtAnnotation]}
+	cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(ctx, encryptionRotationConfigMap, metav1.GetOptions{})
+	if err == nil {
+		candidates = append(candidates, cm.Data["rotated-at"])
+	} else if !apierrors.IsNotFound(err) {
+		warnAPIError(ctx, err, "failed to get secrets-encryption rotation configmap")
+	}
+	for _, value := range candidates {
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+		if err != nil {
+			logrus.WithError(err).Debug("ignoring unparseable secrets-encryption rotation timestamp")
+			continue
+		}
+		if !ok || t.After(rotatedAt) {
+			rotatedAt, ok = t, true
+		}
+	}
+	return rotatedAt, ok
+}
+
+// kubeBenchNamespaces are searched in order for a kube-bench-results ConfigMap.
+var kubeBenchNamespaces = []string{"kube-system", "kube-bench", "default"}
+
+// detectKubeBench summarizes stored kube-bench output from a kube-bench-results
+// ConfigMap. found is false when no such ConfigMap exists or none of its values
+// contains a kube-bench summary.
+func detectKubeBench(ctx context.Context, clientset kubernetes.Interface) (found bool, pass, fail int) {
+	for _, ns := range kubeBenchNamespaces {
+		cm, err := clientset.CoreV1().ConfigMaps(ns).Get(ctx, "kube-bench-results", metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				warnAPIError(ctx, err, "failed to get kube-bench configmap in "+ns)
+			}
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(cm.Data)) {
+			if pass, fail, ok := parseKubeBenchSummary(cm.Data[key]); ok {
+				return true, pass, fail
+			}
+		}
+	}
+	return false, 0, 0
+}
+
+// kubeBenchCountRe matches kube-bench summary lines such as "42 checks PASS".
+var kubeBenchCountRe = regexp.MustCompile(`^(\d+) checks (PASS|FAIL)$`)
+
+// parseKubeBenchSummary extracts PASS and FAIL counts from kube-bench text
+// output. The "== Summary total ==" block is used when present; otherwise the
+// per-section summaries are added up.
+func parseKubeBenchSummary(output string) (pass, fail int, ok bool) {
+	var sectionPass, sectionFail, totalPass, totalFail int
+	inTotal, hasTotal := false, false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "== Summary") {
+			inTotal = line == "== Summary total =="
+			hasTotal = hasTotal || inTotal
+			continue
+		}
+		m := kubeBenchCountRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		ok = true
+		n, _ := strconv.Atoi(m[1])
+		switch {
+		case inTotal && m[2] == "PASS":
+			totalPass += n
+		case inTotal:
+			totalFail += n
+		case m[2] == "PASS":
+			sectionPass += n
+		default:
+			sectionFail += n
+		}
+	}
+	if hasTotal {
+		return totalPass, totalFail, ok
+	}
+	return sectionPass, sectionFail, ok
+}
+
+// detectSystemPullPolicy aggregates the effective imagePullPolicy of every
+// container in the kube-system Deployments and DaemonSets into "always",
+// "ifnotpresent" (which includes Never) or "mixed". Returns "unknown" when
+// there are no containers to inspect.
+func detectSystemPullPolicy(deployments []appsv1.Deployment, daemonSets []appsv1.DaemonSet) string {
+	var always, other int
+	count := func(containers []corev1.Container) {
+		for _, c := range containers {
+			if effectivePullPolicy(c) == corev1.PullAlways {
+				always++
+			} else {
+				other++
+			}
+		}
+	}
+	for _, deploy := range deployments {
+		count(deploy.Spec.Template.Spec.Containers)
+	}
+	for _, ds := range daemonSets {
+		count(ds.Spec.Template.Spec.Containers)
+	}
+
+	switch {
+	case always > 0 && other > 0:
+		return "mixed"
+	case always > 0:
+		return "always"
+	case other > 0:
+		return "ifnotpresent"
+	default:
+		return "unknown"
+	}
+}
+
+// rke2ImageFragment marks images built by RKE2 (rancher/hardened-kubernetes,
+// rancher/hardened-coredns, ...). FIPS builds tag these with a -fips suffix.
+const rke2ImageFragment = "rancher/hardened-"
+
+// detectFIPSMode inspects the RKE2-built images among the kube-system
+// Deployments and DaemonSets and returns "fips" if any is tagged -fips,
+// "standard" if none is, or "unknown" when no RKE2-built image is found.
+func detectFIPSMode(deployments []appsv1.Deployment, daemonSets []appsv1.DaemonSet) string {
+	var rke2Images, fipsImages int
+	inspect := func(containers []corev1.Container) {
+		for _, c := range containers {
+			if !strings.Contains(c.Image, rke2ImageFragment) {
+				continue
+			}
+			rke2Images++
+			if strings.Contains(extractImageVersion(c.Image), "-fips") {
+				fipsImages++
+			}
+		}
+	}
+	for _, deploy := range deployments {
+		inspect(deploy.Spec.Template.Spec.Containers)
+	}
+	for _, ds := range daemonSets {
+		inspect(ds.Spec.Template.Spec.Containers)
+	}
+
+	switch {
+	case fipsImages > 0:
+		return "fips"
+	case rke2Images > 0:
+		return "standard"
+	default:
+		return "unknown"
+	}
+}
+
+// effectivePullPolicy applies the apiserver defaulting rules when no policy is
+// set: Always for untagged or :latest images, IfNotPresent otherwise.
+func effectivePullPolicy(c corev1.Container) corev1.PullPolicy {
+	if c.ImagePullPolicy != "" {
+		return c.ImagePullPolicy
+	}
+	if strings.Contains(c.Image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	if tag := extractImageVersion(c.Image); tag == "" || tag == "latest" || strings.Contains(tag, "/") {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+// detectDegradedWorkloads returns the sorted names of the Deployments with
+// fewer ready replicas than desired and the DaemonSets with fewer ready pods
+// than scheduled. Workloads scaled to zero are never degraded.
+func detectDegradedWorkloads(deployments []appsv1.Deployment, daemonSets []appsv1.DaemonSet) []string {
+	var degraded []string
+	for _, deploy := range deployments {
+		desired := int32(1)
+		if deploy.Spec.Replicas != nil {
+			desired = *deploy.Spec.Replicas
+		}
+		if deploy.Status.ReadyReplicas < desired {
+			degraded = append(degraded, deploy.Name)
+		}
+	}
+	for _, ds := range daemonSets {
+		if ds.Status.NumberReady < ds.Status.DesiredNumberScheduled {
+			degraded = append(degraded, ds.Name)
+		}
+	}
+	slices.Sort(degraded)
+	return degraded
+}
+
+// detectSnapshotController looks for the CSI snapshot controller shipped by RKE2
+// (rke2-snapshot-controller) or installed upstream (snapshot-controller).
+func detectSnapshotController(deployments []appsv1.Deployment) (bool, string) {
+	for _, deploy := range deployments {
+		if !strings.Contains(strings.ToLower(deploy.Name), "snapshot-controller") {
+			continue
+		}
+		return true, containerImageVersion(deploy.Spec.Template.Spec.Containers, "snapshot-controller")
+	}
+	return false, ""
+}
+
+// etcdSnapshotFileResource is RKE2's record of each etcd snapshot, local or S3.
+var etcdSnapshotFileResource = schema.GroupVersionResource{Group: "k3s.cattle.io", Version: "v1", Resource: "etcdsnapshotfiles"}
+
+// etcdSnapshotConfigMap is the older snapshot record, keyed "s3-<name>" for
+// snapshots stored on S3.
+const etcdSnapshotConfigMap = "rke2-etcd-snapshots"
+
+// detectEtcdS3Snapshots reports whether any etcd snapshot was uploaded to S3,
+// from ETCDSnapshotFiles with an s3 spec (when a dynamic client is available)
+// or the snapshot ConfigMap in kube-system. found is false when neither
+// record exists. The S3 credentials Secret is deliberately not read.
+func detectEtcdS3Snapshots(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface) (found, s3 bool) {
+	cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(ctx, etcdSnapshotConfigMap, metav1.GetOptions{})
+	if err == nil {
+		found = true
+		for key := range cm.Data {
+			if strings.HasPrefix(key, "s3-") {
+				s3 = true
+			}
+		}
+	} else if !apierrors.IsNotFound(err) {
+		warnAPIError(ctx, err, "failed to get etcd snapshot configmap")
+	}
+
+	gv := etcdSnapshotFileResource.GroupVersion().String()
+	if dynamicClient == nil || !hasAPIResource(clientset, gv, etcdSnapshotFileResource.Resource) {
+		return found, s3
+	}
+	snapshots, err := dynamicClient.Resource(etcdSnapshotFileResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list etcdsnapshotfiles")
+		return found, s3
+	}
+	found = true
+	for _, snapshot := range snapshots.Items {
+		if spec, ok, _ := unstructured.NestedMap(snapshot.Object, "spec", "s3"); ok && spec != nil {
+			s3 = true
+		}
+	}
+	return found, s3
+}
+
+// hasAPIResource reports whether the apiserver serves resource in groupVersion.
+// Discovery errors, including the group not being installed, are treated as absent.
+func hasAPIResource(clientset kubernetes.Interface, groupVersion, resource string) bool {
+	resources, err := clientset.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		return false
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func detectGPUOperator(ctx context.Context, workloads *workloadCache) (string, string) {
 	gpuNamespaces := map[string]string{
 		"gpu-operator":              "nvidia-gpu-operator",
 		"kube-amd-gpu":              "amd-gpu-operator",
@@ -393,57 +2417,1205 @@ func detectGPUOperator(ctx context.Context, clientset kubernetes.Interface) (str
 	}
 
 	for ns, operator := range gpuNamespaces {
-		daemonSets, err := clientset.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+		daemonSets, err := workloads.daemonSets(ctx, ns)
+		if err != nil {
+			continue
+		}
+		for _, ds := range daemonSets {
+			name := strings.ToLower(ds.Name)
+			if strings.Contains(name, "device-plugin") || strings.Contains(name, "driver") {
+				return operator, containerImageVersion(ds.Spec.Template.Spec.Containers, "device-plugin", "driver")
+			}
+		}
+	}
+
+	return "none", ""
+}
+
+// detectRancherWebhook returns the image version of the rancher-webhook
+// Deployment in cattle-system, which enforces Rancher's RBAC and PSA
+// admission rules, or "" when it is not installed.
+func detectRancherWebhook(ctx context.Context, clientset kubernetes.Interface) string {
+	deploy, err := clientset.AppsV1().Deployments("cattle-system").Get(ctx, "rancher-webhook", metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			warnAPIError(ctx, err, "failed to get rancher-webhook deployment")
+		}
+		return ""
+	}
+	return containerImageVersion(deploy.Spec.Template.Spec.Containers, "rancher-webhook")
+}
+
+func detectRancherManager(ctx context.Context, clientset kubernetes.Interface) (managed bool, version, installUUID, role string) {
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, "cattle-system", metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			warnAPIError(ctx, err, "failed to get cattle-system namespace")
+		}
+		return false, "", "", ""
+	}
+
+	// The Rancher server and its management.cattle.io clusters API only exist
+	// in the local (management) cluster.
+	role = "unknown"
+	if _, err := clientset.AppsV1().Deployments("cattle-system").Get(ctx, "rancher", metav1.GetOptions{}); err == nil {
+		role = "local"
+	} else {
+		if !apierrors.IsNotFound(err) {
+			warnAPIError(ctx, err, "failed to get rancher deployment")
+		}
+		if hasAPIResource(clientset, "management.cattle.io/v3", "clusters") {
+			role = "local"
+		}
+	}
+
+	deploy, err := clientset.AppsV1().Deployments("cattle-system").Get(ctx, "cattle-cluster-agent", metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			warnAPIError(ctx, err, "failed to get cattle-cluster-agent deployment")
+		}
+		return true, "", "", role
+	}
+
+	// Sidecars such as service-mesh proxies may be injected ahead of the agent,
+	// so match the agent by image and look for its env in every container.
+	containers := deploy.Spec.Template.Spec.Containers
+	version = containerImageVersion(containers, "rancher-agent")
+	var clusterName string
+	for _, container := range containers {
+		for _, env := range container.Env {
+			switch {
+			case env.Name == "CATTLE_INSTALL_UUID" && installUUID == "":
+				installUUID = env.Value
+			case env.Name == "CATTLE_CLUSTER_NAME" && clusterName == "":
+				clusterName = env.Value
+			}
+		}
+	}
+	// A cluster agent without the Rancher server points at a remote Rancher
+	if role == "unknown" {
+		if clusterName == "local" {
+			role = "local"
+		} else {
+			role = "downstream"
+		}
+	}
+	return true, version, installUUID, role
+}
+
+// resolveClusterUUID returns the value of the first source in sources that has
+// one, and the name of that source. Without any it returns "" and "none".
+func resolveClusterUUID(sources []string, values map[string]string) (uuid, source string) {
+	for _, source := range sources {
+		if values[source] != "" {
+			return values[source], source
+		}
+	}
+	return "", "none"
+}
+
+// clusterIdentity combines the cluster UUID with the Rancher install UUID, if
+// any, so the endpoint can correlate a cluster across Rancher re-registrations.
+// The result only depends on its inputs, and is empty without a cluster UUID.
+func clusterIdentity(clusterUUID, rancherInstallUUID string) string {
+	if clusterUUID == "" || rancherInstallUUID == "" || rancherInstallUUID == clusterUUID {
+		return clusterUUID
+	}
+	return clusterUUID + "/" + rancherInstallUUID
+}
+
+// detectAPISurface counts the API groups served by the apiserver and reports
+// whether any alpha API versions are enabled. Since apiserver flags are not
+// readable from inside the cluster, the served alpha/beta group-versions are
+// also returned as a hint that feature gates or runtime-config were changed.
+// Returns -1 if discovery fails.
+func detectAPISurface(ctx context.Context, clientset kubernetes.Interface) (groupCount int, alphaEnabled bool, nonDefault []string) {
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		warnAPIError(ctx, err, "failed to discover API groups")
+		return -1, false, nil
+	}
+	for _, group := range groups.Groups {
+		for _, v := range group.Versions {
+			isAlpha := strings.Contains(v.Version, "alpha")
+			if isAlpha {
+				alphaEnabled = true
+			}
+			if isAlpha || strings.Contains(v.Version, "beta") {
+				nonDefault = append(nonDefault, v.GroupVersion)
+			}
+		}
+	}
+	sort.Strings(nonDefault)
+	return len(groups.Groups), alphaEnabled, nonDefault
+}
+
+// authNamespaces are the namespaces where auth proxies are conventionally installed.
+var authNamespaces = []string{"kube-system", "cattle-system", "dex", "keycloak", "oauth2-proxy", "auth"}
+
+// authProxyPatterns maps Deployment name fragments of known auth proxies to the
+// protocol they imply, in match order.
+var authProxyPatterns = []struct {
+	pattern  string
+	protocol string
+}{
+	{"dex", "oidc"},
+	{"keycloak", "oidc"},
+	{"oauth2-proxy", "oidc"},
+	{"saml", "saml"},
+	{"shibboleth", "saml"},
+}
+
+// detectExternalAuth makes a best-effort guess at external authentication by
+// looking for known auth-proxy Deployments. The apiserver's --oidc-* flags are
+// not visible from inside the cluster, so a cluster configured for OIDC directly
+// on the apiserver still reports "none". Returns "unknown" if no proxy was found
+// but some namespaces could not be listed.
+func detectExternalAuth(ctx context.Context, workloads *workloadCache) string {
+	listFailed := false
+	for _, ns := range authNamespaces {
+		deployments, err := workloads.deployments(ctx, ns)
+		if err != nil {
+			listFailed = true
+			continue
+		}
+		for _, deploy := range deployments {
+			name := strings.ToLower(deploy.Name)
+			for _, p := range authProxyPatterns {
+				if strings.Contains(name, p.pattern) {
+					return p.protocol
+				}
+			}
+		}
+	}
+	if listFailed {
+		return "unknown"
+	}
+	return "none"
+}
+
+// detectKubernetesDashboard reports whether the Kubernetes Dashboard is deployed
+// in its conventional namespace, along with its image version.
+func detectKubernetesDashboard(ctx context.Context, clientset kubernetes.Interface, workloads *workloadCache) (bool, string) {
+	if _, err := clientset.CoreV1().Namespaces().Get(ctx, "kubernetes-dashboard", metav1.GetOptions{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			warnAPIError(ctx, err, "failed to get kubernetes-dashboard namespace")
+		}
+		return false, ""
+	}
+	deployments, err := workloads.deployments(ctx, "kubernetes-dashboard")
+	if err != nil {
+		// workloadCache has already counted the failure
+		logrus.WithError(err).Warn("failed to list kubernetes-dashboard deployments")
+		return false, ""
+	}
+	for _, deploy := range deployments {
+		name := strings.ToLower(deploy.Name)
+		if !strings.Contains(name, "kubernetes-dashboard") || strings.Contains(name, "metrics-scraper") {
+			continue
+		}
+		return true, containerImageVersion(deploy.Spec.Template.Spec.Containers, "dashboard")
+	}
+	return false, ""
+}
+
+// monitoringNamespaces are where a standalone Prometheus Operator (or the
+// kube-prometheus-stack chart) is conventionally installed.
+var monitoringNamespaces = []string{"monitoring", "prometheus", "kube-prometheus-stack", "prometheus-operator", "observability"}
+
+// prometheusOperatorPatterns are Deployment name fragments of a Prometheus Operator.
+var prometheusOperatorPatterns = []string{"prometheus-operator", "kube-prometheus-stack-operator"}
+
+// detectMonitoringStack reports "rancher-monitoring" when Rancher Monitoring runs
+// in cattle-monitoring-system, "prometheus-operator" for a standalone operator in
+// one of monitoringNamespaces, or "none". The version is the operator image tag.
+func detectMonitoringStack(ctx context.Context, workloads *workloadCache) (stack, version string) {
+	if deployments, err := workloads.deployments(ctx, "cattle-monitoring-system"); err == nil {
+		// The chart also deploys Grafana, kube-state-metrics etc. under the same
+		// prefix; only the operator carries the version we want.
+		found := false
+		for _, deploy := range deployments {
+			name := strings.ToLower(deploy.Name)
+			if !strings.Contains(name, "rancher-monitoring") {
+				continue
+			}
+			found = true
+			if strings.HasSuffix(name, "-operator") {
+				version = containerImageVersion(deploy.Spec.Template.Spec.Containers, "prometheus-operator")
+			}
+		}
+		if found {
+			return "rancher-monitoring", version
+		}
+	}
+	for _, ns := range monitoringNamespaces {
+		deployments, err := workloads.deployments(ctx, ns)
+		if err != nil {
+			continue
+		}
+		for _, deploy := range deployments {
+			name := strings.ToLower(deploy.Name)
+			for _, pattern := range prometheusOperatorPatterns {
+				if strings.Contains(name, pattern) {
+					return "prometheus-operator", containerImageVersion(deploy.Spec.Template.Spec.Containers, "prometheus-operator")
+				}
+			}
+		}
+	}
+	return "none", ""
+}
+
+// secretManagers are matched in order by Deployment name within their
+// conventional namespaces. image picks the container whose tag is the version.
+var secretManagers = []struct {
+	name       string
+	namespaces []string
+	pattern    string
+	image      string
+}{
+	{"external-secrets", []string{"external-secrets", "external-secrets-system"}, "external-secrets", "external-secrets"},
+	{"vault", []string{"vault", "vault-system"}, "vault-agent-injector", "vault-k8s"},
+}
+
+// detectSecretManager reports whether the External Secrets Operator or the
+// Vault Agent Injector is installed, along with its image version, or "none".
+func detectSecretManager(ctx context.Context, workloads *workloadCache) (manager, version string) {
+	for _, sm := range secretManagers {
+		for _, ns := range sm.namespaces {
+			deployments, err := workloads.deployments(ctx, ns)
+			if err != nil {
+				continue
+			}
+			for _, deploy := range deployments {
+				name := strings.ToLower(deploy.Name)
+				// external-secrets also ships -webhook and -cert-controller Deployments
+				if !strings.Contains(name, sm.pattern) || strings.HasSuffix(name, "-webhook") || strings.HasSuffix(name, "-cert-controller") {
+					continue
+				}
+				return sm.name, containerImageVersion(deploy.Spec.Template.Spec.Containers, sm.image)
+			}
+		}
+	}
+	return "none", ""
+}
+
+// batchSchedulers are secondary schedulers matched by Deployment name within
+// their conventional namespaces. image picks the container whose tag is the version.
+var batchSchedulers = []struct {
+	name       string
+	namespaces []string
+	pattern    string
+	image      string
+}{
+	{"volcano", []string{"volcano-system"}, "volcano-scheduler", "vc-scheduler"},
+	{"yunikorn", []string{"yunikorn"}, "yunikorn-scheduler", "yunikorn"},
+}
+
+// detectBatchSchedulers returns the image version of each installed batch
+// scheduler from batchSchedulers, keyed by scheduler name.
+func detectBatchSchedulers(ctx context.Context, workloads *workloadCache) map[string]string {
+	versions := map[string]string{}
+	for _, bs := range batchSchedulers {
+		for _, ns := range bs.namespaces {
+			deployments, err := workloads.deployments(ctx, ns)
+			if err != nil {
+				continue
+			}
+			for _, deploy := range deployments {
+				if strings.Contains(strings.ToLower(deploy.Name), bs.pattern) {
+					versions[bs.name] = containerImageVersion(deploy.Spec.Template.Spec.Containers, bs.image)
+					break
+				}
+			}
+		}
+	}
+	return versions
+}
+
+// kubeVirtNamespaces are where KubeVirt runs: its own namespace upstream, and
+// harvester-system on Harvester.
+var kubeVirtNamespaces = []string{"kubevirt", "harvester-system"}
+
+// detectVirtualization reports "kubevirt" when the virt-controller Deployment or
+// the virt-handler DaemonSet runs in one of kubeVirtNamespaces, or "none". The
+// version is the virt-controller image tag.
+func detectVirtualization(ctx context.Context, workloads *workloadCache) (virtualization, version string) {
+	for _, ns := range kubeVirtNamespaces {
+		found := false
+		if deployments, err := workloads.deployments(ctx, ns); err == nil {
+			for _, deploy := range deployments {
+				if strings.HasSuffix(deploy.Name, "virt-controller") {
+					found = true
+					version = containerImageVersion(deploy.Spec.Template.Spec.Containers, "virt-controller")
+				}
+			}
+		}
+		if daemonSets, err := workloads.daemonSets(ctx, ns); err == nil {
+			for _, ds := range daemonSets {
+				if strings.HasSuffix(ds.Name, "virt-handler") {
+					found = true
+					if version == "" {
+						version = containerImageVersion(ds.Spec.Template.Spec.Containers, "virt-handler")
+					}
+				}
+			}
+		}
+		if found {
+			return "kubevirt", version
+		}
+	}
+	return "none", ""
+}
+
+// spireNamespaces are where the SPIRE Helm charts install the agent: the
+// spiffe/spire chart splits spire-system and spire-server, older manifests use spire.
+var spireNamespaces = []string{"spire-system", "spire-server", "spire"}
+
+// detectWorkloadIdentity reports "spire" when the spire-agent DaemonSet runs in
+// one of spireNamespaces, or "none". The version is the spire-agent image tag.
+func detectWorkloadIdentity(ctx context.Context, workloads *workloadCache) (identity, version string) {
+	for _, ns := range spireNamespaces {
+		daemonSets, err := workloads.daemonSets(ctx, ns)
 		if err != nil {
 			continue
 		}
-		for _, ds := range daemonSets.Items {
-			name := strings.ToLower(ds.Name)
-			if strings.Contains(name, "device-plugin") || strings.Contains(name, "driver") {
-				version := ""
-				if len(ds.Spec.Template.Spec.Containers) > 0 {
-					version = extractImageVersion(ds.Spec.Template.Spec.Containers[0].Image)
+		for _, ds := range daemonSets {
+			if strings.HasSuffix(ds.Name, "spire-agent") {
+				return "spire", containerImageVersion(ds.Spec.Template.Spec.Containers, "spire-agent")
+			}
+		}
+	}
+	return "none", ""
+}
+
+// Mesh mTLS modes reported as mesh-mtls.
+const (
+	meshMTLSStrict     = "strict"
+	meshMTLSPermissive = "permissive"
+	meshMTLSEnabled    = "enabled"
+	meshMTLSUnknown    = "unknown"
+)
+
+// istioRootNamespace holds mesh-wide Istio policies in a default install.
+const istioRootNamespace = "istio-system"
+
+// peerAuthenticationResources are the Istio PeerAuthentication versions to try,
+// newest first; security.istio.io/v1 was added in Istio 1.22.
+var peerAuthenticationResources = []schema.GroupVersionResource{
+	{Group: "security.istio.io", Version: "v1", Resource: "peerauthentications"},
+	{Group: "security.istio.io", Version: "v1beta1", Resource: "peerauthentications"},
+}
+
+// detectMeshMTLS reports whether service-to-service traffic is mTLS-only. It
+// returns "" when no mesh is found, so the field is omitted. For Istio
+// (istiod in istio-system) the result is "strict" when the mesh-wide
+// PeerAuthentication, the one in the root namespace without a selector, sets
+// mtls.mode STRICT, and "permissive" otherwise, Istio's default. Namespace- or
+// workload-scoped policies don't make the whole mesh strict. Linkerd
+// (linkerd-destination in linkerd) always encrypts meshed traffic, so it is
+// reported as "enabled". "unknown" means Istio was found but its policies
+// could not be read.
+func detectMeshMTLS(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface, workloads *workloadCache) string {
+	if hasDeployment(ctx, workloads, istioRootNamespace, "istiod") {
+		return detectIstioMTLS(ctx, clientset, dynamicClient)
+	}
+	if hasDeployment(ctx, workloads, "linkerd", "linkerd-destination") {
+		return meshMTLSEnabled
+	}
+	return ""
+}
+
+func detectIstioMTLS(ctx context.Context, clientset kubernetes.Interface, dynamicClient dynamic.Interface) string {
+	if dynamicClient == nil {
+		return meshMTLSUnknown
+	}
+	for _, gvr := range peerAuthenticationResources {
+		if !hasAPIResource(clientset, gvr.GroupVersion().String(), gvr.Resource) {
+			continue
+		}
+		policies, err := dynamicClient.Resource(gvr).Namespace(istioRootNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			warnAPIError(ctx, err, "failed to list peerauthentications")
+			return meshMTLSUnknown
+		}
+		for _, policy := range policies.Items {
+			if selector, ok, _ := unstructured.NestedMap(policy.Object, "spec", "selector"); ok && len(selector) > 0 {
+				continue
+			}
+			if mode, _, _ := unstructured.NestedString(policy.Object, "spec", "mtls", "mode"); strings.EqualFold(mode, "STRICT") {
+				return meshMTLSStrict
+			}
+		}
+		return meshMTLSPermissive
+	}
+	return meshMTLSUnknown
+}
+
+// hasDeployment reports whether namespace has a Deployment named name.
+func hasDeployment(ctx context.Context, workloads *workloadCache, namespace, name string) bool {
+	deployments, err := workloads.deployments(ctx, namespace)
+	if err != nil {
+		return false
+	}
+	for _, deploy := range deployments {
+		if deploy.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// systemPDBTargets are name fragments identifying critical system workloads
+// whose availability should be protected by a PodDisruptionBudget.
+var systemPDBTargets = []string{"coredns", "ingress-nginx", "traefik"}
+
+// detectPodDisruptionBudgets counts PodDisruptionBudgets cluster-wide and reports
+// whether any of them protects a critical system workload, matched by PDB name
+// or selector label values. Returns -1 if PDBs cannot be listed.
+func detectPodDisruptionBudgets(ctx context.Context, clientset kubernetes.Interface) (count int, systemCoverage bool) {
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list poddisruptionbudgets")
+		return -1, false
+	}
+	for _, pdb := range pdbs.Items {
+		candidates := []string{pdb.Name}
+		if pdb.Spec.Selector != nil {
+			for _, v := range pdb.Spec.Selector.MatchLabels {
+				candidates = append(candidates, v)
+			}
+		}
+		for _, c := range candidates {
+			c = strings.ToLower(c)
+			for _, target := range systemPDBTargets {
+				if strings.Contains(c, target) {
+					systemCoverage = true
 				}
-				return operator, version
 			}
 		}
 	}
+	return len(pdbs.Items), systemCoverage
+}
 
-	return "none", ""
+// detectPriorityClasses counts PriorityClasses, reports whether both built-in
+// system classes exist, and counts the user-defined (non system-) classes.
+// Returns -1 counts if PriorityClasses cannot be listed.
+func detectPriorityClasses(ctx context.Context, clientset kubernetes.Interface) (count int, systemClasses bool, custom int) {
+	classes, err := clientset.SchedulingV1().PriorityClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list priorityclasses")
+		return -1, false, -1
+	}
+	var clusterCritical, nodeCritical bool
+	for _, pc := range classes.Items {
+		switch pc.Name {
+		case "system-cluster-critical":
+			clusterCritical = true
+		case "system-node-critical":
+			nodeCritical = true
+		}
+		if !strings.HasPrefix(pc.Name, "system-") {
+			custom++
+		}
+	}
+	return len(classes.Items), clusterCritical && nodeCritical, custom
 }
 
-func detectRancherManager(ctx context.Context, clientset kubernetes.Interface) (managed bool, version, installUUID string) {
-	_, err := clientset.CoreV1().Namespaces().Get(ctx, "cattle-system", metav1.GetOptions{})
+// detectAPF reports whether API Priority and Fairness is served and counts its
+// FlowSchemas. Clusters older than 1.29 only serve the v1beta3 API, and clusters
+// with APF disabled serve neither, so discovery gates the list. Returns -1 if
+// APF is not served or FlowSchemas cannot be listed.
+func detectAPF(ctx context.Context, clientset kubernetes.Interface) (enabled bool, flowSchemas int) {
+	var count func() (int, error)
+	switch {
+	case hasAPIResource(clientset, "flowcontrol.apiserver.k8s.io/v1", "flowschemas"):
+		count = func() (int, error) {
+			list, err := clientset.FlowcontrolV1().FlowSchemas().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			return len(list.Items), nil
+		}
+	case hasAPIResource(clientset, "flowcontrol.apiserver.k8s.io/v1beta3", "flowschemas"):
+		count = func() (int, error) {
+			list, err := clientset.FlowcontrolV1beta3().FlowSchemas().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			return len(list.Items), nil
+		}
+	default:
+		return false, -1
+	}
+	n, err := count()
 	if err != nil {
-		return false, "", ""
+		warnAPIError(ctx, err, "failed to list flowschemas")
+		return true, -1
 	}
+	return true, n
+}
 
-	deploy, err := clientset.AppsV1().Deployments("cattle-system").Get(ctx, "cattle-cluster-agent", metav1.GetOptions{})
+// detectValidatingAdmissionPolicies counts CEL ValidatingAdmissionPolicies and
+// their bindings. Only the v1 API (1.30+) is considered since the beta API was
+// off by default. served is false when the API is not available; counts are -1
+// if listing fails.
+func detectValidatingAdmissionPolicies(ctx context.Context, clientset kubernetes.Interface) (served bool, policies, bindings int) {
+	if !hasAPIResource(clientset, "admissionregistration.k8s.io/v1", "validatingadmissionpolicies") {
+		return false, 0, 0
+	}
+	policies, bindings = -1, -1
+	if list, err := clientset.AdmissionregistrationV1().ValidatingAdmissionPolicies().List(ctx, metav1.ListOptions{}); err != nil {
+		warnAPIError(ctx, err, "failed to list validatingadmissionpolicies")
+	} else {
+		policies = len(list.Items)
+	}
+	if list, err := clientset.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings().List(ctx, metav1.ListOptions{}); err != nil {
+		warnAPIError(ctx, err, "failed to list validatingadmissionpolicybindings")
+	} else {
+		bindings = len(list.Items)
+	}
+	return true, policies, bindings
+}
+
+// detectTenancy counts namespaces and classifies the cluster as "single" tenant
+// when at most one namespace besides the system namespaces and default exists,
+// or "multi" otherwise. Returns -1 and "unknown" if namespaces cannot be listed.
+func detectTenancy(ctx context.Context, clientset kubernetes.Interface) (count int, tenancy string) {
+	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return true, "", ""
+		warnAPIError(ctx, err, "failed to list namespaces")
+		return -1, "unknown"
+	}
+	userNamespaces := 0
+	for _, ns := range namespaces.Items {
+		if !systemNamespaces[ns.Name] && ns.Name != metav1.NamespaceDefault {
+			userNamespaces++
+		}
 	}
+	if userNamespaces > 1 {
+		return len(namespaces.Items), "multi"
+	}
+	return len(namespaces.Items), "single"
+}
 
-	if len(deploy.Spec.Template.Spec.Containers) > 0 {
-		container := deploy.Spec.Template.Spec.Containers[0]
-		version = extractImageVersion(container.Image)
-		for _, env := range container.Env {
-			if env.Name == "CATTLE_INSTALL_UUID" && env.Value != "" {
-				installUUID = env.Value
-				break
+// detectNamespaceLimits counts the namespaces holding at least one
+// ResourceQuota and those holding at least one LimitRange. Either count is -1
+// if its objects cannot be listed.
+func detectNamespaceLimits(ctx context.Context, clientset kubernetes.Interface) (withQuota, withLimitRange int) {
+	withQuota, withLimitRange = -1, -1
+	if quotas, err := clientset.CoreV1().ResourceQuotas("").List(ctx, metav1.ListOptions{}); err != nil {
+		warnAPIError(ctx, err, "failed to list resourcequotas")
+	} else {
+		namespaces := make(map[string]bool)
+		for _, quota := range quotas.Items {
+			namespaces[quota.Namespace] = true
+		}
+		withQuota = len(namespaces)
+	}
+	if limitRanges, err := clientset.CoreV1().LimitRanges("").List(ctx, metav1.ListOptions{}); err != nil {
+		warnAPIError(ctx, err, "failed to list limitranges")
+	} else {
+		namespaces := make(map[string]bool)
+		for _, limitRange := range limitRanges.Items {
+			namespaces[limitRange.Namespace] = true
+		}
+		withLimitRange = len(namespaces)
+	}
+	return withQuota, withLimitRange
+}
+
+// detectClusterAdminBindings counts the distinct ServiceAccounts, Users and Groups
+// bound to the cluster-admin ClusterRole, excluding the built-in system:masters
+// group. Returns -1 if ClusterRoleBindings cannot be listed.
+func detectClusterAdminBindings(ctx context.Context, clientset kubernetes.Interface) int {
+	bindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list clusterrolebindings")
+		return -1
+	}
+	subjects := make(map[string]struct{})
+	for _, binding := range bindings.Items {
+		if binding.RoleRef.Kind != "ClusterRole" || binding.RoleRef.Name != "cluster-admin" {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			if subject.Kind == rbacv1.GroupKind && subject.Name == "system:masters" {
+				continue
 			}
+			subjects[subject.Kind+"/"+subject.Namespace+"/"+subject.Name] = struct{}{}
 		}
 	}
-	return true, version, installUUID
+	return len(subjects)
 }
 
-// detectIPStack determines the cluster's IP stack configuration from the kubernetes service.
-func detectIPStack(ctx context.Context, clientset kubernetes.Interface) string {
-	kubeSvc, err := clientset.CoreV1().Services("default").Get(ctx, "kubernetes", metav1.GetOptions{})
+// rbacWriteVerbs are the RBAC verbs that modify resources.
+var rbacWriteVerbs = []string{"create", "update", "patch", "delete", "deletecollection", "*"}
+
+// detectOverprivilegedDefaultSAs counts the default ServiceAccounts of
+// namespaces outside systemNamespaces that a RoleBinding or ClusterRoleBinding
+// grants a write verb on core API resources. Subjects are matched as
+// ServiceAccount "default" or as User "system:serviceaccount:<ns>:default".
+// Returns -1 if bindings or roles cannot be listed.
+func detectOverprivilegedDefaultSAs(ctx context.Context, clientset kubernetes.Interface) int {
+	clusterRoles, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list clusterroles")
+		return -1
+	}
+	roles, err := clientset.RbacV1().Roles("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list roles")
+		return -1
+	}
+	clusterRoleBindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		warnAPIError(ctx, err, "failed to list clusterrolebindings")
+		return -1
+	}
+	roleBindings, err := clientset.RbacV1().RoleBindings("").List(ctx, metav1.ListOptions{})
 	if err != nil {
-		logrus.WithError(err).Warn("failed to get kubernetes service for IP stack detection")
+		warnAPIError(ctx, err, "failed to list rolebindings")
+		return -1
+	}
+
+	clusterRoleWrites := make(map[string]bool)
+	for _, role := range clusterRoles.Items {
+		clusterRoleWrites[role.Name] = grantsCoreWrite(role.Rules)
+	}
+	roleWrites := make(map[string]bool)
+	for _, role := range roles.Items {
+		roleWrites[role.Namespace+"/"+role.Name] = grantsCoreWrite(role.Rules)
+	}
+
+	overprivileged := make(map[string]bool)
+	addSubjects := func(subjects []rbacv1.Subject, bindingNamespace string) {
+		for _, subject := range subjects {
+			if namespace, ok := defaultServiceAccountNamespace(subject, bindingNamespace); ok && !systemNamespaces[namespace] {
+				overprivileged[namespace] = true
+			}
+		}
+	}
+	for _, binding := range clusterRoleBindings.Items {
+		if binding.RoleRef.Kind == "ClusterRole" && clusterRoleWrites[binding.RoleRef.Name] {
+			addSubjects(binding.Subjects, "")
+		}
+	}
+	for _, binding := range roleBindings.Items {
+		writes := false
+		switch binding.RoleRef.Kind {
+		case "ClusterRole":
+			writes = clusterRoleWrites[binding.RoleRef.Name]
+		case "Role":
+			writes = roleWrites[binding.Namespace+"/"+binding.RoleRef.Name]
+		}
+		if writes {
+			addSubjects(binding.Subjects, binding.Namespace)
+		}
+	}
+	return len(overprivileged)
+}
+
+// defaultServiceAccountNamespace returns the namespace of the default
+// ServiceAccount subject refers to. A ServiceAccount subject without a
+// namespace belongs to the namespace of its RoleBinding.
+func defaultServiceAccountNamespace(subject rbacv1.Subject, bindingNamespace string) (string, bool) {
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		if subject.Name != "default" {
+			return "", false
+		}
+		if subject.Namespace != "" {
+			return subject.Namespace, true
+		}
+		return bindingNamespace, bindingNamespace != ""
+	case rbacv1.UserKind:
+		namespace, ok := strings.CutPrefix(subject.Name, "system:serviceaccount:")
+		if !ok {
+			return "", false
+		}
+		namespace, ok = strings.CutSuffix(namespace, ":default")
+		return namespace, ok && namespace != "" && !strings.Contains(namespace, ":")
+	}
+	return "", false
+}
+
+// grantsCoreWrite reports whether rules allow a write verb on resources of the
+// core API group.
+func grantsCoreWrite(rules []rbacv1.PolicyRule) bool {
+	for _, rule := range rules {
+		if len(rule.Resources) == 0 {
+			continue
+		}
+		if !slices.Contains(rule.APIGroups, "") && !slices.Contains(rule.APIGroups, "*") {
+			continue
+		}
+		for _, verb := range rule.Verbs {
+			if slices.Contains(rbacWriteVerbs, verb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// podInspector examines a single pod during scanPods.
+type podInspector func(pod *corev1.Pod)
+
+// scanPods lists pods cluster-wide in pages of podListPageSize and runs every
+// inspector on each pod, so all pod-level signals are computed in one pass
+// without holding the full pod list in memory.
+func scanPods(ctx context.Context, clientset kubernetes.Interface, inspectors ...podInspector) error {
+	opts := metav1.ListOptions{Limit: podListPageSize}
+	for {
+		pods, err := clientset.CoreV1().Pods("").List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for i := range pods.Items {
+			for _, inspect := range inspectors {
+				inspect(&pods.Items[i])
+			}
+		}
+		if pods.Continue == "" {
+			return nil
+		}
+		opts.Continue = pods.Continue
+	}
+}
+
+// podScan holds the pod-level signals gathered by scanClusterPods.
+type podScan struct {
+	hostProcess, hostPort, root int
+	hostIPC, unsafeSysctl       int
+	evicted, privilegedInit     int
+	ephemeral                   int
+	seLinux, appArmor           int
+	podCIDR, serviceCIDR        string
+	schedulers                  map[string]bool
+	err                         error
+}
+
+// scanClusterPods runs every pod inspector Collect needs in a single scanPods
+// pass. A failed list is logged and returned in err.
+func scanClusterPods(ctx context.Context, clientset kubernetes.Interface) podScan {
+	scan := podScan{schedulers: map[string]bool{}}
+	scan.err = scanPods(ctx, clientset,
+		countPods(&scan.hostProcess, isHostProcessPod),
+		countPods(&scan.hostPort, usesHostPort),
+		countPods(&scan.hostIPC, usesHostIPC),
+		countPods(&scan.unsafeSysctl, usesUnsafeSysctl),
+		countPods(&scan.root, runsAsRoot),
+		countPods(&scan.evicted, isEvictedPod),
+		countPods(&scan.privilegedInit, hasPrivilegedInitContainer),
+		countPods(&scan.ephemeral, hasEphemeralContainers),
+		countPods(&scan.seLinux, usesSELinuxOptions),
+		countPods(&scan.appArmor, usesAppArmorProfile),
+		captureCIDRFlags(&scan.podCIDR, &scan.serviceCIDR),
+		collectSchedulerNames(scan.schedulers),
+	)
+	if scan.err != nil {
+		warnAPIError(ctx, scan.err, "failed to list pods")
+	}
+	return scan
+}
+
+// countPods returns an inspector that increments counter for each matching pod.
+func countPods(counter *int, match func(pod *corev1.Pod) bool) podInspector {
+	return func(pod *corev1.Pod) {
+		if match(pod) {
+			*counter++
+		}
+	}
+}
+
+// isHostProcessPod reports whether pod runs Windows HostProcess containers,
+// either through the pod-level security context or on any individual container.
+func isHostProcessPod(pod *corev1.Pod) bool {
+	if pod.Spec.SecurityContext != nil && isHostProcess(pod.Spec.SecurityContext.WindowsOptions) {
+		return true
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.SecurityContext != nil && isHostProcess(c.SecurityContext.WindowsOptions) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHostProcess(opts *corev1.WindowsSecurityContextOptions) bool {
+	return opts != nil && opts.HostProcess != nil && *opts.HostProcess
+}
+
+// systemNamespaces hold platform components that legitimately use host-level
+// features; they are excluded from workload audit counts.
+var systemNamespaces = map[string]bool{
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+	"cattle-system":   true,
+}
+
+// usesHostPort reports whether a pod outside system namespaces has a container
+// binding a hostPort.
+func usesHostPort(pod *corev1.Pod) bool {
+	if systemNamespaces[pod.Namespace] {
+		return false
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			if port.HostPort != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesHostIPC reports whether a pod outside system namespaces shares the host's
+// IPC namespace.
+func usesHostIPC(pod *corev1.Pod) bool {
+	return !systemNamespaces[pod.Namespace] && pod.Spec.HostIPC
+}
+
+// safeSysctls is the kubelet's safe sysctl set: namespaced sysctls that cannot
+// affect other pods on the node. Anything else must be allowed per node with
+// --allowed-unsafe-sysctls.
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.tcp_fin_timeout":            true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_rmem":                   true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.tcp_wmem":                   true,
+}
+
+// usesUnsafeSysctl reports whether a pod outside system namespaces sets a
+// sysctl outside safeSysctls. Names may use / as the separator.
+func usesUnsafeSysctl(pod *corev1.Pod) bool {
+	if systemNamespaces[pod.Namespace] || pod.Spec.SecurityContext == nil {
+		return false
+	}
+	for _, sysctl := range pod.Spec.SecurityContext.Sysctls {
+		if !safeSysctls[strings.ReplaceAll(sysctl.Name, "/", ".")] {
+			return true
+		}
+	}
+	return false
+}
+
+// isEvictedPod reports whether pod was evicted, e.g. under node pressure, and
+// is kept as a Failed pod until it is deleted or garbage collected.
+func isEvictedPod(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted"
+}
+
+// hasPrivilegedInitContainer reports whether a pod outside system namespaces
+// has an init container, sidecars included, running privileged.
+func hasPrivilegedInitContainer(pod *corev1.Pod) bool {
+	if systemNamespaces[pod.Namespace] {
+		return false
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEphemeralContainers reports whether an ephemeral container, e.g. from
+// kubectl debug, was ever added to pod. System namespaces are included, as
+// debugging access there is the more notable case.
+func hasEphemeralContainers(pod *corev1.Pod) bool {
+	return len(pod.Spec.EphemeralContainers) > 0
+}
+
+// runsAsRoot approximates whether a pod outside system namespaces may run as
+// root: some container has neither runAsNonRoot: true nor a non-zero runAsUser,
+// taking container-level settings over pod-level ones. The image's USER is not
+// visible here, so pods relying on it are counted as well.
+func runsAsRoot(pod *corev1.Pod) bool {
+	if systemNamespaces[pod.Namespace] {
+		return false
+	}
+	var podNonRoot *bool
+	var podUser *int64
+	if sc := pod.Spec.SecurityContext; sc != nil {
+		podNonRoot, podUser = sc.RunAsNonRoot, sc.RunAsUser
+	}
+	for _, c := range pod.Spec.Containers {
+		nonRoot, user := podNonRoot, podUser
+		if sc := c.SecurityContext; sc != nil {
+			if sc.RunAsNonRoot != nil {
+				nonRoot = sc.RunAsNonRoot
+			}
+			if sc.RunAsUser != nil {
+				user = sc.RunAsUser
+			}
+		}
+		if !ptr.Deref(nonRoot, false) && ptr.Deref(user, 0) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// usesSELinuxOptions reports whether a pod sets seLinuxOptions at pod or
+// container level.
+func usesSELinuxOptions(pod *corev1.Pod) bool {
+	if sc := pod.Spec.SecurityContext; sc != nil && isSELinuxSet(sc.SELinuxOptions) {
+		return true
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.SecurityContext != nil && isSELinuxSet(c.SecurityContext.SELinuxOptions) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSELinuxSet(opts *corev1.SELinuxOptions) bool {
+	return opts != nil && *opts != corev1.SELinuxOptions{}
+}
+
+// appArmorAnnotationPrefix is the pre-1.30 per-container AppArmor annotation,
+// still widely used by charts.
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// usesAppArmorProfile reports whether a pod requests a confining AppArmor
+// profile, through appArmorProfile at pod or container level or the legacy
+// annotation. Unconfined profiles don't count.
+func usesAppArmorProfile(pod *corev1.Pod) bool {
+	if sc := pod.Spec.SecurityContext; sc != nil && isAppArmorConfined(sc.AppArmorProfile) {
+		return true
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.SecurityContext != nil && isAppArmorConfined(c.SecurityContext.AppArmorProfile) {
+			return true
+		}
+	}
+	for key, value := range pod.Annotations {
+		if strings.HasPrefix(key, appArmorAnnotationPrefix) && value != "unconfined" {
+			return true
+		}
+	}
+	return false
+}
+
+func isAppArmorConfined(profile *corev1.AppArmorProfile) bool {
+	return profile != nil && profile.Type != corev1.AppArmorProfileTypeUnconfined
+}
+
+// macFromPodCounts infers the mandatory access control system from pod
+// security contexts. SELinux and AppArmor don't run side by side, and
+// seLinuxOptions only mean something on SELinux hosts, while AppArmor profiles
+// are often set by charts regardless, so SELinux wins when both appear.
+func macFromPodCounts(seLinuxPods, appArmorPods int) string {
+	switch {
+	case seLinuxPods > 0:
+		return "selinux"
+	case appArmorPods > 0:
+		return "apparmor"
+	default:
+		return "none"
+	}
+}
+
+// collectSchedulerNames returns an inspector that adds every schedulerName
+// other than the default scheduler to names.
+func collectSchedulerNames(names map[string]bool) podInspector {
+	return func(pod *corev1.Pod) {
+		if name := pod.Spec.SchedulerName; name != "" && name != corev1.DefaultSchedulerName {
+			names[name] = true
+		}
+	}
+}
+
+// captureCIDRFlags returns an inspector that reads --cluster-cidr and
+// --service-cluster-ip-range from the mirror pods of the kube-controller-manager
+// and kube-apiserver static pods in kube-system. The first value seen wins.
+func captureCIDRFlags(podCIDR, serviceCIDR *string) podInspector {
+	return func(pod *corev1.Pod) {
+		if pod.Namespace != "kube-system" {
+			return
+		}
+		if component := pod.Labels["component"]; component != "kube-controller-manager" && component != "kube-apiserver" {
+			return
+		}
+		for _, c := range pod.Spec.Containers {
+			for _, arg := range slices.Concat(c.Command, c.Args) {
+				if value, ok := strings.CutPrefix(arg, "--cluster-cidr="); ok && *podCIDR == "" {
+					*podCIDR = value
+				}
+				if value, ok := strings.CutPrefix(arg, "--service-cluster-ip-range="); ok && *serviceCIDR == "" {
+					*serviceCIDR = value
+				}
+			}
+		}
+	}
+}
+
+// aggregateCIDRs returns the smallest prefix covering all node pod CIDRs, per IP
+// family and comma-separated like the --cluster-cidr flag. It is a lower bound
+// of the real cluster CIDR, as nodes only hold the ranges allocated so far.
+// Returns "" if no node has a pod CIDR.
+func aggregateCIDRs(cidrs []string) string {
+	var v4, v6 netip.Prefix
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			v4 = coverPrefix(v4, prefix.Masked())
+		} else {
+			v6 = coverPrefix(v6, prefix.Masked())
+		}
+	}
+	var parts []string
+	for _, agg := range []netip.Prefix{v4, v6} {
+		if agg.IsValid() {
+			parts = append(parts, agg.String())
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// coverPrefix widens agg until it also contains prefix. An invalid agg starts
+// from prefix itself.
+func coverPrefix(agg, prefix netip.Prefix) netip.Prefix {
+	if !agg.IsValid() {
+		return prefix
+	}
+	for agg.Bits() > prefix.Bits() || !agg.Contains(prefix.Addr()) {
+		agg = netip.PrefixFrom(agg.Addr(), agg.Bits()-1).Masked()
+	}
+	return agg
+}
+
+// cidrCapacity returns the number of addresses in the IPv4 range of a
+// comma-separated CIDR list, or -1 if it has none. IPv6 ranges are too large
+// to exhaust and are not counted.
+func cidrCapacity(cidrs string) int {
+	for _, cidr := range strings.Split(cidrs, ",") {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err == nil && prefix.Addr().Is4() {
+			return 1 << (32 - prefix.Bits())
+		}
+	}
+	return -1
+}
+
+// etcdTLSPorts are the etcd client and peer ports, which RKE2 always serves over TLS.
+var etcdTLSPorts = map[int32]bool{2379: true, 2380: true}
+
+// etcdTLSKeyFragments mark ConfigMap keys that carry etcd TLS material or settings.
+var etcdTLSKeyFragments = []string{"cert", "tls", "trusted-ca"}
+
+// detectEtcdTLS is a best-effort check for etcd client/peer TLS. etcd runs as a
+// static pod whose flags are not exposed through the API, so this looks for
+// indirect evidence in kube-system: etcd Services or EndpointSlices exposing the
+// TLS client/peer ports or TLS-named ports, and etcd-* ConfigMaps carrying
+// certificate settings. Returns "unknown" when no evidence is found, which does
+// not by itself mean TLS is disabled.
+func detectEtcdTLS(ctx context.Context, clientset kubernetes.Interface) string {
+	isTLSPort := func(name string, port int32) bool {
+		name = strings.ToLower(name)
+		return etcdTLSPorts[port] || strings.Contains(name, "https") || strings.Contains(name, "tls")
+	}
+
+	if services, err := clientset.CoreV1().Services("kube-system").List(ctx, metav1.ListOptions{}); err == nil {
+		for _, svc := range services.Items {
+			if !strings.Contains(strings.ToLower(svc.Name), "etcd") {
+				continue
+			}
+			for _, port := range svc.Spec.Ports {
+				if isTLSPort(port.Name, port.Port) {
+					return "enabled"
+				}
+			}
+		}
+	} else {
+		warnAPIError(ctx, err, "failed to list kube-system services for etcd TLS detection")
+	}
+
+	if endpointSlices, err := clientset.DiscoveryV1().EndpointSlices("kube-system").List(ctx, metav1.ListOptions{}); err == nil {
+		for _, slice := range endpointSlices.Items {
+			if !strings.Contains(strings.ToLower(slice.Labels[discoveryv1.LabelServiceName]), "etcd") {
+				continue
+			}
+			for _, port := range slice.Ports {
+				if port.Port != nil && isTLSPort(ptr.Deref(port.Name, ""), *port.Port) {
+					return "enabled"
+				}
+			}
+		}
+	} else {
+		warnAPIError(ctx, err, "failed to list kube-system endpointslices for etcd TLS detection")
+	}
+
+	if configMaps, err := clientset.CoreV1().ConfigMaps("kube-system").List(ctx, metav1.ListOptions{}); err == nil {
+		for _, cm := range configMaps.Items {
+			if !strings.HasPrefix(strings.ToLower(cm.Name), "etcd-") {
+				continue
+			}
+			for key := range cm.Data {
+				key = strings.ToLower(key)
+				for _, fragment := range etcdTLSKeyFragments {
+					if strings.Contains(key, fragment) {
+						return "enabled"
+					}
+				}
+			}
+		}
+	} else {
+		warnAPIError(ctx, err, "failed to list kube-system configmaps for etcd TLS detection")
+	}
+
+	return "unknown"
+}
+
+// nodeIPFamily reports the address families of the nodes' InternalIPs:
+// "ipv4", "ipv6", "dual", or "unknown" when no node reports one.
+func nodeIPFamily(hasIPv4, hasIPv6 bool) string {
+	switch {
+	case hasIPv4 && hasIPv6:
+		return "dual"
+	case hasIPv4:
+		return "ipv4"
+	case hasIPv6:
+		return "ipv6"
+	default:
 		return "unknown"
 	}
-	if len(kubeSvc.Spec.IPFamilies) == 0 {
+}
+
+// ipStackMismatch reports whether the service-based ip-stack disagrees with
+// the families of the node InternalIPs. Unknown on either side is no mismatch.
+func ipStackMismatch(ipStack, nodeFamily string) bool {
+	serviceFamily, ok := map[string]string{"ipv4-only": "ipv4", "ipv6-only": "ipv6", "dual-stack": "dual"}[ipStack]
+	if !ok || nodeFamily == "unknown" {
+		return false
+	}
+	return serviceFamily != nodeFamily
+}
+
+// detectIPStack determines the cluster's IP stack configuration from the
+// kubernetes service among services.
+func detectIPStack(services []corev1.Service) string {
+	var kubeSvc *corev1.Service
+	for i := range services {
+		if services[i].Namespace == "default" && services[i].Name == "kubernetes" {
+			kubeSvc = &services[i]
+		}
+	}
+	if kubeSvc == nil || len(kubeSvc.Spec.IPFamilies) == 0 {
 		return "unknown"
 	}
 	hasIPv4, hasIPv6 := false, false
@@ -466,3 +3638,56 @@ func detectIPStack(ctx context.Context, clientset kubernetes.Interface) string {
 		return "unknown"
 	}
 }
+
+// apiserverPort is the port RKE2 serves the Kubernetes API on.
+const apiserverPort = 6443
+
+// detectAPIServerExposure reports how far the API server is reachable through
+// Services: "loadbalancer", "nodeport" or "clusterip", the most exposed type
+// among the kubernetes service and apiserver proxies. A proxy is a Service
+// named kube-apiserver*, selecting the kube-apiserver static pods, or without
+// a selector on port 6443, i.e. with hand-made endpoints. Aggregated API
+// servers such as prometheus-adapter also serve on 6443 through their own
+// selector, so port alone is not enough. Returns "unknown" when none is found.
+func detectAPIServerExposure(services []corev1.Service) string {
+	exposure := "unknown"
+	rank := map[string]int{"unknown": 0, "clusterip": 1, "nodeport": 2, "loadbalancer": 3}
+	for _, svc := range services {
+		if !isAPIServerService(svc) {
+			continue
+		}
+		var svcExposure string
+		switch svc.Spec.Type {
+		case corev1.ServiceTypeLoadBalancer:
+			svcExposure = "loadbalancer"
+		case corev1.ServiceTypeNodePort:
+			svcExposure = "nodeport"
+		case corev1.ServiceTypeExternalName:
+			continue
+		default:
+			svcExposure = "clusterip"
+		}
+		if rank[svcExposure] > rank[exposure] {
+			exposure = svcExposure
+		}
+	}
+	return exposure
+}
+
+func isAPIServerService(svc corev1.Service) bool {
+	if svc.Namespace == "default" && svc.Name == "kubernetes" {
+		return true
+	}
+	if svc.Spec.Selector["component"] == "kube-apiserver" || strings.HasPrefix(svc.Name, "kube-apiserver") {
+		return true
+	}
+	if len(svc.Spec.Selector) > 0 {
+		return false
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Port == apiserverPort || port.TargetPort.IntValue() == apiserverPort {
+			return true
+		}
+	}
+	return false
+}
@@ -1,19 +1,49 @@
 package telemetry
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	flowcontrolv1 "k8s.io/api/flowcontrol/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 )
 
 func TestExtractImageVersion(t *testing.T) {
@@ -85,6 +115,56 @@ func TestIsControlPlaneNode(t *testing.T) {
 	}
 }
 
+func TestIsEOLOSImage(t *testing.T) {
+	tests := []struct {
+		osImage string
+		want    bool
+	}{
+		{"Ubuntu 18.04.6 LTS", true},
+		{"CentOS Linux 7 (Core)", true},
+		{"SUSE Linux Enterprise Server 12 SP5", true},
+		{"Ubuntu 24.04.1 LTS", false},
+		{"SUSE Linux Enterprise Server 15 SP6", false},
+		{"SLE Micro 6.1", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.osImage, func(t *testing.T) {
+			if got := isEOLOSImage(tt.osImage); got != tt.want {
+				t.Errorf("isEOLOSImage(%q) = %v, want %v", tt.osImage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKernelRisk(t *testing.T) {
+	tests := []struct {
+		release string
+		want    string
+	}{
+		{"5.10.90", kernelRiskVulnerable},
+		{"5.15.20-flatcar", kernelRiskVulnerable},
+		{"5.16.1", kernelRiskVulnerable},
+		{"5.15.142-flatcar", kernelRiskOK},
+		{"6.1.58-talos", kernelRiskOK},
+		{"4.19.0", kernelRiskOK},
+		{"5.15.0-91-generic", kernelRiskUnknown},
+		{"4.18.0-477.10.1.el8_8.x86_64", kernelRiskUnknown},
+		{"5.14.21-150400.24.46-default", kernelRiskUnknown},
+		{"", kernelRiskUnknown},
+		{"not-a-kernel", kernelRiskUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.release, func(t *testing.T) {
+			if got := kernelRisk(tt.release); got != tt.want {
+				t.Errorf("kernelRisk(%q) = %q, want %q", tt.release, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetSELinuxStatus(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -235,345 +315,680 @@ func TestCollect_NodeInfoInconsistent(t *testing.T) {
 	}
 }
 
-func TestCollect_CNIDetection(t *testing.T) {
+func TestCollect_Zones(t *testing.T) {
 	tests := []struct {
-		name        string
-		daemonSet   string
-		image       string
-		expectedCNI string
+		name          string
+		nodes         []runtime.Object
+		wantZones     int
+		wantMultizone bool
 	}{
-		{"canal", "rke2-canal", "rancher/hardened-calico:v3.26.0", "canal"},
-		{"flannel", "kube-flannel-ds", "flannel/flannel:v0.22.0", "flannel"},
-		{"calico", "calico-node", "calico/node:v3.26.0", "calico"},
-		{"cilium", "cilium", "cilium/cilium:v1.14.0", "cilium"},
-		{"weave", "weave-net", "weaveworks/weave-kube:2.8.1", "weave"},
+		{
+			name: "three nodes in two zones, control plane in one",
+			nodes: []runtime.Object{
+				testNode("server-1", nodeLabel(corev1.LabelTopologyZone, "zone-a"), controlPlaneNode),
+				testNode("server-2", nodeLabel(corev1.LabelTopologyZone, "zone-a"), controlPlaneNode),
+				testNode("agent-1", nodeLabel(corev1.LabelTopologyZone, "zone-b")),
+			},
+			wantZones:     2,
+			wantMultizone: false,
+		},
+		{
+			name: "control plane spans zones",
+			nodes: []runtime.Object{
+				testNode("server-1", nodeLabel(corev1.LabelTopologyZone, "zone-a"), controlPlaneNode),
+				testNode("server-2", nodeLabel(corev1.LabelTopologyZone, "zone-b"), controlPlaneNode),
+				testNode("agent-1", nodeLabel(corev1.LabelTopologyZone, "zone-b")),
+			},
+			wantZones:     2,
+			wantMultizone: true,
+		},
+		{
+			name:          "no zone labels",
+			nodes:         []runtime.Object{testNode("server-1")},
+			wantZones:     0,
+			wantMultizone: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			clientset := fake.NewClientset(
+			objects := append([]runtime.Object{
 				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-				&corev1.Node{
-					ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-					Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
-				},
-				&appsv1.DaemonSet{
-					ObjectMeta: metav1.ObjectMeta{Name: tt.daemonSet, Namespace: "kube-system"},
-					Spec: appsv1.DaemonSetSpec{
-						Template: corev1.PodTemplateSpec{
-							Spec: corev1.PodSpec{
-								Containers: []corev1.Container{{Image: tt.image}},
-							},
-						},
-					},
-				},
-			)
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
 
 			data, err := Collect(context.Background(), clientset, "recommended")
 			if err != nil {
 				t.Fatalf("Collect() error = %v", err)
 			}
 
-			if data.ExtraFieldInfo["cni-plugin"] != tt.expectedCNI {
-				t.Errorf("cni-plugin = %v, want %v", data.ExtraFieldInfo["cni-plugin"], tt.expectedCNI)
+			if data.ExtraFieldInfo["zone-count"] != tt.wantZones {
+				t.Errorf("zone-count = %v, want %v", data.ExtraFieldInfo["zone-count"], tt.wantZones)
+			}
+			if data.ExtraFieldInfo["control-plane-multizone"] != tt.wantMultizone {
+				t.Errorf("control-plane-multizone = %v, want %v", data.ExtraFieldInfo["control-plane-multizone"], tt.wantMultizone)
 			}
 		})
 	}
 }
 
-func TestCollect_IngressDetection(t *testing.T) {
+func TestCollect_ControlPlaneIsolated(t *testing.T) {
+	controlPlaneTaint := corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}
+
 	tests := []struct {
-		name            string
-		deploymentName  string
-		image           string
-		expectedIngress string
+		name     string
+		nodes    []runtime.Object
+		expected bool
 	}{
-		{"nginx", "rke2-ingress-nginx-controller", "rancher/nginx-ingress-controller:v1.9.0", "rke2-ingress-nginx"},
-		{"traefik", "traefik", "traefik:v2.10", "traefik"},
+		{
+			name: "all control plane nodes tainted",
+			nodes: []runtime.Object{
+				testNode("server-1", controlPlaneNode, nodeTaints(controlPlaneTaint)),
+				testNode("server-2", controlPlaneNode, nodeTaints(corev1.Taint{Key: "CriticalAddonsOnly", Value: "true", Effect: corev1.TaintEffectNoExecute})),
+				testNode("agent-1"),
+			},
+			expected: true,
+		},
+		{
+			name: "one control plane node untainted",
+			nodes: []runtime.Object{
+				testNode("server-1", controlPlaneNode, nodeTaints(controlPlaneTaint)),
+				testNode("server-2", controlPlaneNode),
+			},
+			expected: false,
+		},
+		{
+			name: "PreferNoSchedule does not isolate",
+			nodes: []runtime.Object{
+				testNode("server-1", controlPlaneNode, nodeTaints(corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectPreferNoSchedule})),
+			},
+			expected: false,
+		},
+		{
+			name:     "no control plane nodes",
+			nodes:    []runtime.Object{testNode("agent-1")},
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			clientset := fake.NewClientset(
+			objects := append([]runtime.Object{
 				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-				&corev1.Node{
-					ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-					Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
-				},
-				&appsv1.Deployment{
-					ObjectMeta: metav1.ObjectMeta{Name: tt.deploymentName, Namespace: "kube-system"},
-					Spec: appsv1.DeploymentSpec{
-						Template: corev1.PodTemplateSpec{
-							Spec: corev1.PodSpec{
-								Containers: []corev1.Container{{Image: tt.image}},
-							},
-						},
-					},
-				},
-			)
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
 
 			data, err := Collect(context.Background(), clientset, "recommended")
 			if err != nil {
 				t.Fatalf("Collect() error = %v", err)
 			}
 
-			if data.ExtraFieldInfo["ingress-controller"] != tt.expectedIngress {
-				t.Errorf("ingress-controller = %v, want %v", data.ExtraFieldInfo["ingress-controller"], tt.expectedIngress)
+			if data.ExtraFieldInfo["control-plane-isolated"] != tt.expected {
+				t.Errorf("control-plane-isolated = %v, want %v", data.ExtraFieldInfo["control-plane-isolated"], tt.expected)
 			}
 		})
 	}
 }
 
-func TestCollect_GPUDetection(t *testing.T) {
-	clientset := fake.NewClientset(
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-1"},
-			Status: corev1.NodeStatus{
-				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
-				Allocatable: corev1.ResourceList{
-					"nvidia.com/gpu": resource.MustParse("2"),
-				},
-			},
+func TestCollect_HostedControlPlane(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodes       []runtime.Object
+		nodesDenied bool
+		expected    bool
+	}{
+		{
+			name:     "only agent nodes",
+			nodes:    []runtime.Object{testNode("agent-1"), testNode("agent-2")},
+			expected: true,
 		},
-		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "cpu-node-1"},
-			Status: corev1.NodeStatus{
-				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
-			},
+		{
+			name:     "control plane node visible",
+			nodes:    []runtime.Object{testNode("server-1", controlPlaneNode), testNode("agent-1")},
+			expected: false,
 		},
-	)
+		{
+			name:     "no nodes",
+			expected: false,
+		},
+		{
+			name:        "nodes denied",
+			nodes:       []runtime.Object{testNode("agent-1")},
+			nodesDenied: true,
+			expected:    false,
+		},
+	}
 
-	data, err := Collect(context.Background(), clientset, "recommended")
-	if err != nil {
-		t.Fatalf("Collect() error = %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
+			if tt.nodesDenied {
+				clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", errors.New("denied"))
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["hosted-control-plane"] != tt.expected {
+				t.Errorf("hosted-control-plane = %v, want %v", data.ExtraFieldInfo["hosted-control-plane"], tt.expected)
+			}
+		})
 	}
+}
 
-	if data.ExtraFieldInfo["gpuNodeCount"] != 1 {
-		t.Errorf("gpuNodeCount = %v, want 1", data.ExtraFieldInfo["gpuNodeCount"])
+func TestCollect_CIDRs(t *testing.T) {
+	node := func(name string, podCIDRs ...string) *corev1.Node {
+		return testNode(name, nodePodCIDRs(podCIDRs...))
 	}
-	if data.ExtraFieldInfo["gpu-vendor"] != "nvidia" {
-		t.Errorf("gpu-vendor = %v, want nvidia", data.ExtraFieldInfo["gpu-vendor"])
+	staticPod := func(component string, args ...string) *corev1.Pod {
+		return testPod(component+"-server-1", "kube-system", func(pod *corev1.Pod) {
+			pod.Labels = map[string]string{"component": component, "tier": "control-plane"}
+			pod.Spec.Containers[0] = corev1.Container{Name: component, Command: []string{component}, Args: args}
+		})
 	}
-}
 
-func TestCollect_RancherManaged(t *testing.T) {
-	clientset := fake.NewClientset(
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
-		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+	tests := []struct {
+		name                    string
+		mode                    string
+		objects                 []runtime.Object
+		expectedPodCIDR         string
+		expectedServiceCIDR     string
+		expectedPodCapacity     int
+		expectedServiceCapacity int
+	}{
+		{
+			name: "from node podCIDRs",
+			mode: "recommended",
+			objects: []runtime.Object{
+				node("server-1", "10.42.0.0/24"),
+				node("agent-1", "10.42.1.0/24"),
+				node("agent-2", "10.42.2.0/24"),
+			},
+			expectedPodCIDR:         "10.42.0.0/22",
+			expectedServiceCIDR:     "unknown",
+			expectedPodCapacity:     1024,
+			expectedServiceCapacity: -1,
 		},
-		&appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
-			Spec: appsv1.DeploymentSpec{
-				Template: corev1.PodTemplateSpec{
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{{
-							Image: "rancher/rancher-agent:v2.8.0",
-							Env: []corev1.EnvVar{
-								{Name: "CATTLE_INSTALL_UUID", Value: "rancher-install-uuid-123"},
-							},
-						}},
-					},
-				},
+		{
+			name: "dual-stack node podCIDRs",
+			mode: "recommended",
+			objects: []runtime.Object{
+				node("server-1", "10.42.0.0/24", "2001:cafe:42::/64"),
+				node("agent-1", "10.42.1.0/24", "2001:cafe:42:1::/64"),
 			},
+			expectedPodCIDR:         "10.42.0.0/23,2001:cafe:42::/63",
+			expectedServiceCIDR:     "unknown",
+			expectedPodCapacity:     512,
+			expectedServiceCapacity: -1,
+		},
+		{
+			name: "from kube-controller-manager flags",
+			mode: "recommended",
+			objects: []runtime.Object{
+				node("server-1", "10.42.0.0/24"),
+				staticPod("kube-controller-manager", "--cluster-cidr=10.42.0.0/16", "--service-cluster-ip-range=10.43.0.0/16"),
+			},
+			expectedPodCIDR:         "10.42.0.0/16",
+			expectedServiceCIDR:     "10.43.0.0/16",
+			expectedPodCapacity:     65536,
+			expectedServiceCapacity: 65536,
+		},
+		{
+			name: "service range from kube-apiserver",
+			mode: "recommended",
+			objects: []runtime.Object{
+				staticPod("kube-apiserver", "--service-cluster-ip-range=10.43.0.0/20"),
+			},
+			expectedPodCIDR:         "unknown",
+			expectedServiceCIDR:     "10.43.0.0/20",
+			expectedPodCapacity:     -1,
+			expectedServiceCapacity: 4096,
+		},
+		{
+			name: "minimal mode",
+			mode: "minimal",
+			objects: []runtime.Object{
+				staticPod("kube-controller-manager", "--cluster-cidr=10.42.0.0/16", "--service-cluster-ip-range=10.43.0.0/16"),
+			},
+			expectedPodCIDR:         "10.42.0.0/16",
+			expectedServiceCIDR:     "10.43.0.0/16",
+			expectedPodCapacity:     -1,
+			expectedServiceCapacity: -1,
 		},
-	)
-
-	data, err := Collect(context.Background(), clientset, "recommended")
-	if err != nil {
-		t.Fatalf("Collect() error = %v", err)
 	}
 
-	if data.ExtraFieldInfo["rancher-managed"] != true {
-		t.Errorf("rancher-managed = %v, want true", data.ExtraFieldInfo["rancher-managed"])
-	}
-	if data.ExtraFieldInfo["rancher-version"] != "v2.8.0" {
-		t.Errorf("rancher-version = %v, want v2.8.0", data.ExtraFieldInfo["rancher-version"])
-	}
-	if data.ExtraFieldInfo["rancher-install-uuid"] != "rancher-install-uuid-123" {
-		t.Errorf("rancher-install-uuid = %v, want rancher-install-uuid-123", data.ExtraFieldInfo["rancher-install-uuid"])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["pod-cidr"] != tt.expectedPodCIDR {
+				t.Errorf("pod-cidr = %v, want %v", data.ExtraFieldInfo["pod-cidr"], tt.expectedPodCIDR)
+			}
+			if data.ExtraFieldInfo["service-cidr"] != tt.expectedServiceCIDR {
+				t.Errorf("service-cidr = %v, want %v", data.ExtraFieldInfo["service-cidr"], tt.expectedServiceCIDR)
+			}
+			if data.ExtraFieldInfo["pod-cidr-capacity"] != tt.expectedPodCapacity {
+				t.Errorf("pod-cidr-capacity = %v, want %v", data.ExtraFieldInfo["pod-cidr-capacity"], tt.expectedPodCapacity)
+			}
+			if data.ExtraFieldInfo["service-cidr-capacity"] != tt.expectedServiceCapacity {
+				t.Errorf("service-cidr-capacity = %v, want %v", data.ExtraFieldInfo["service-cidr-capacity"], tt.expectedServiceCapacity)
+			}
+		})
 	}
 }
 
-func TestCollect_MissingKubeSystem(t *testing.T) {
-	clientset := fake.NewClientset()
+func TestCollect_CgroupVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []runtime.Object
+		expected string
+	}{
+		{
+			name: "labeled v2",
+			nodes: []runtime.Object{
+				testNode("server-1", nodeLabel("node.kubernetes.io/cgroup", "v2")),
+				testNode("agent-1", nodeLabel("node.kubernetes.io/cgroup-version", "cgroupv2")),
+				testNode("agent-2"),
+			},
+			expected: "v2",
+		},
+		{
+			name: "any v1 node",
+			nodes: []runtime.Object{
+				testNode("server-1", nodeLabel("node.kubernetes.io/cgroup", "2")),
+				testNode("agent-1", nodeLabel("node.kubernetes.io/cgroup", "v1")),
+			},
+			expected: "v1",
+		},
+		{
+			name:     "unrecognized value",
+			nodes:    []runtime.Object{testNode("server-1", nodeLabel("node.kubernetes.io/cgroup", "hybrid"))},
+			expected: "unknown",
+		},
+		{
+			name:     "no labels",
+			nodes:    []runtime.Object{testNode("server-1")},
+			expected: "unknown",
+		},
+	}
 
-	_, err := Collect(context.Background(), clientset, "recommended")
-	if err == nil {
-		t.Error("Collect() expected error for missing kube-system namespace")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["cgroup-version"] != tt.expected {
+				t.Errorf("cgroup-version = %v, want %v", data.ExtraFieldInfo["cgroup-version"], tt.expected)
+			}
+		})
 	}
 }
 
-func TestSend_Success(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			t.Errorf("expected POST, got %s", r.Method)
-		}
-		if r.Header.Get("Content-Type") != "application/json" {
-			t.Errorf("expected Content-Type application/json, got %s", r.Header.Get("Content-Type"))
+func TestCollect_EncryptionKeyAge(t *testing.T) {
+	now := time.Now()
+	kubeSystem := func(annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid", Annotations: annotations}}
+	}
+	rotationConfigMap := func(rotatedAt time.Time) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-secrets-encryption-rotation", Namespace: "kube-system"},
+			Data:       map[string]string{"rotated-at": rotatedAt.Format(time.RFC3339)},
 		}
+	}
 
-		w.WriteHeader(http.StatusOK)
-		resp := Response{
-			Versions: []Version{
-				{Name: "v1.30.1", ReleaseDate: "2024-01-01"},
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected interface{}
+	}{
+		{
+			name: "rotation annotation",
+			objects: []runtime.Object{kubeSystem(map[string]string{
+				"rke2.cattle.io/secrets-encryption-rotated-at": now.Add(-10*24*time.Hour - time.Hour).Format(time.RFC3339),
+			})},
+			expected: 10,
+		},
+		{
+			name: "newest of annotation and configmap",
+			objects: []runtime.Object{
+				kubeSystem(map[string]string{
+					"rke2.cattle.io/secrets-encryption-rotated-at": now.Add(-90 * 24 * time.Hour).Format(time.RFC3339),
+				}),
+				rotationConfigMap(now.Add(-3*24*time.Hour - time.Hour)),
 			},
-			RequestIntervalInMinutes: 480,
-		}
-		_ = json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	data := &Data{
-		AppVersion:     "v1.30.0",
-		ExtraTagInfo:   map[string]string{"clusteruuid": "test"},
-		ExtraFieldInfo: map[string]interface{}{"serverNodeCount": 1},
+			expected: 3,
+		},
+		{
+			name: "unparseable annotation",
+			objects: []runtime.Object{kubeSystem(map[string]string{
+				"rke2.cattle.io/secrets-encryption-rotated-at": "last tuesday",
+			})},
+			expected: nil,
+		},
+		{
+			name:     "no indicator",
+			objects:  []runtime.Object{kubeSystem(nil)},
+			expected: nil,
+		},
 	}
 
-	resp, err := Send(context.Background(), data, server.URL)
-	if err != nil {
-		t.Fatalf("Send() error = %v", err)
-	}
-	if resp == nil {
-		t.Fatal("Send() returned nil response")
-	}
-	if len(resp.Versions) != 1 {
-		t.Errorf("expected 1 version, got %d", len(resp.Versions))
-	}
-	if resp.Versions[0].Name != "v1.30.1" {
-		t.Errorf("version name = %q, want v1.30.1", resp.Versions[0].Name)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(tt.objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if got := data.ExtraFieldInfo["encryption-key-age-days"]; got != tt.expected {
+				t.Errorf("encryption-key-age-days = %v, want %v", got, tt.expected)
+			}
+		})
 	}
 }
 
-func TestSend_RetryOnError(t *testing.T) {
-	var attempts atomic.Int32
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		attempt := attempts.Add(1)
-		if attempt < 3 {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(Response{})
-	}))
-	defer server.Close()
+func TestCollect_Collectors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []CollectOption
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "all by default",
+			present: []string{"cni-plugin", "ingress-controller", "etcd-tls", "pdb-count"},
+		},
+		{
+			name:    "include list",
+			opts:    []CollectOption{WithCollectors("cni", "ingress", "nodes")},
+			present: []string{"cni-plugin", "cni-encryption", "ingress-controller"},
+			absent:  []string{"etcd-tls", "pdb-count", "rancher-managed", "root-pod-count", "pod-cidr", "api-group-count"},
+		},
+		{
+			name:    "exclude list",
+			opts:    []CollectOption{WithoutCollectors("cni", "pods")},
+			present: []string{"ingress-controller", "etcd-tls", "pod-cidr"},
+			absent:  []string{"cni-plugin", "cni-encryption", "root-pod-count", "mac-in-use"},
+		},
+		{
+			name:    "include and exclude",
+			opts:    []CollectOption{WithCollectors("cni", "ingress"), WithoutCollectors("ingress")},
+			present: []string{"cni-plugin"},
+			absent:  []string{"ingress-controller"},
+		},
+	}
 
-	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "server-1"}},
+			)
 
-	_, err := Send(context.Background(), data, server.URL)
+			data, err := Collect(context.Background(), clientset, "recommended", tt.opts...)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			// Mandatory collection always happens
+			if data.ExtraTagInfo["clusteruuid"] != "uuid" {
+				t.Errorf("clusteruuid = %q, want uuid", data.ExtraTagInfo["clusteruuid"])
+			}
+			if data.ExtraFieldInfo["serverNodeCount"] != 0 || data.ExtraFieldInfo["agentNodeCount"] != 1 {
+				t.Errorf("node counts = %v/%v, want 0/1", data.ExtraFieldInfo["serverNodeCount"], data.ExtraFieldInfo["agentNodeCount"])
+			}
+			for _, key := range tt.present {
+				if _, ok := data.ExtraFieldInfo[key]; !ok {
+					t.Errorf("%s missing", key)
+				}
+			}
+			for _, key := range tt.absent {
+				if value, ok := data.ExtraFieldInfo[key]; ok {
+					t.Errorf("%s = %v, want it absent", key, value)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCollectors(t *testing.T) {
+	names, err := ParseCollectors(" cni, ingress ,nodes,")
 	if err != nil {
-		t.Fatalf("Send() error = %v", err)
+		t.Fatalf("ParseCollectors() error = %v", err)
 	}
-	if attempts.Load() != 3 {
-		t.Errorf("expected 3 attempts, got %d", attempts.Load())
+	if want := []string{"cni", "ingress", "nodes"}; !slices.Equal(names, want) {
+		t.Errorf("ParseCollectors() = %v, want %v", names, want)
 	}
-}
 
-func TestSend_AllRetriesFail(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-	}))
-	defer server.Close()
+	if _, err := ParseCollectors("cni,bogus"); err == nil || !strings.Contains(err.Error(), `"bogus"`) {
+		t.Errorf("ParseCollectors() error = %v, want unknown collector \"bogus\"", err)
+	}
+}
 
-	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+func TestCollect_InvalidSkew(t *testing.T) {
+	node := func(name, kubelet string) *corev1.Node {
+		return testNode(name, nodeInfo(corev1.NodeSystemInfo{KubeletVersion: kubelet}))
+	}
 
-	_, err := Send(context.Background(), data, server.URL)
-	if err == nil {
-		t.Error("Send() expected error after all retries fail")
+	tests := []struct {
+		name      string
+		apiserver string
+		nodes     []runtime.Object
+		expected  bool
+	}{
+		{
+			name:      "kubelet ahead of apiserver",
+			apiserver: "v1.31.4+rke2r1",
+			nodes:     []runtime.Object{node("server-1", "v1.31.4+rke2r1"), node("agent-1", "v1.32.2+rke2r1")},
+			expected:  true,
+		},
+		{
+			name:      "kubelet patch ahead of apiserver",
+			apiserver: "v1.32.1+rke2r1",
+			nodes:     []runtime.Object{node("agent-1", "v1.32.2+rke2r1")},
+			expected:  true,
+		},
+		{
+			name:      "kubelets older or equal",
+			apiserver: "v1.32.2+rke2r1",
+			nodes:     []runtime.Object{node("server-1", "v1.32.2+rke2r1"), node("agent-1", "v1.30.9+rke2r1")},
+			expected:  false,
+		},
+		{
+			name:      "build metadata ignored",
+			apiserver: "v1.32.2+rke2r1",
+			nodes:     []runtime.Object{node("agent-1", "v1.32.2+rke2r2")},
+			expected:  false,
+		},
+		{
+			name:      "unparsable apiserver version",
+			apiserver: "",
+			nodes:     []runtime.Object{node("agent-1", "v1.32.2+rke2r1")},
+			expected:  false,
+		},
 	}
-}
 
-func TestSend_MalformedResponse(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("not json"))
-	}))
-	defer server.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &k8sversion.Info{GitVersion: tt.apiserver}
 
-	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
 
-	resp, err := Send(context.Background(), data, server.URL)
-	if err != nil {
-		t.Errorf("Send() error = %v, want nil (graceful degradation)", err)
-	}
-	if resp != nil {
-		t.Errorf("Send() response = %v, want nil", resp)
+			if data.ExtraFieldInfo["invalid-skew"] != tt.expected {
+				t.Errorf("invalid-skew = %v, want %v", data.ExtraFieldInfo["invalid-skew"], tt.expected)
+			}
+		})
 	}
 }
 
-func TestCollect_GPUOperatorDetection(t *testing.T) {
-	clientset := fake.NewClientset(
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "gpu-operator"}},
-		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
-		},
-		&appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{Name: "nvidia-device-plugin-daemonset", Namespace: "gpu-operator"},
-			Spec: appsv1.DaemonSetSpec{
-				Template: corev1.PodTemplateSpec{
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{{Image: "nvcr.io/nvidia/k8s-device-plugin:v0.14.0"}},
-					},
-				},
+func TestCollect_EOLOS(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		nodes         []runtime.Object
+		expectedCount int
+		expectedEOL   bool
+	}{
+		{
+			name: "one EOL node",
+			mode: "recommended",
+			nodes: []runtime.Object{
+				testNode("node-1", nodeInfo(corev1.NodeSystemInfo{OSImage: "Ubuntu 18.04.6 LTS"})),
+				testNode("node-2", nodeInfo(corev1.NodeSystemInfo{OSImage: "Ubuntu 24.04.1 LTS"})),
 			},
+			expectedCount: 1,
+			expectedEOL:   true,
+		},
+		{
+			name:          "supported only",
+			mode:          "recommended",
+			nodes:         []runtime.Object{testNode("node-1", nodeInfo(corev1.NodeSystemInfo{OSImage: "SLE Micro 6.1"}))},
+			expectedCount: 0,
+			expectedEOL:   false,
+		},
+		{
+			name:          "minimal mode",
+			mode:          "minimal",
+			nodes:         []runtime.Object{testNode("node-1", nodeInfo(corev1.NodeSystemInfo{OSImage: "CentOS Linux 7 (Core)"}))},
+			expectedCount: -1,
+			expectedEOL:   true,
 		},
-	)
-
-	data, err := Collect(context.Background(), clientset, "recommended")
-	if err != nil {
-		t.Fatalf("Collect() error = %v", err)
 	}
 
-	if data.ExtraFieldInfo["gpu-operator"] != "nvidia-gpu-operator" {
-		t.Errorf("gpu-operator = %v, want nvidia-gpu-operator", data.ExtraFieldInfo["gpu-operator"])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["eol-os-node-count"] != tt.expectedCount {
+				t.Errorf("eol-os-node-count = %v, want %v", data.ExtraFieldInfo["eol-os-node-count"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["has-eol-os"] != tt.expectedEOL {
+				t.Errorf("has-eol-os = %v, want %v", data.ExtraFieldInfo["has-eol-os"], tt.expectedEOL)
+			}
+		})
 	}
 }
 
-func TestCollect_IngressAsDaemonSet(t *testing.T) {
-	clientset := fake.NewClientset(
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+func TestCollect_KernelRisk(t *testing.T) {
+	node := func(name, kernel string) *corev1.Node {
+		return testNode(name, nodeInfo(corev1.NodeSystemInfo{KernelVersion: kernel}))
+	}
+
+	tests := []struct {
+		name          string
+		mode          string
+		nodes         []runtime.Object
+		expectedRisk  string
+		expectedCount int
+	}{
+		{
+			name:          "vulnerable kernel",
+			mode:          "recommended",
+			nodes:         []runtime.Object{node("node-1", "5.10.90"), node("node-2", "5.15.0-91-generic")},
+			expectedRisk:  "known-vulnerable",
+			expectedCount: 1,
 		},
-		&appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
-			Spec: appsv1.DaemonSetSpec{
-				Template: corev1.PodTemplateSpec{
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{{Image: "rancher/nginx-ingress-controller:v1.9.0"}},
-					},
-				},
-			},
+		{
+			name:          "patched kernel",
+			mode:          "recommended",
+			nodes:         []runtime.Object{node("node-1", "5.15.142-flatcar")},
+			expectedRisk:  "ok",
+			expectedCount: 0,
+		},
+		{
+			name:          "distro kernel",
+			mode:          "recommended",
+			nodes:         []runtime.Object{node("node-1", "5.15.142-flatcar"), node("node-2", "5.15.0-91-generic")},
+			expectedRisk:  "unknown",
+			expectedCount: 0,
+		},
+		{
+			name:          "no nodes",
+			mode:          "recommended",
+			expectedRisk:  "unknown",
+			expectedCount: 0,
+		},
+		{
+			name:          "minimal mode",
+			mode:          "minimal",
+			nodes:         []runtime.Object{node("node-1", "5.10.90")},
+			expectedRisk:  "known-vulnerable",
+			expectedCount: -1,
 		},
-	)
-
-	data, err := Collect(context.Background(), clientset, "recommended")
-	if err != nil {
-		t.Fatalf("Collect() error = %v", err)
 	}
 
-	if data.ExtraFieldInfo["ingress-controller"] != "rke2-ingress-nginx" {
-		t.Errorf("ingress-controller = %v, want rke2-ingress-nginx", data.ExtraFieldInfo["ingress-controller"])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["kernel-risk"] != tt.expectedRisk {
+				t.Errorf("kernel-risk = %v, want %v", data.ExtraFieldInfo["kernel-risk"], tt.expectedRisk)
+			}
+			if data.ExtraFieldInfo["vulnerable-kernel-node-count"] != tt.expectedCount {
+				t.Errorf("vulnerable-kernel-node-count = %v, want %v", data.ExtraFieldInfo["vulnerable-kernel-node-count"], tt.expectedCount)
+			}
+		})
 	}
 }
 
-func TestCollect_IPStackFromService(t *testing.T) {
+func TestCollect_CNIDetection(t *testing.T) {
 	tests := []struct {
-		name       string
-		ipFamilies []corev1.IPFamily
-		expected   string
+		name        string
+		daemonSet   string
+		image       string
+		expectedCNI string
 	}{
-		{"ipv4-only", []corev1.IPFamily{corev1.IPv4Protocol}, "ipv4-only"},
-		{"ipv6-only", []corev1.IPFamily{corev1.IPv6Protocol}, "ipv6-only"},
-		{"dual-stack", []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}, "dual-stack"},
-		{"dual-stack-v6-first", []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}, "dual-stack"},
-		{"empty-families", []corev1.IPFamily{}, "unknown"},
+		{"canal", "rke2-canal", "rancher/hardened-calico:v3.26.0", "canal"},
+		{"flannel", "kube-flannel-ds", "flannel/flannel:v0.22.0", "flannel"},
+		{"calico", "calico-node", "calico/node:v3.26.0", "calico"},
+		{"cilium", "cilium", "cilium/cilium:v1.14.0", "cilium"},
+		{"weave", "weave-net", "weaveworks/weave-kube:2.8.1", "weave"},
 	}
 
 	for _, tt := range tests {
@@ -582,13 +997,17 @@ func TestCollect_IPStackFromService(t *testing.T) {
 				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
 				&corev1.Node{
 					ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-					Status: corev1.NodeStatus{
-						NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
-					},
+					Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
 				},
-				&corev1.Service{
-					ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
-					Spec:       corev1.ServiceSpec{IPFamilies: tt.ipFamilies},
+				&appsv1.DaemonSet{
+					ObjectMeta: metav1.ObjectMeta{Name: tt.daemonSet, Namespace: "kube-system"},
+					Spec: appsv1.DaemonSetSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Image: tt.image}},
+							},
+						},
+					},
 				},
 			)
 
@@ -597,149 +1016,369 @@ func TestCollect_IPStackFromService(t *testing.T) {
 				t.Fatalf("Collect() error = %v", err)
 			}
 
-			if data.ExtraFieldInfo["ip-stack"] != tt.expected {
-				t.Errorf("ip-stack = %v, want %v", data.ExtraFieldInfo["ip-stack"], tt.expected)
+			if data.ExtraFieldInfo["cni-plugin"] != tt.expectedCNI {
+				t.Errorf("cni-plugin = %v, want %v", data.ExtraFieldInfo["cni-plugin"], tt.expectedCNI)
 			}
 		})
 	}
 }
 
-func TestCollect_IPStackNoService(t *testing.T) {
-	clientset := fake.NewClientset(
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
-			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+func TestCollect_CNIEncryption(t *testing.T) {
+	daemonSet := func(name string, env ...corev1.EnvVar) *appsv1.DaemonSet {
+		ds := testDaemonSet(name, "kube-system", "")
+		ds.Spec.Template.Spec.Containers[0].Env = env
+		return ds
+	}
+	configMap := func(name string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"}, Data: data}
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{
+			name: "cilium wireguard",
+			objects: []runtime.Object{
+				daemonSet("cilium"),
+				configMap("cilium-config", map[string]string{"enable-wireguard": "true"}),
+			},
+			expected: "wireguard",
 		},
-	)
+		{
+			name: "cilium ipsec",
+			objects: []runtime.Object{
+				daemonSet("cilium"),
+				configMap("cilium-config", map[string]string{"enable-ipsec": "true"}),
+			},
+			expected: "ipsec",
+		},
+		{
+			name: "cilium without encryption",
+			objects: []runtime.Object{
+				daemonSet("cilium"),
+				configMap("cilium-config", map[string]string{"enable-wireguard": "false"}),
+			},
+			expected: "none",
+		},
+		{
+			name:     "cilium config missing",
+			objects:  []runtime.Object{daemonSet("cilium")},
+			expected: "unknown",
+		},
+		{
+			name: "canal flannel wireguard backend",
+			objects: []runtime.Object{
+				daemonSet("rke2-canal"),
+				configMap("rke2-canal-config", map[string]string{"net-conf.json": `{"Network": "10.42.0.0/16", "Backend": {"Type": "wireguard"}}`}),
+			},
+			expected: "wireguard",
+		},
+		{
+			name: "canal vxlan backend",
+			objects: []runtime.Object{
+				daemonSet("rke2-canal"),
+				configMap("rke2-canal-config", map[string]string{"net-conf.json": `{"Network": "10.42.0.0/16", "Backend": {"Type": "vxlan"}}`}),
+			},
+			expected: "none",
+		},
+		{
+			name:     "calico felix wireguard env",
+			objects:  []runtime.Object{daemonSet("calico-node", corev1.EnvVar{Name: "FELIX_WIREGUARDENABLED", Value: "true"})},
+			expected: "wireguard",
+		},
+		{
+			name:     "calico without env",
+			objects:  []runtime.Object{daemonSet("calico-node")},
+			expected: "unknown",
+		},
+	}
 
-	data, err := Collect(context.Background(), clientset, "recommended")
-	if err != nil {
-		t.Fatalf("Collect() error = %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["cni-encryption"] != tt.expected {
+				t.Errorf("cni-encryption = %v, want %v", data.ExtraFieldInfo["cni-encryption"], tt.expected)
+			}
+		})
 	}
+}
 
-	if data.ExtraFieldInfo["ip-stack"] != "unknown" {
-		t.Errorf("ip-stack = %v, want unknown", data.ExtraFieldInfo["ip-stack"])
+func TestCollect_IngressDetection(t *testing.T) {
+	tests := []struct {
+		name            string
+		deploymentName  string
+		image           string
+		expectedIngress string
+	}{
+		{"nginx", "rke2-ingress-nginx-controller", "rancher/nginx-ingress-controller:v1.9.0", "rke2-ingress-nginx"},
+		{"traefik", "traefik", "traefik:v2.10", "traefik"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+					Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+				},
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: tt.deploymentName, Namespace: "kube-system"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Image: tt.image}},
+							},
+						},
+					},
+				},
+			)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["ingress-controller"] != tt.expectedIngress {
+				t.Errorf("ingress-controller = %v, want %v", data.ExtraFieldInfo["ingress-controller"], tt.expectedIngress)
+			}
+		})
 	}
 }
 
-func TestCollect_MinimalMode(t *testing.T) {
+func TestCollect_GPUDetection(t *testing.T) {
 	clientset := fake.NewClientset(
 		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
-		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
 		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:   "server-1",
-				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
-			},
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu-node-1"},
 			Status: corev1.NodeStatus{
 				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
 				Allocatable: corev1.ResourceList{
-					corev1.ResourceCPU:    resource.MustParse("4"),
-					corev1.ResourceMemory: resource.MustParse("8Gi"),
+					"nvidia.com/gpu": resource.MustParse("2"),
 				},
 			},
 		},
 		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{Name: "agent-1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "cpu-node-1"},
 			Status: corev1.NodeStatus{
 				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
-				Allocatable: corev1.ResourceList{
-					corev1.ResourceCPU:    resource.MustParse("8"),
-					corev1.ResourceMemory: resource.MustParse("16Gi"),
-				},
-			},
-		},
-		&appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
-			Spec: appsv1.DeploymentSpec{
-				Template: corev1.PodTemplateSpec{
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{{
-							Image: "rancher/rancher-agent:v2.8.0",
-							Env: []corev1.EnvVar{
-								{Name: "CATTLE_INSTALL_UUID", Value: "test-uuid"},
-							},
-						}},
-					},
-				},
 			},
 		},
 	)
 
-	data, err := Collect(context.Background(), clientset, "minimal")
+	data, err := Collect(context.Background(), clientset, "recommended")
 	if err != nil {
 		t.Fatalf("Collect() error = %v", err)
 	}
 
-	// Mode field should be set
-	if data.ExtraFieldInfo["mode"] != "minimal" {
-		t.Errorf("mode = %q, want %q", data.ExtraFieldInfo["mode"], "minimal")
-	}
-
-	// Node counts should be -1 in minimal mode
-	if data.ExtraFieldInfo["serverNodeCount"] != -1 {
-		t.Errorf("serverNodeCount = %v, want -1", data.ExtraFieldInfo["serverNodeCount"])
+	if data.ExtraFieldInfo["gpuNodeCount"] != 1 {
+		t.Errorf("gpuNodeCount = %v, want 1", data.ExtraFieldInfo["gpuNodeCount"])
 	}
-	if data.ExtraFieldInfo["agentNodeCount"] != -1 {
-		t.Errorf("agentNodeCount = %v, want -1", data.ExtraFieldInfo["agentNodeCount"])
+	if data.ExtraFieldInfo["gpu-vendor"] != "nvidia" {
+		t.Errorf("gpu-vendor = %v, want nvidia", data.ExtraFieldInfo["gpu-vendor"])
 	}
-	if data.ExtraFieldInfo["gpuNodeCount"] != -1 {
-		t.Errorf("gpuNodeCount = %v, want -1", data.ExtraFieldInfo["gpuNodeCount"])
+}
+
+func TestCollect_GPUResources(t *testing.T) {
+	gpuNode := func(resourceName corev1.ResourceName) *corev1.Node {
+		return testNode("gpu-node-1", nodeAllocatable(corev1.ResourceList{resourceName: resource.MustParse("1")}))
 	}
 
-	// CPU/memory should be -1 in minimal mode
-	if data.ExtraFieldInfo["serverCPU"] != int64(-1) {
-		t.Errorf("serverCPU = %v, want -1", data.ExtraFieldInfo["serverCPU"])
+	tests := []struct {
+		name           string
+		node           *corev1.Node
+		opts           []CollectOption
+		expectedCount  int
+		expectedVendor any
+	}{
+		{
+			name:           "habana gaudi",
+			node:           gpuNode("habana.ai/gaudi"),
+			expectedCount:  1,
+			expectedVendor: "habana",
+		},
+		{
+			name:           "nvidia shared GPU",
+			node:           gpuNode("nvidia.com/gpu.shared"),
+			expectedCount:  1,
+			expectedVendor: "nvidia",
+		},
+		{
+			name:           "intel i915",
+			node:           gpuNode("gpu.intel.com/i915"),
+			expectedCount:  1,
+			expectedVendor: "intel",
+		},
+		{
+			name:           "unknown resource",
+			node:           gpuNode("example.com/gpu"),
+			expectedCount:  0,
+			expectedVendor: nil,
+		},
+		{
+			name:           "added resource",
+			node:           gpuNode("example.com/gpu"),
+			opts:           []CollectOption{WithGPUResources([]GPUResource{{"example.com/gpu", "example"}})},
+			expectedCount:  1,
+			expectedVendor: "example",
+		},
+		{
+			name:           "overridden vendor",
+			node:           gpuNode("habana.ai/gaudi"),
+			opts:           []CollectOption{WithGPUResources([]GPUResource{{"habana.ai/gaudi", "intel"}})},
+			expectedCount:  1,
+			expectedVendor: "intel",
+		},
 	}
-	if data.ExtraFieldInfo["agentCPU"] != int64(-1) {
-		t.Errorf("agentCPU = %v, want -1", data.ExtraFieldInfo["agentCPU"])
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				tt.node,
+			)
+
+			data, err := Collect(context.Background(), clientset, "recommended", tt.opts...)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["gpuNodeCount"] != tt.expectedCount {
+				t.Errorf("gpuNodeCount = %v, want %v", data.ExtraFieldInfo["gpuNodeCount"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["gpu-vendor"] != tt.expectedVendor {
+				t.Errorf("gpu-vendor = %v, want %v", data.ExtraFieldInfo["gpu-vendor"], tt.expectedVendor)
+			}
+		})
 	}
-	if data.ExtraFieldInfo["serverMemory"] != int64(-1) {
-		t.Errorf("serverMemory = %v, want -1", data.ExtraFieldInfo["serverMemory"])
+}
+
+func TestParseGPUResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []GPUResource
+		wantErr  bool
+	}{
+		{
+			name:     "pairs with spaces",
+			spec:     "habana.ai/gaudi=habana, example.com/gpu = example,",
+			expected: []GPUResource{{"habana.ai/gaudi", "habana"}, {"example.com/gpu", "example"}},
+		},
+		{
+			name:    "missing vendor",
+			spec:    "example.com/gpu",
+			wantErr: true,
+		},
+		{
+			name:    "empty resource",
+			spec:    "=example",
+			wantErr: true,
+		},
 	}
-	if data.ExtraFieldInfo["agentMemory"] != int64(-1) {
-		t.Errorf("agentMemory = %v, want -1", data.ExtraFieldInfo["agentMemory"])
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGPUResources(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGPUResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.expected) {
+				t.Errorf("ParseGPUResources() = %v, want %v", got, tt.expected)
+			}
+		})
 	}
+}
 
-	// Rancher-managed should still be present
-	if data.ExtraFieldInfo["rancher-managed"] != true {
-		t.Errorf("rancher-managed = %v, want true", data.ExtraFieldInfo["rancher-managed"])
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "pairs with spaces",
+			spec:     "env=prod, region = eu-west,",
+			expected: map[string]string{"env": "prod", "region": "eu-west"},
+		},
+		{
+			name:     "empty value",
+			spec:     "team=",
+			expected: map[string]string{"team": ""},
+		},
+		{
+			name:    "missing value",
+			spec:    "env",
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			spec:    "=prod",
+			wantErr: true,
+		},
+		{
+			name:    "reserved clusteruuid",
+			spec:    "env=prod,clusteruuid=spoofed",
+			wantErr: true,
+		},
+		{
+			name:    "reserved kubernetesVersion",
+			spec:    "kubernetesVersion=v1.0.0",
+			wantErr: true,
+		},
 	}
 
-	// Rancher version and UUID should be empty strings in minimal mode
-	if data.ExtraFieldInfo["rancher-version"] != "" {
-		t.Errorf("rancher-version = %v, want empty string", data.ExtraFieldInfo["rancher-version"])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTags(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.expected) {
+				t.Errorf("ParseTags() = %v, want %v", got, tt.expected)
+			}
+		})
 	}
-	if data.ExtraFieldInfo["rancher-install-uuid"] != "" {
-		t.Errorf("rancher-install-uuid = %v, want empty string", data.ExtraFieldInfo["rancher-install-uuid"])
+}
+
+func TestCollect_Tags(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+	)
+
+	data, err := Collect(context.Background(), clientset, "minimal",
+		WithTags(map[string]string{"env": "prod", "clusteruuid": "spoofed"}))
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
 	}
 
-	// OS info should still be present
-	if data.ExtraFieldInfo["os"] != "test" {
-		t.Errorf("os = %v, want test", data.ExtraFieldInfo["os"])
+	if data.ExtraTagInfo["env"] != "prod" {
+		t.Errorf("env = %q, want prod", data.ExtraTagInfo["env"])
 	}
-	if data.ExtraFieldInfo["arch"] != "amd64" {
-		t.Errorf("arch = %v, want amd64", data.ExtraFieldInfo["arch"])
+	if data.ExtraTagInfo["clusteruuid"] != "uuid" {
+		t.Errorf("clusteruuid = %q, want the reserved tag kept as uuid", data.ExtraTagInfo["clusteruuid"])
 	}
 }
 
-func TestCollect_RecommendedModeIncludesRancherDetails(t *testing.T) {
+func TestCollect_RancherManaged(t *testing.T) {
 	clientset := fake.NewClientset(
 		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
 		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
 		&corev1.Node{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:   "server-1",
-				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
-			},
-			Status: corev1.NodeStatus{
-				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
-				Allocatable: corev1.ResourceList{
-					corev1.ResourceCPU:    resource.MustParse("4"),
-					corev1.ResourceMemory: resource.MustParse("8Gi"),
-				},
-			},
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
 		},
 		&appsv1.Deployment{
 			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
@@ -749,7 +1388,7 @@ func TestCollect_RecommendedModeIncludesRancherDetails(t *testing.T) {
 						Containers: []corev1.Container{{
 							Image: "rancher/rancher-agent:v2.8.0",
 							Env: []corev1.EnvVar{
-								{Name: "CATTLE_INSTALL_UUID", Value: "test-uuid"},
+								{Name: "CATTLE_INSTALL_UUID", Value: "rancher-install-uuid-123"},
 							},
 						}},
 					},
@@ -763,27 +1402,4463 @@ func TestCollect_RecommendedModeIncludesRancherDetails(t *testing.T) {
 		t.Fatalf("Collect() error = %v", err)
 	}
 
-	// Mode field should be set
-	if data.ExtraFieldInfo["mode"] != "recommended" {
-		t.Errorf("mode = %q, want %q", data.ExtraFieldInfo["mode"], "recommended")
+	if data.ExtraFieldInfo["rancher-managed"] != true {
+		t.Errorf("rancher-managed = %v, want true", data.ExtraFieldInfo["rancher-managed"])
+	}
+	if data.ExtraFieldInfo["rancher-version"] != "v2.8.0" {
+		t.Errorf("rancher-version = %v, want v2.8.0", data.ExtraFieldInfo["rancher-version"])
+	}
+	if data.ExtraFieldInfo["rancher-install-uuid"] != "rancher-install-uuid-123" {
+		t.Errorf("rancher-install-uuid = %v, want rancher-install-uuid-123", data.ExtraFieldInfo["rancher-install-uuid"])
 	}
+}
 
-	// Node counts should have actual values
-	if data.ExtraFieldInfo["serverNodeCount"] != 1 {
-		t.Errorf("serverNodeCount = %v, want 1", data.ExtraFieldInfo["serverNodeCount"])
+func TestCollect_ClusterUUIDSources(t *testing.T) {
+	rancherAgent := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Image: "rancher/rancher-agent:v2.8.0",
+						Env:   []corev1.EnvVar{{Name: "CATTLE_INSTALL_UUID", Value: "rancher-install-uuid"}},
+					}},
+				},
+			},
+		},
 	}
 
-	// CPU/memory should have actual values
-	serverCPU, ok := data.ExtraFieldInfo["serverCPU"].(int64)
-	if !ok || serverCPU == 0 {
-		t.Errorf("serverCPU = %v, want non-zero", data.ExtraFieldInfo["serverCPU"])
+	tests := []struct {
+		name             string
+		kubeSystemUID    string
+		rancher          bool
+		opts             []CollectOption
+		expectedUUID     string
+		expectedSource   string
+		expectedIdentity string
+	}{
+		{
+			name:             "kube-system UID",
+			kubeSystemUID:    "kube-system-uid",
+			rancher:          true,
+			opts:             []CollectOption{WithClusterID("operator-id")},
+			expectedUUID:     "kube-system-uid",
+			expectedSource:   "kube-system",
+			expectedIdentity: "kube-system-uid/rancher-install-uuid",
+		},
+		{
+			name:             "operator cluster ID",
+			rancher:          true,
+			opts:             []CollectOption{WithClusterID("operator-id")},
+			expectedUUID:     "operator-id",
+			expectedSource:   "cluster-id",
+			expectedIdentity: "operator-id/rancher-install-uuid",
+		},
+		{
+			name:             "Rancher install UUID",
+			rancher:          true,
+			expectedUUID:     "rancher-install-uuid",
+			expectedSource:   "rancher",
+			expectedIdentity: "rancher-install-uuid",
+		},
+		{
+			name:             "no source",
+			expectedUUID:     "",
+			expectedSource:   "none",
+			expectedIdentity: "",
+		},
+		{
+			name:             "operator ID first",
+			kubeSystemUID:    "kube-system-uid",
+			opts:             []CollectOption{WithClusterID("operator-id"), WithUUIDSources("cluster-id", "kube-system")},
+			expectedUUID:     "operator-id",
+			expectedSource:   "cluster-id",
+			expectedIdentity: "operator-id",
+		},
+		{
+			name:             "Rancher left out",
+			rancher:          true,
+			opts:             []CollectOption{WithUUIDSources("kube-system", "cluster-id")},
+			expectedUUID:     "",
+			expectedSource:   "none",
+			expectedIdentity: "",
+		},
 	}
 
-	// Rancher details should be present in recommended mode
-	if data.ExtraFieldInfo["rancher-version"] != "v2.8.0" {
-		t.Errorf("rancher-version = %v, want v2.8.0", data.ExtraFieldInfo["rancher-version"])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: types.UID(tt.kubeSystemUID)}},
+			}
+			if tt.rancher {
+				objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}}, rancherAgent)
+			}
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended", tt.opts...)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraTagInfo["clusteruuid"] != tt.expectedUUID {
+				t.Errorf("clusteruuid = %q, want %q", data.ExtraTagInfo["clusteruuid"], tt.expectedUUID)
+			}
+			if data.ExtraTagInfo["clusteruuid-source"] != tt.expectedSource {
+				t.Errorf("clusteruuid-source = %q, want %q", data.ExtraTagInfo["clusteruuid-source"], tt.expectedSource)
+			}
+			if data.ExtraTagInfo["clusterIdentity"] != tt.expectedIdentity {
+				t.Errorf("clusterIdentity = %q, want %q", data.ExtraTagInfo["clusterIdentity"], tt.expectedIdentity)
+			}
+		})
 	}
-	if data.ExtraFieldInfo["rancher-install-uuid"] != "test-uuid" {
-		t.Errorf("rancher-install-uuid = %v, want test-uuid", data.ExtraFieldInfo["rancher-install-uuid"])
+}
+
+func TestParseUUIDSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "reordered", spec: "cluster-id, kube-system,", expected: []string{"cluster-id", "kube-system"}},
+		{name: "unknown", spec: "kube-system,hostname", wantErr: true},
+		{name: "repeated", spec: "rancher,rancher", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUUIDSources(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUUIDSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.expected) {
+				t.Errorf("ParseUUIDSources() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_RancherClusterRole(t *testing.T) {
+	agent := func(clusterName string) *appsv1.Deployment {
+		d := testDeployment("cattle-cluster-agent", "cattle-system", "rancher/rancher-agent:v2.8.0")
+		d.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "CATTLE_CLUSTER_NAME", Value: clusterName}}
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		failing  bool
+		expected any
+	}{
+		{
+			name: "rancher server deployment",
+			objects: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "rancher", Namespace: "cattle-system"}},
+				agent("local"),
+			},
+			expected: "local",
+		},
+		{
+			name:     "agent registered as local",
+			objects:  []runtime.Object{agent("local")},
+			expected: "local",
+		},
+		{
+			name:     "downstream agent",
+			objects:  []runtime.Object{agent("c-m-abc123")},
+			expected: "downstream",
+		},
+		{
+			name:     "rancher deployment get fails",
+			objects:  []runtime.Object{agent("c-m-abc123")},
+			failing:  true,
+			expected: "downstream",
+		},
+		{
+			name:     "no rancher or agent deployment",
+			objects:  nil,
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+			if tt.failing {
+				clientset.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if action.(k8stesting.GetAction).GetName() != "rancher" {
+						return false, nil, nil
+					}
+					return true, nil, apierrors.NewServiceUnavailable("unavailable")
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["rancher-cluster-role"] != tt.expected {
+				t.Errorf("rancher-cluster-role = %v, want %v", data.ExtraFieldInfo["rancher-cluster-role"], tt.expected)
+			}
+			ran := strings.Split(data.ExtraFieldInfo["collectors-run"].(string), ",")
+			if slices.Contains(ran, "rancher") == tt.failing {
+				t.Errorf("collectors-run = %v, want rancher included %v", ran, !tt.failing)
+			}
+		})
+	}
+}
+
+func TestCollect_EtcdS3Snapshots(t *testing.T) {
+	snapshotFile := func(name string, s3 bool) runtime.Object {
+		spec := map[string]interface{}{"snapshotName": name, "location": "file:///var/lib/rancher/rke2/server/db/snapshots/" + name}
+		if s3 {
+			spec["location"] = "s3://backups/" + name
+			spec["s3"] = map[string]interface{}{"bucket": "backups", "endpoint": "s3.amazonaws.com"}
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "k3s.cattle.io/v1",
+			"kind":       "ETCDSnapshotFile",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec":       spec,
+		}}
+	}
+	snapshotAPI := []*metav1.APIResourceList{
+		{GroupVersion: "k3s.cattle.io/v1", APIResources: []metav1.APIResource{{Name: "etcdsnapshotfiles"}}},
+	}
+
+	tests := []struct {
+		name      string
+		objects   []runtime.Object
+		served    []*metav1.APIResourceList
+		snapshots []runtime.Object
+		expected  interface{}
+	}{
+		{
+			name:      "s3 snapshot file",
+			served:    snapshotAPI,
+			snapshots: []runtime.Object{snapshotFile("local-1", false), snapshotFile("s3-1", true)},
+			expected:  true,
+		},
+		{
+			name:      "local snapshot files only",
+			served:    snapshotAPI,
+			snapshots: []runtime.Object{snapshotFile("local-1", false)},
+			expected:  false,
+		},
+		{
+			name: "s3 entry in snapshot configmap",
+			objects: []runtime.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "rke2-etcd-snapshots", Namespace: "kube-system"},
+				Data:       map[string]string{"s3-etcd-snapshot-server-1-1700000000": "{}"},
+			}},
+			expected: true,
+		},
+		{
+			name:     "no snapshot records",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = tt.served
+			dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{etcdSnapshotFileResource: "ETCDSnapshotFileList"},
+				tt.snapshots...)
+
+			data, err := Collect(context.Background(), clientset, "recommended", WithDynamicClient(dynamicClient))
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if got := data.ExtraFieldInfo["etcd-s3-snapshots"]; got != tt.expected {
+				t.Errorf("etcd-s3-snapshots = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_DetectorTimeout(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol}},
+		},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "k3s.cattle.io/v1", APIResources: []metav1.APIResource{{Name: "etcdsnapshotfiles"}}},
+	}
+	// A hung API call that ignores its context, like a broken aggregated API
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{etcdSnapshotFileResource: "ETCDSnapshotFileList"})
+	dynamicClient.PrependReactor("list", "etcdsnapshotfiles", func(k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+		return true, nil, errors.New("released")
+	})
+
+	start := time.Now()
+	data, err := Collect(context.Background(), clientset, "recommended",
+		WithDynamicClient(dynamicClient), WithDetectorTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Collect() took %v, want it to abandon the hung detector", elapsed)
+	}
+
+	if got := data.ExtraFieldInfo["collectors-timed-out"]; got != "etcd-snapshots" {
+		t.Errorf("collectors-timed-out = %v, want etcd-snapshots", got)
+	}
+	if _, ok := data.ExtraFieldInfo["etcd-s3-snapshots"]; ok {
+		t.Error("etcd-s3-snapshots set by an abandoned detector")
+	}
+	ran := strings.Split(data.ExtraFieldInfo["collectors-run"].(string), ",")
+	if slices.Contains(ran, "etcd-snapshots") {
+		t.Error("collectors-run contains the abandoned etcd-snapshots")
+	}
+	// Collectors after the hung one still run
+	for _, name := range []string{"etcd-tls", "ip-stack"} {
+		if !slices.Contains(ran, name) {
+			t.Errorf("collectors-run = %v, want it to contain %s", ran, name)
+		}
+	}
+}
+
+func TestCollect_RancherClusterRoleFromManagementAPI(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "management.cattle.io/v3", APIResources: []metav1.APIResource{{Name: "clusters"}}},
+	}
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["rancher-cluster-role"] != "local" {
+		t.Errorf("rancher-cluster-role = %v, want local", data.ExtraFieldInfo["rancher-cluster-role"])
+	}
+}
+
+func TestCollect_NotRancherManagedOmitsClusterRole(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if role, ok := data.ExtraFieldInfo["rancher-cluster-role"]; ok {
+		t.Errorf("rancher-cluster-role = %v, want absent", role)
+	}
+}
+
+func TestCollect_ClusterIdentity(t *testing.T) {
+	objects := func() []runtime.Object {
+		return []runtime.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "kube-system-uid"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Image: "rancher/rancher-agent:v2.8.0",
+								Env:   []corev1.EnvVar{{Name: "CATTLE_INSTALL_UUID", Value: "install-uuid"}},
+							}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{"rancher managed", "recommended", objects(), "kube-system-uid/install-uuid"},
+		{"minimal mode omits install UUID", "minimal", objects(), "kube-system-uid"},
+		{"not rancher managed", "recommended", objects()[:1], "kube-system-uid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var identities []string
+			for range 2 {
+				data, err := Collect(context.Background(), fake.NewClientset(tt.objects...), tt.mode)
+				if err != nil {
+					t.Fatalf("Collect() error = %v", err)
+				}
+				identities = append(identities, data.ExtraTagInfo["clusterIdentity"])
+			}
+
+			if identities[0] != tt.expected {
+				t.Errorf("clusterIdentity = %q, want %q", identities[0], tt.expected)
+			}
+			if identities[0] != identities[1] {
+				t.Errorf("clusterIdentity not stable: %q != %q", identities[0], identities[1])
+			}
+		})
+	}
+}
+
+func TestCollect_MissingKubeSystem(t *testing.T) {
+	clientset := fake.NewClientset()
+
+	_, err := Collect(context.Background(), clientset, "recommended")
+	if !errors.Is(err, ErrMissingKubeSystem) {
+		t.Errorf("Collect() error = %v, want ErrMissingKubeSystem", err)
+	}
+}
+
+func TestCollect_ListErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		verb     string
+		resource string
+		apiErr   error
+		wantErr  error
+	}{
+		{"nodes", "list", "nodes", apierrors.NewServiceUnavailable("etcd leader changed"), ErrNodeListFailed},
+		{"daemonsets", "list", "daemonsets", apierrors.NewServiceUnavailable("etcd leader changed"), ErrWorkloadListFailed},
+		{"deployments", "list", "deployments", apierrors.NewServiceUnavailable("etcd leader changed"), ErrWorkloadListFailed},
+		{"kube-system forbidden", "get", "namespaces", apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "kube-system", errors.New("denied")), ErrMissingKubeSystem},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			)
+			clientset.PrependReactor(tt.verb, tt.resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, tt.apiErr
+			})
+
+			_, err := Collect(context.Background(), clientset, "recommended")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Collect() error = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, tt.apiErr) {
+				t.Errorf("Collect() error = %v, want the API error to stay wrapped", err)
+			}
+		})
+	}
+}
+
+func TestCollect_RBACDenied(t *testing.T) {
+	forbidden := func(resource string) k8stesting.ReactionFunc {
+		return func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: resource}, "", errors.New("denied"))
+		}
+	}
+
+	tests := []struct {
+		name        string
+		resources   []string
+		checkField  string
+		wantField   any
+		wantDenials int
+	}{
+		{"nothing denied", nil, "serverNodeCount", 1, 0},
+		{"nodes denied", []string{"nodes"}, "serverNodeCount", -1, 1},
+		// Listed by both the cluster-admin and default-sa collectors
+		{"clusterrolebindings denied", []string{"clusterrolebindings"}, "cluster-admin-subject-count", -1, 2},
+		{"rolebindings denied", []string{"rolebindings"}, "default-sa-overprivileged-count", -1, 1},
+		{"nodes and priorityclasses denied", []string{"nodes", "priorityclasses"}, "priorityclass-count", -1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+					Name:   "server-1",
+					Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"},
+				}},
+			)
+			for _, resource := range tt.resources {
+				clientset.PrependReactor("list", resource, forbidden(resource))
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v, want forbidden calls to be tolerated", err)
+			}
+
+			if data.ExtraFieldInfo[tt.checkField] != tt.wantField {
+				t.Errorf("%s = %v, want %v", tt.checkField, data.ExtraFieldInfo[tt.checkField], tt.wantField)
+			}
+			if data.ExtraFieldInfo["rbac-denied-count"] != tt.wantDenials {
+				t.Errorf("rbac-denied-count = %v, want %d", data.ExtraFieldInfo["rbac-denied-count"], tt.wantDenials)
+			}
+		})
+	}
+}
+
+func TestCollect_DegradedSystemWorkloads(t *testing.T) {
+	coredns := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "rke2-coredns-rke2-coredns", Namespace: "kube-system"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+	}
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		coredns,
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-metrics-server", Namespace: "kube-system"},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-snapshot-controller", Namespace: "kube-system"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(0))},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-canal", Namespace: "kube-system"},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, NumberReady: 2},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, NumberReady: 3},
+		},
+		// Only kube-system is considered
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		expected any
+	}{
+		{name: "partially ready coredns", mode: "recommended", expected: "rke2-canal,rke2-coredns-rke2-coredns"},
+		{name: "minimal mode", mode: "minimal", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Collect(context.Background(), fake.NewClientset(objects...), tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if got := data.ExtraFieldInfo["degraded-system-workloads"]; got != tt.expected {
+				t.Errorf("degraded-system-workloads = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_CollectorsRun(t *testing.T) {
+	forbidden := func(resource string) k8stesting.ReactionFunc {
+		return func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: resource}, "", errors.New("denied"))
+		}
+	}
+	all := append(slices.Clone(Collectors), MandatoryCollectors...)
+	without := func(names ...string) string {
+		ran := slices.DeleteFunc(slices.Clone(all), func(name string) bool { return slices.Contains(names, name) })
+		slices.Sort(ran)
+		return strings.Join(ran, ",")
+	}
+
+	tests := []struct {
+		name      string
+		opts      []CollectOption
+		forbidden []string
+		expected  string
+	}{
+		{"all collectors", nil, nil, without()},
+		{"included collectors", []CollectOption{WithCollectors("cni", "pdb")}, nil, "cni,nodes,pdb,uuid,version"},
+		{"excluded collector", []CollectOption{WithoutCollectors("pods")}, nil, without("pods")},
+		{"denied list fails its collector", nil, []string{"poddisruptionbudgets"}, without("pdb")},
+		{"denied node list", nil, []string{"nodes"}, without("nodes")},
+		{"denied pod scan fails pods and cidrs", nil, []string{"pods"}, without("pods", "cidrs")},
+		{"denied kube-system daemonsets fail their readers", nil, []string{"daemonsets"}, without("cni", "ingress", "pull-policy", "fips", "gpu-operator", "virtualization", "workload-identity", "data-dir", "system-workloads")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+					Spec:       corev1.ServiceSpec{IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol}},
+				},
+			)
+			for _, resource := range tt.forbidden {
+				clientset.PrependReactor("list", resource, forbidden(resource))
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended", tt.opts...)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if got := data.ExtraFieldInfo["collectors-run"]; got != tt.expected {
+				t.Errorf("collectors-run = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_Instrumentation(t *testing.T) {
+	collect := func(t *testing.T, opts ...CollectOption) map[string]interface{} {
+		t.Helper()
+		clientset := fake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}})
+		counter := &atomic.Int64{}
+		// Stand in for the counting transport main wraps around the real clients
+		clientset.PrependReactor("*", "*", func(k8stesting.Action) (bool, runtime.Object, error) {
+			counter.Add(1)
+			return false, nil, nil
+		})
+		data, err := Collect(context.Background(), clientset, "recommended", append(opts, WithAPICallCounter(counter))...)
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		return data.ExtraFieldInfo
+	}
+
+	few := collect(t, WithCollectors("pdb"))
+	all := collect(t)
+
+	for _, fields := range []map[string]interface{}{few, all} {
+		if duration, ok := fields["collect-duration-ms"].(int64); !ok || duration < 0 {
+			t.Errorf("collect-duration-ms = %v, want a non-negative int64", fields["collect-duration-ms"])
+		}
+	}
+	fewCalls, _ := few["api-calls"].(int64)
+	allCalls, _ := all["api-calls"].(int64)
+	if fewCalls <= 0 || allCalls <= fewCalls {
+		t.Errorf("api-calls = %d with one collector and %d with all, want positive and increasing", fewCalls, allCalls)
+	}
+
+	t.Run("without counter", func(t *testing.T) {
+		clientset := fake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}})
+		data, err := Collect(context.Background(), clientset, "recommended", WithCollectors("pdb"))
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		if data.ExtraFieldInfo["api-calls"] != int64(-1) {
+			t.Errorf("api-calls = %v, want -1", data.ExtraFieldInfo["api-calls"])
+		}
+	})
+}
+
+func TestCollect_SchemaVersion(t *testing.T) {
+	clientset := fake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}})
+
+	data, err := Collect(context.Background(), clientset, "minimal", WithCollectors())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if data.SchemaVersion != SchemaVersion || SchemaVersion < 1 {
+		t.Errorf("SchemaVersion = %d, want %d", data.SchemaVersion, SchemaVersion)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := fmt.Sprintf(`"schemaVersion":%d`, SchemaVersion); !strings.Contains(string(raw), want) {
+		t.Errorf("payload %s missing %s", raw, want)
+	}
+}
+
+func TestSend_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected Content-Type application/json, got %s", r.Header.Get("Content-Type"))
+		}
+		var received Data
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		if received.SchemaVersion != SchemaVersion {
+			t.Errorf("schemaVersion = %d, want %d", received.SchemaVersion, SchemaVersion)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		resp := Response{
+			Versions: []Version{
+				{Name: "v1.30.1", ReleaseDate: "2024-01-01"},
+			},
+			RequestIntervalInMinutes: 480,
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	data := &Data{
+		SchemaVersion:  SchemaVersion,
+		AppVersion:     "v1.30.0",
+		ExtraTagInfo:   map[string]string{"clusteruuid": "test"},
+		ExtraFieldInfo: map[string]interface{}{"serverNodeCount": 1},
+	}
+
+	result, err := Send(context.Background(), data, server.URL)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	resp := result.Response
+	if resp == nil {
+		t.Fatal("Send() returned nil response")
+	}
+	if len(resp.Versions) != 1 {
+		t.Errorf("expected 1 version, got %d", len(resp.Versions))
+	}
+	if resp.Versions[0].Name != "v1.30.1" {
+		t.Errorf("version name = %q, want v1.30.1", resp.Versions[0].Name)
+	}
+	if resp.Acknowledged != nil {
+		t.Errorf("Acknowledged = %+v, want nil for endpoints that don't echo it", resp.Acknowledged)
+	}
+}
+
+func TestSend_RetryOnError(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempt := attempts.Add(1)
+		if attempt < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer server.Close()
+
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	_, err := Send(context.Background(), data, server.URL)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts.Load())
+	}
+}
+
+func TestSend_RetryableStatus(t *testing.T) {
+	tests := []struct {
+		status       int
+		wantAttempts int32
+	}{
+		{http.StatusBadRequest, 1},
+		{http.StatusServiceUnavailable, maxRetries},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("payload invalid: missing appVersion"))
+			}))
+			defer server.Close()
+
+			data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+			result, err := Send(context.Background(), data, server.URL)
+			if err == nil {
+				t.Fatal("Send() error = nil, want error")
+			}
+			if attempts.Load() != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts.Load(), tt.wantAttempts)
+			}
+			if result.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, tt.status)
+			}
+			if tt.wantAttempts == 1 && !strings.Contains(err.Error(), "payload invalid: missing appVersion") {
+				t.Errorf("error %q does not include the response body", err)
+			}
+		})
+	}
+}
+
+func TestSend_Timeouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		delay        time.Duration
+		opts         []SendOption
+		wantAttempts int32
+		wantErr      string
+	}{
+		{
+			// The first backoff alone outlasts the deadline
+			name:         "overall deadline cuts retries short",
+			opts:         []SendOption{WithOverallTimeout(500 * time.Millisecond)},
+			wantAttempts: 1,
+			wantErr:      "send cancelled: context deadline exceeded",
+		},
+		{
+			name:         "attempt timeout",
+			delay:        time.Second,
+			opts:         []SendOption{WithAttemptTimeout(100 * time.Millisecond), WithOverallTimeout(2500 * time.Millisecond)},
+			wantAttempts: 2,
+			wantErr:      "context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(tt.delay):
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+			start := time.Now()
+			_, err := Send(context.Background(), data, server.URL, tt.opts...)
+			if err == nil {
+				t.Fatal("Send() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("Send() took %v, want the deadline to stop it", elapsed)
+			}
+		})
+	}
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	tests := map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusNotFound:            false,
+		http.StatusUnprocessableEntity: false,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+	}
+	for status, want := range tests {
+		if got := isRetryableStatus(status); got != want {
+			t.Errorf("isRetryableStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestSend_IdempotencyKeyStableAcrossRetries(t *testing.T) {
+	var attempts atomic.Int32
+	keys := make(chan string, maxRetries)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get(IdempotencyKeyHeader)
+		if attempts.Add(1) < maxRetries {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer server.Close()
+
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	if _, err := Send(context.Background(), data, server.URL, WithIdempotencyKey("run-key")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	close(keys)
+
+	count := 0
+	for key := range keys {
+		count++
+		if key != "run-key" {
+			t.Errorf("attempt %d %s = %q, want %q", count, IdempotencyKeyHeader, key, "run-key")
+		}
+	}
+	if count != maxRetries {
+		t.Errorf("expected %d attempts, got %d", maxRetries, count)
+	}
+}
+
+func TestSend_Transformers(t *testing.T) {
+	received := make(chan Data, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data Data
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- data
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer server.Close()
+
+	data := &Data{
+		AppVersion:     "test",
+		ExtraTagInfo:   map[string]string{"clusteruuid": "test", "clusterIdentity": "test/install"},
+		ExtraFieldInfo: map[string]interface{}{},
+	}
+	var order []string
+	enrich := func(d *Data) {
+		order = append(order, "enrich")
+		d.ExtraFieldInfo["site"] = "eu-1"
+	}
+	// Sees the field set by the previous transformer
+	suffix := func(d *Data) {
+		order = append(order, "suffix")
+		d.ExtraFieldInfo["site"] = d.ExtraFieldInfo["site"].(string) + "-dc"
+	}
+
+	if _, err := Send(context.Background(), data, server.URL, WithTransformers(enrich, suffix), WithTransformers(RedactUUID)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if !slices.Equal(order, []string{"enrich", "suffix"}) {
+		t.Errorf("transformer order = %v, want [enrich suffix]", order)
+	}
+	got := <-received
+	if got.ExtraFieldInfo["site"] != "eu-1-dc" {
+		t.Errorf("sent site = %v, want eu-1-dc", got.ExtraFieldInfo["site"])
+	}
+	if _, ok := got.ExtraTagInfo["clusteruuid"]; ok {
+		t.Error("sent payload still has clusteruuid")
+	}
+	if got.ExtraFieldInfo["anonymous"] != true {
+		t.Errorf("sent anonymous = %v, want true", got.ExtraFieldInfo["anonymous"])
+	}
+	if data.ExtraFieldInfo["site"] != "eu-1-dc" {
+		t.Errorf("data site = %v, want transformers to modify the caller's Data", data.ExtraFieldInfo["site"])
+	}
+}
+
+func TestSend_AllRetriesFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	_, err := Send(context.Background(), data, server.URL)
+	if err == nil {
+		t.Error("Send() expected error after all retries fail")
+	}
+}
+
+func TestSend_Result(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		wantErr        bool
+		wantSuccess    bool
+		wantAttempts   int
+		wantStatusCode int
+		wantResponse   bool
+	}{
+		{"success", http.StatusOK, false, true, 1, http.StatusOK, true},
+		{"all fail", http.StatusServiceUnavailable, true, false, maxRetries, http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_ = json.NewEncoder(w).Encode(Response{RequestIntervalInMinutes: 480})
+			}))
+			defer server.Close()
+
+			data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+			result, err := Send(context.Background(), data, server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result == nil {
+				t.Fatal("Send() returned nil result")
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v", result.Success, tt.wantSuccess)
+			}
+			if result.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", result.Attempts, tt.wantAttempts)
+			}
+			if result.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, tt.wantStatusCode)
+			}
+			if result.Endpoint != server.URL {
+				t.Errorf("Endpoint = %q, want %q", result.Endpoint, server.URL)
+			}
+			if (result.Response != nil) != tt.wantResponse {
+				t.Errorf("Response = %v, want present %v", result.Response, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestSend_ContextCancelledDuringRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := Send(ctx, data, server.URL)
+	if err == nil {
+		t.Fatal("Send() expected error when context is cancelled")
+	}
+	if result.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", result.Attempts)
+	}
+}
+
+func TestSend_MalformedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	result, err := Send(context.Background(), data, server.URL)
+	if err != nil {
+		t.Errorf("Send() error = %v, want nil (graceful degradation)", err)
+	}
+	if result.Response != nil {
+		t.Errorf("Send() response = %v, want nil", result.Response)
+	}
+}
+
+func TestSend_GzipResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         func(t *testing.T) []byte
+		wantResponse bool
+	}{
+		{
+			name: "gzip-encoded JSON",
+			body: func(t *testing.T) []byte {
+				var buf bytes.Buffer
+				zw := gzip.NewWriter(&buf)
+				if err := json.NewEncoder(zw).Encode(Response{Versions: []Version{{Name: "v1.32.3+rke2r1"}}}); err != nil {
+					t.Fatalf("encode response: %v", err)
+				}
+				if err := zw.Close(); err != nil {
+					t.Fatalf("close gzip writer: %v", err)
+				}
+				return buf.Bytes()
+			},
+			wantResponse: true,
+		},
+		{
+			name:         "corrupt gzip",
+			body:         func(*testing.T) []byte { return []byte("not gzip") },
+			wantResponse: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+					t.Errorf("Accept-Encoding = %q, want gzip", got)
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+			result, err := Send(context.Background(), data, server.URL)
+			if err != nil {
+				t.Fatalf("Send() error = %v, want nil", err)
+			}
+			if (result.Response != nil) != tt.wantResponse {
+				t.Fatalf("Send() response = %v, want response %v", result.Response, tt.wantResponse)
+			}
+			if tt.wantResponse && (len(result.Response.Versions) != 1 || result.Response.Versions[0].Name != "v1.32.3+rke2r1") {
+				t.Errorf("Send() versions = %v, want v1.32.3+rke2r1", result.Response.Versions)
+			}
+		})
+	}
+}
+
+func TestCollect_GPUOperatorDetection(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "gpu-operator"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "nvidia-device-plugin-daemonset", Namespace: "gpu-operator"},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Image: "nvcr.io/nvidia/k8s-device-plugin:v0.14.0"}},
+					},
+				},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["gpu-operator"] != "nvidia-gpu-operator" {
+		t.Errorf("gpu-operator = %v, want nvidia-gpu-operator", data.ExtraFieldInfo["gpu-operator"])
+	}
+}
+
+func TestCollect_IngressAsDaemonSet(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Image: "rancher/nginx-ingress-controller:v1.9.0"}},
+					},
+				},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["ingress-controller"] != "rke2-ingress-nginx" {
+		t.Errorf("ingress-controller = %v, want rke2-ingress-nginx", data.ExtraFieldInfo["ingress-controller"])
+	}
+}
+
+func TestCollect_IPStackFromService(t *testing.T) {
+	tests := []struct {
+		name       string
+		ipFamilies []corev1.IPFamily
+		expected   string
+	}{
+		{"ipv4-only", []corev1.IPFamily{corev1.IPv4Protocol}, "ipv4-only"},
+		{"ipv6-only", []corev1.IPFamily{corev1.IPv6Protocol}, "ipv6-only"},
+		{"dual-stack", []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}, "dual-stack"},
+		{"dual-stack-v6-first", []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}, "dual-stack"},
+		{"empty-families", []corev1.IPFamily{}, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+					Status: corev1.NodeStatus{
+						NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
+					},
+				},
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+					Spec:       corev1.ServiceSpec{IPFamilies: tt.ipFamilies},
+				},
+			)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["ip-stack"] != tt.expected {
+				t.Errorf("ip-stack = %v, want %v", data.ExtraFieldInfo["ip-stack"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_IPStackNoService(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["ip-stack"] != "unknown" {
+		t.Errorf("ip-stack = %v, want unknown", data.ExtraFieldInfo["ip-stack"])
+	}
+}
+
+func TestCollect_APIServerExposure(t *testing.T) {
+	kubernetesSvc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt32(6443)}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{
+			name:     "cluster ip only",
+			objects:  []runtime.Object{kubernetesSvc},
+			expected: "clusterip",
+		},
+		{
+			name: "nodeport apiserver proxy",
+			objects: []runtime.Object{kubernetesSvc, &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "kube-apiserver-proxy", Namespace: "kube-system"},
+				Spec: corev1.ServiceSpec{
+					Type:     corev1.ServiceTypeNodePort,
+					Selector: map[string]string{"component": "kube-apiserver"},
+					Ports:    []corev1.ServicePort{{Port: 6443, NodePort: 30443}},
+				},
+			}},
+			expected: "nodeport",
+		},
+		{
+			name: "loadbalancer with manual endpoints",
+			objects: []runtime.Object{kubernetesSvc, &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "infra"},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Port: 6443}},
+				},
+			}},
+			expected: "loadbalancer",
+		},
+		{
+			name: "aggregated apiserver on 6443",
+			objects: []runtime.Object{kubernetesSvc, &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "prometheus-adapter", Namespace: "monitoring"},
+				Spec: corev1.ServiceSpec{
+					Type:     corev1.ServiceTypeNodePort,
+					Selector: map[string]string{"app": "prometheus-adapter"},
+					Ports:    []corev1.ServicePort{{Port: 443, TargetPort: intstr.FromInt32(6443)}},
+				},
+			}},
+			expected: "clusterip",
+		},
+		{
+			name:     "no services",
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["apiserver-exposure"] != tt.expected {
+				t.Errorf("apiserver-exposure = %v, want %v", data.ExtraFieldInfo["apiserver-exposure"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_NodeIPFamily(t *testing.T) {
+	node := func(name string, ips ...string) *corev1.Node {
+		// External addresses don't count toward the node IP family
+		return testNode(name, nodeAddresses(corev1.NodeInternalIP, ips...), nodeAddresses(corev1.NodeExternalIP, "203.0.113.10"))
+	}
+
+	tests := []struct {
+		name             string
+		ipFamilies       []corev1.IPFamily
+		nodes            []runtime.Object
+		expectedFamily   string
+		expectedMismatch bool
+	}{
+		{
+			name:             "ipv6 nodes with dual-stack service",
+			ipFamilies:       []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+			nodes:            []runtime.Object{node("node-1", "fd00::1"), node("node-2", "fd00::2")},
+			expectedFamily:   "ipv6",
+			expectedMismatch: true,
+		},
+		{
+			name:             "dual-stack nodes and service",
+			ipFamilies:       []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol},
+			nodes:            []runtime.Object{node("node-1", "10.0.0.1", "fd00::1")},
+			expectedFamily:   "dual",
+			expectedMismatch: false,
+		},
+		{
+			name:             "ipv4 nodes and service",
+			ipFamilies:       []corev1.IPFamily{corev1.IPv4Protocol},
+			nodes:            []runtime.Object{node("node-1", "10.0.0.1")},
+			expectedFamily:   "ipv4",
+			expectedMismatch: false,
+		},
+		{
+			name:             "no internal IPs",
+			ipFamilies:       []corev1.IPFamily{corev1.IPv4Protocol},
+			nodes:            []runtime.Object{node("node-1")},
+			expectedFamily:   "unknown",
+			expectedMismatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubernetes", Namespace: "default"},
+					Spec:       corev1.ServiceSpec{IPFamilies: tt.ipFamilies},
+				},
+			}, tt.nodes...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["node-ip-family"] != tt.expectedFamily {
+				t.Errorf("node-ip-family = %v, want %v", data.ExtraFieldInfo["node-ip-family"], tt.expectedFamily)
+			}
+			if data.ExtraFieldInfo["ip-stack-mismatch"] != tt.expectedMismatch {
+				t.Errorf("ip-stack-mismatch = %v, want %v", data.ExtraFieldInfo["ip-stack-mismatch"], tt.expectedMismatch)
+			}
+		})
+	}
+}
+
+func TestCollect_MinimalMode(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "server-1",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent-1"},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("8"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+				},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Image: "rancher/rancher-agent:v2.8.0",
+							Env: []corev1.EnvVar{
+								{Name: "CATTLE_INSTALL_UUID", Value: "test-uuid"},
+							},
+						}},
+					},
+				},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "minimal")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	// Mode field should be set
+	if data.ExtraFieldInfo["mode"] != "minimal" {
+		t.Errorf("mode = %q, want %q", data.ExtraFieldInfo["mode"], "minimal")
+	}
+
+	// Node counts should be -1 in minimal mode
+	if data.ExtraFieldInfo["serverNodeCount"] != -1 {
+		t.Errorf("serverNodeCount = %v, want -1", data.ExtraFieldInfo["serverNodeCount"])
+	}
+	if data.ExtraFieldInfo["agentNodeCount"] != -1 {
+		t.Errorf("agentNodeCount = %v, want -1", data.ExtraFieldInfo["agentNodeCount"])
+	}
+	if data.ExtraFieldInfo["gpuNodeCount"] != -1 {
+		t.Errorf("gpuNodeCount = %v, want -1", data.ExtraFieldInfo["gpuNodeCount"])
+	}
+
+	// CPU/memory should be -1 in minimal mode
+	if data.ExtraFieldInfo["serverCPU"] != int64(-1) {
+		t.Errorf("serverCPU = %v, want -1", data.ExtraFieldInfo["serverCPU"])
+	}
+	if data.ExtraFieldInfo["agentCPU"] != int64(-1) {
+		t.Errorf("agentCPU = %v, want -1", data.ExtraFieldInfo["agentCPU"])
+	}
+	if data.ExtraFieldInfo["serverMemory"] != int64(-1) {
+		t.Errorf("serverMemory = %v, want -1", data.ExtraFieldInfo["serverMemory"])
+	}
+	if data.ExtraFieldInfo["agentMemory"] != int64(-1) {
+		t.Errorf("agentMemory = %v, want -1", data.ExtraFieldInfo["agentMemory"])
+	}
+
+	// Rancher-managed should still be present
+	if data.ExtraFieldInfo["rancher-managed"] != true {
+		t.Errorf("rancher-managed = %v, want true", data.ExtraFieldInfo["rancher-managed"])
+	}
+
+	// Rancher version and UUID should be empty strings in minimal mode
+	if data.ExtraFieldInfo["rancher-version"] != "" {
+		t.Errorf("rancher-version = %v, want empty string", data.ExtraFieldInfo["rancher-version"])
+	}
+	if data.ExtraFieldInfo["rancher-install-uuid"] != "" {
+		t.Errorf("rancher-install-uuid = %v, want empty string", data.ExtraFieldInfo["rancher-install-uuid"])
+	}
+
+	// OS info should still be present
+	if data.ExtraFieldInfo["os"] != "test" {
+		t.Errorf("os = %v, want test", data.ExtraFieldInfo["os"])
+	}
+	if data.ExtraFieldInfo["arch"] != "amd64" {
+		t.Errorf("arch = %v, want amd64", data.ExtraFieldInfo["arch"])
+	}
+}
+
+func TestCollect_RecommendedModeIncludesRancherDetails(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "server-1",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Image: "rancher/rancher-agent:v2.8.0",
+							Env: []corev1.EnvVar{
+								{Name: "CATTLE_INSTALL_UUID", Value: "test-uuid"},
+							},
+						}},
+					},
+				},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	// Mode field should be set
+	if data.ExtraFieldInfo["mode"] != "recommended" {
+		t.Errorf("mode = %q, want %q", data.ExtraFieldInfo["mode"], "recommended")
+	}
+
+	// Node counts should have actual values
+	if data.ExtraFieldInfo["serverNodeCount"] != 1 {
+		t.Errorf("serverNodeCount = %v, want 1", data.ExtraFieldInfo["serverNodeCount"])
+	}
+
+	// CPU/memory should have actual values
+	serverCPU, ok := data.ExtraFieldInfo["serverCPU"].(int64)
+	if !ok || serverCPU == 0 {
+		t.Errorf("serverCPU = %v, want non-zero", data.ExtraFieldInfo["serverCPU"])
+	}
+
+	// Rancher details should be present in recommended mode
+	if data.ExtraFieldInfo["rancher-version"] != "v2.8.0" {
+		t.Errorf("rancher-version = %v, want v2.8.0", data.ExtraFieldInfo["rancher-version"])
+	}
+	if data.ExtraFieldInfo["rancher-install-uuid"] != "test-uuid" {
+		t.Errorf("rancher-install-uuid = %v, want test-uuid", data.ExtraFieldInfo["rancher-install-uuid"])
+	}
+}
+
+func TestCollect_WorkloadsListedOncePerNamespace(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-canal", Namespace: "kube-system"},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "rancher/hardened-calico:v3.26.0"}}},
+				},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "rancher/nginx-ingress-controller:v1.9.0"}}},
+				},
+			},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "nvidia-device-plugin-daemonset", Namespace: "gpu-operator"},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "nvcr.io/nvidia/k8s-device-plugin:v0.14.0"}}},
+				},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"cni-plugin":           "canal",
+		"cni-version":          "v3.26.0",
+		"ingress-controller":   "rke2-ingress-nginx",
+		"ingress-version":      "v1.9.0",
+		"gpu-operator":         "nvidia-gpu-operator",
+		"gpu-operator-version": "v0.14.0",
+	}
+	for key, want := range expected {
+		if data.ExtraFieldInfo[key] != want {
+			t.Errorf("%s = %v, want %v", key, data.ExtraFieldInfo[key], want)
+		}
+	}
+
+	lists := make(map[string]int)
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() != "list" {
+			continue
+		}
+		resource := action.GetResource().Resource
+		if resource != "daemonsets" && resource != "deployments" {
+			continue
+		}
+		lists[resource+"/"+action.GetNamespace()]++
+	}
+	for key, count := range lists {
+		if count != 1 {
+			t.Errorf("%s listed %d times, want 1", key, count)
+		}
+	}
+}
+
+func BenchmarkCollect(b *testing.B) {
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+	}
+	for i := 0; i < 50; i++ {
+		objects = append(objects, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+		})
+		objects = append(objects, &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("ds-%d", i), Namespace: "kube-system"},
+		})
+		objects = append(objects, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("deploy-%d", i), Namespace: "kube-system"},
+		})
+	}
+	clientset := fake.NewClientset(objects...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Collect(context.Background(), clientset, "recommended"); err != nil {
+			b.Fatalf("Collect() error = %v", err)
+		}
+	}
+}
+
+func TestCollect_PodDisruptionBudgets(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             string
+		pdbs             []runtime.Object
+		expectedCount    int
+		expectedCoverage bool
+	}{
+		{
+			name: "coredns pdb",
+			mode: "recommended",
+			pdbs: []runtime.Object{
+				&policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "rke2-coredns-rke2-coredns", Namespace: "kube-system"},
+				},
+				&policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					},
+				},
+			},
+			expectedCount:    2,
+			expectedCoverage: true,
+		},
+		{
+			name: "selector match",
+			mode: "recommended",
+			pdbs: []runtime.Object{
+				&policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "kube-system"},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "coredns"}},
+					},
+				},
+			},
+			expectedCount:    1,
+			expectedCoverage: true,
+		},
+		{
+			name: "application pdb only",
+			mode: "recommended",
+			pdbs: []runtime.Object{
+				&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			},
+			expectedCount:    1,
+			expectedCoverage: false,
+		},
+		{
+			name:             "no pdbs",
+			mode:             "recommended",
+			expectedCount:    0,
+			expectedCoverage: false,
+		},
+		{
+			name: "minimal mode",
+			mode: "minimal",
+			pdbs: []runtime.Object{
+				&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"}},
+			},
+			expectedCount:    -1,
+			expectedCoverage: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+					Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"}},
+				},
+			}, tt.pdbs...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["pdb-count"] != tt.expectedCount {
+				t.Errorf("pdb-count = %v, want %v", data.ExtraFieldInfo["pdb-count"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["system-pdb-coverage"] != tt.expectedCoverage {
+				t.Errorf("system-pdb-coverage = %v, want %v", data.ExtraFieldInfo["system-pdb-coverage"], tt.expectedCoverage)
+			}
+		})
+	}
+}
+
+func TestCollect_IngressTLS(t *testing.T) {
+	ingresses := []runtime.Object{
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "secure", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"app.example.com"}, SecretName: "app-tls"}},
+			},
+		},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "plaintext", Namespace: "web"}},
+	}
+
+	tests := []struct {
+		name            string
+		mode            string
+		ingresses       []runtime.Object
+		expectedCount   int
+		expectedWithTLS int
+	}{
+		{"tls and plaintext", "recommended", ingresses, 2, 1},
+		{"no ingresses", "recommended", nil, 0, 0},
+		{"minimal mode", "minimal", ingresses, -1, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.ingresses...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["ingress-count"] != tt.expectedCount {
+				t.Errorf("ingress-count = %v, want %v", data.ExtraFieldInfo["ingress-count"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["ingress-with-tls"] != tt.expectedWithTLS {
+				t.Errorf("ingress-with-tls = %v, want %v", data.ExtraFieldInfo["ingress-with-tls"], tt.expectedWithTLS)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_InsecureSkipVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []SendOption
+		expected bool
+	}{
+		{"default", nil, false},
+		{"disabled", []SendOption{WithInsecureSkipVerify(false)}, false},
+		{"enabled", []SendOption{WithInsecureSkipVerify(true)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &sendConfig{}
+			for _, opt := range tt.opts {
+				opt(cfg)
+			}
+			transport, ok := httpClient(cfg).Transport.(*http.Transport)
+			if !ok {
+				t.Fatal("httpClient() transport is not *http.Transport")
+			}
+			got := transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify
+			if got != tt.expected {
+				t.Errorf("InsecureSkipVerify = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSend_ReusesConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{Versions: []Version{{Name: "v1.32.2+rke2r1"}}})
+	}))
+	defer server.Close()
+
+	var conns, reused atomic.Int32
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conns.Add(1)
+			if info.Reused {
+				reused.Add(1)
+			}
+		},
+	})
+
+	data := &Data{ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+	for range 2 {
+		if _, err := Send(ctx, data, server.URL); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	if conns.Load() != 2 || reused.Load() != 1 {
+		t.Errorf("got %d connections with %d reused, want the second send to reuse the first connection", conns.Load(), reused.Load())
+	}
+}
+
+func TestSend_UnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "relay.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	var gotPath string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	data := &Data{ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+	result, err := Send(context.Background(), data, "unix://"+socketPath)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !result.Success {
+		t.Error("Send() Success = false, want true")
+	}
+	if gotPath != "/v1/checkupgrade" {
+		t.Errorf("request path = %q, want /v1/checkupgrade", gotPath)
+	}
+}
+
+func TestSend_UnixSocketWithoutPath(t *testing.T) {
+	data := &Data{ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+	result, err := Send(context.Background(), data, "unix://")
+	if err == nil {
+		t.Fatal("Send() expected error for unix endpoint without socket path")
+	}
+	if result.Attempts != 0 {
+		t.Errorf("Attempts = %d, want 0", result.Attempts)
+	}
+}
+
+func TestSend_InsecureSelfSignedServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer server.Close()
+
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	if _, err := Send(context.Background(), data, server.URL, WithInsecureSkipVerify(true)); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+}
+
+func TestCollect_ClusterAdminSubjects(t *testing.T) {
+	builtin := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: "system:masters"}},
+	}
+	extraSA := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci-admin"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "ci", Namespace: "ci"}},
+	}
+	duplicateSA := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci-admin-2"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "ci", Namespace: "ci"}},
+	}
+	viewer := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "viewer"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"}},
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		bindings []runtime.Object
+		expected int
+	}{
+		{"builtin only", "recommended", []runtime.Object{builtin}, 0},
+		{"extra service account", "recommended", []runtime.Object{builtin, extraSA, viewer}, 1},
+		{"duplicate subject", "recommended", []runtime.Object{builtin, extraSA, duplicateSA}, 1},
+		{"minimal mode", "minimal", []runtime.Object{builtin, extraSA}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.bindings...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["cluster-admin-subject-count"] != tt.expected {
+				t.Errorf("cluster-admin-subject-count = %v, want %v", data.ExtraFieldInfo["cluster-admin-subject-count"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_DefaultSAOverprivileged(t *testing.T) {
+	edit := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "edit"},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"pods", "secrets"}, Verbs: []string{"get", "list", "create", "update", "delete"}},
+		},
+	}
+	view := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "view"},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list", "watch"}},
+		},
+	}
+	bindEdit := func(namespace string, subject rbacv1.Subject) *rbacv1.RoleBinding {
+		return &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-edit", Namespace: namespace},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+			Subjects:   []rbacv1.Subject{subject},
+		}
+	}
+	defaultSA := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "default"}
+
+	tests := []struct {
+		name     string
+		mode     string
+		objects  []runtime.Object
+		expected int
+	}{
+		{
+			name:     "default bound to edit",
+			mode:     "recommended",
+			objects:  []runtime.Object{edit, bindEdit("team-a", defaultSA)},
+			expected: 1,
+		},
+		{
+			name: "default bound to edit as user",
+			mode: "recommended",
+			objects: []runtime.Object{edit, &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-b-default-edit"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "system:serviceaccount:team-b:default"}},
+			}},
+			expected: 1,
+		},
+		{
+			name: "read-only and non-default bindings",
+			mode: "recommended",
+			objects: []runtime.Object{edit, view,
+				&rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "default-view", Namespace: "team-a"},
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+					Subjects:   []rbacv1.Subject{defaultSA},
+				},
+				bindEdit("team-a", rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: "deployer"}),
+			},
+			expected: 0,
+		},
+		{
+			name:     "system namespace excluded",
+			mode:     "recommended",
+			objects:  []runtime.Object{edit, bindEdit("kube-system", defaultSA)},
+			expected: 0,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			objects:  []runtime.Object{edit, bindEdit("team-a", defaultSA)},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["default-sa-overprivileged-count"] != tt.expected {
+				t.Errorf("default-sa-overprivileged-count = %v, want %v", data.ExtraFieldInfo["default-sa-overprivileged-count"], tt.expected)
+			}
+		})
+	}
+}
+func TestCollect_KubernetesDashboard(t *testing.T) {
+	dashboard := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-dashboard", Namespace: "kubernetes-dashboard"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "kubernetesui/dashboard:v2.7.0"}}},
+			},
+		},
+	}
+	scraper := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "dashboard-metrics-scraper", Namespace: "kubernetes-dashboard"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "kubernetesui/metrics-scraper:v1.0.8"}}},
+			},
+		},
+	}
+	dashboardNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kubernetes-dashboard"}}
+
+	tests := []struct {
+		name              string
+		objects           []runtime.Object
+		failing           string
+		expectedInstalled bool
+		expectedVersion   interface{}
+	}{
+		{"installed", []runtime.Object{dashboardNS, scraper, dashboard}, "", true, "v2.7.0"},
+		{"namespace only", []runtime.Object{dashboardNS, scraper}, "", false, nil},
+		{"not installed", nil, "", false, nil},
+		{"namespace get fails", []runtime.Object{dashboardNS, dashboard}, "namespaces", false, nil},
+		{"deployment list fails", []runtime.Object{dashboardNS, dashboard}, "deployments", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+			switch tt.failing {
+			case "namespaces":
+				clientset.PrependReactor("get", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if action.(k8stesting.GetAction).GetName() != "kubernetes-dashboard" {
+						return false, nil, nil
+					}
+					return true, nil, apierrors.NewServiceUnavailable("unavailable")
+				})
+			case "deployments":
+				clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if action.GetNamespace() != "kubernetes-dashboard" {
+						return false, nil, nil
+					}
+					return true, nil, apierrors.NewServiceUnavailable("unavailable")
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["kubernetes-dashboard"] != tt.expectedInstalled {
+				t.Errorf("kubernetes-dashboard = %v, want %v", data.ExtraFieldInfo["kubernetes-dashboard"], tt.expectedInstalled)
+			}
+			if data.ExtraFieldInfo["kubernetes-dashboard-version"] != tt.expectedVersion {
+				t.Errorf("kubernetes-dashboard-version = %v, want %v", data.ExtraFieldInfo["kubernetes-dashboard-version"], tt.expectedVersion)
+			}
+			ran := strings.Split(data.ExtraFieldInfo["collectors-run"].(string), ",")
+			if slices.Contains(ran, "dashboard") != (tt.failing == "") {
+				t.Errorf("collectors-run = %v, want dashboard included %v", ran, tt.failing == "")
+			}
+		})
+	}
+}
+
+func TestCollect_HostProcessPods(t *testing.T) {
+	hostProcess := true
+	tests := []struct {
+		name     string
+		mode     string
+		pods     []runtime.Object
+		expected int
+	}{
+		{
+			name: "pod-level hostprocess",
+			mode: "recommended",
+			pods: []runtime.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "calico-node-windows", Namespace: "kube-system"},
+					Spec: corev1.PodSpec{
+						SecurityContext: &corev1.PodSecurityContext{
+							WindowsOptions: &corev1.WindowsSecurityContextOptions{HostProcess: &hostProcess},
+						},
+						Containers: []corev1.Container{{Name: "node"}},
+					},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}}},
+				},
+			},
+			expected: 1,
+		},
+		{
+			name: "container-level hostprocess",
+			mode: "recommended",
+			pods: []runtime.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "exporter", Namespace: "monitoring"},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name: "exporter",
+							SecurityContext: &corev1.SecurityContext{
+								WindowsOptions: &corev1.WindowsSecurityContextOptions{HostProcess: &hostProcess},
+							},
+						}},
+					},
+				},
+			},
+			expected: 1,
+		},
+		{
+			name:     "no pods",
+			mode:     "recommended",
+			expected: 0,
+		},
+		{
+			name: "minimal mode",
+			mode: "minimal",
+			pods: []runtime.Object{
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "calico-node-windows", Namespace: "kube-system"},
+					Spec: corev1.PodSpec{
+						SecurityContext: &corev1.PodSecurityContext{
+							WindowsOptions: &corev1.WindowsSecurityContextOptions{HostProcess: &hostProcess},
+						},
+					},
+				},
+			},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["hostprocess-pod-count"] != tt.expected {
+				t.Errorf("hostprocess-pod-count = %v, want %v", data.ExtraFieldInfo["hostprocess-pod-count"], tt.expected)
+			}
+		})
+	}
+}
+
+// pageNodes makes the fake clientset serve nodes in pages of pageSize. The fake
+// tracker ignores Limit/Continue, so pages are served in call order.
+func pageNodes(clientset *fake.Clientset, nodes []corev1.Node, pageSize int) *int {
+	calls := 0
+	clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		start := calls * pageSize
+		calls++
+		end := start + pageSize
+		list := &corev1.NodeList{}
+		if end < len(nodes) {
+			list.Continue = fmt.Sprintf("page-%d", calls)
+		} else {
+			end = len(nodes)
+		}
+		list.Items = nodes[start:end]
+		return true, list, nil
+	})
+	return &calls
+}
+
+// testDeployment returns a Deployment whose pod template runs one container
+// with image.
+func testDeployment(name, namespace, image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: image}}},
+			},
+		},
+	}
+}
+
+// testDaemonSet returns a DaemonSet whose pod template runs one container
+// with image.
+func testDaemonSet(name, namespace, image string) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: image}}},
+			},
+		},
+	}
+}
+
+// testNode returns a Node named name with each of opts applied.
+func testNode(name string, opts ...func(*corev1.Node)) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+	for _, opt := range opts {
+		opt(node)
+	}
+	return node
+}
+
+// controlPlaneNode gives a test node the control plane role label.
+func controlPlaneNode(node *corev1.Node) {
+	node.Labels["node-role.kubernetes.io/control-plane"] = "true"
+}
+
+func nodeLabel(key, value string) func(*corev1.Node) {
+	return func(node *corev1.Node) { node.Labels[key] = value }
+}
+
+func nodeTaints(taints ...corev1.Taint) func(*corev1.Node) {
+	return func(node *corev1.Node) { node.Spec.Taints = append(node.Spec.Taints, taints...) }
+}
+
+func nodePodCIDRs(cidrs ...string) func(*corev1.Node) {
+	return func(node *corev1.Node) { node.Spec.PodCIDRs = append(node.Spec.PodCIDRs, cidrs...) }
+}
+
+func nodeAddresses(addressType corev1.NodeAddressType, addresses ...string) func(*corev1.Node) {
+	return func(node *corev1.Node) {
+		for _, address := range addresses {
+			node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: addressType, Address: address})
+		}
+	}
+}
+
+func nodeInfo(info corev1.NodeSystemInfo) func(*corev1.Node) {
+	return func(node *corev1.Node) { node.Status.NodeInfo = info }
+}
+
+func nodeAllocatable(allocatable corev1.ResourceList) func(*corev1.Node) {
+	return func(node *corev1.Node) { node.Status.Allocatable = allocatable }
+}
+
+// testPod returns a Pod in namespace running one container named "app", with
+// each of opts applied.
+func testPod(name, namespace string, opts ...func(*corev1.Pod)) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	for _, opt := range opts {
+		opt(pod)
+	}
+	return pod
+}
+
+// podSecurity sets the pod security context and that of the "app" container.
+func podSecurity(podSC *corev1.PodSecurityContext, containerSC *corev1.SecurityContext) func(*corev1.Pod) {
+	return func(pod *corev1.Pod) {
+		pod.Spec.SecurityContext = podSC
+		pod.Spec.Containers[0].SecurityContext = containerSC
+	}
+}
+
+func TestCollect_PagedNodeList(t *testing.T) {
+	var nodes []corev1.Node
+	for i := 0; i < 250; i++ {
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)},
+			Status: corev1.NodeStatus{
+				NodeInfo: corev1.NodeSystemInfo{OSImage: "test", KernelVersion: "5.0", Architecture: "amd64"},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			},
+		}
+		if i < 3 {
+			node.Labels = map[string]string{"node-role.kubernetes.io/control-plane": ""}
+		}
+		if i == 249 {
+			node.Status.NodeInfo.KernelVersion = "6.0"
+		}
+		nodes = append(nodes, node)
+	}
+
+	clientset := fake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}})
+	calls := pageNodes(clientset, nodes, nodeListPageSize)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if *calls != 3 {
+		t.Errorf("node list calls = %d, want 3", *calls)
+	}
+	if data.ExtraFieldInfo["serverNodeCount"] != 3 {
+		t.Errorf("serverNodeCount = %v, want 3", data.ExtraFieldInfo["serverNodeCount"])
+	}
+	if data.ExtraFieldInfo["agentNodeCount"] != 247 {
+		t.Errorf("agentNodeCount = %v, want 247", data.ExtraFieldInfo["agentNodeCount"])
+	}
+	if data.ExtraFieldInfo["agentCPU"] != int64(247*2000) {
+		t.Errorf("agentCPU = %v, want %d", data.ExtraFieldInfo["agentCPU"], 247*2000)
+	}
+	if data.ExtraFieldInfo["serverMemory"] != int64(3*1024*1024*1024) {
+		t.Errorf("serverMemory = %v, want %d", data.ExtraFieldInfo["serverMemory"], 3*1024*1024*1024)
+	}
+	if data.ExtraFieldInfo["node-info-consistent"] != false {
+		t.Errorf("node-info-consistent = %v, want false (last page differs)", data.ExtraFieldInfo["node-info-consistent"])
+	}
+}
+
+func BenchmarkForEachNode(b *testing.B) {
+	var nodes []corev1.Node
+	for i := 0; i < 1000; i++ {
+		nodes = append(nodes, corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)}})
+	}
+
+	b.Run("paged", func(b *testing.B) {
+		clientset := fake.NewClientset()
+		calls := pageNodes(clientset, nodes, nodeListPageSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			*calls = 0
+			count := 0
+			if err := forEachNode(context.Background(), clientset, func(*corev1.Node) { count++ }); err != nil {
+				b.Fatalf("forEachNode() error = %v", err)
+			}
+			if count != len(nodes) {
+				b.Fatalf("visited %d nodes, want %d", count, len(nodes))
+			}
+		}
+	})
+
+	// Baseline: a single List of every node, as before paging
+	b.Run("unpaged", func(b *testing.B) {
+		clientset := fake.NewClientset()
+		clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, &corev1.NodeList{Items: nodes}, nil
+		})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			list, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				b.Fatalf("List() error = %v", err)
+			}
+			if len(list.Items) != len(nodes) {
+				b.Fatalf("listed %d nodes, want %d", len(list.Items), len(nodes))
+			}
+		}
+	})
+}
+
+func TestCollect_CNIConflict(t *testing.T) {
+	tests := []struct {
+		name             string
+		daemonSets       []runtime.Object
+		expectedPlugin   string
+		expectedConflict bool
+		expectedDetected []string
+	}{
+		{
+			name: "flannel and calico",
+			daemonSets: []runtime.Object{
+				testDaemonSet("kube-flannel-ds", "kube-system", "flannel/flannel:v0.22.0"),
+				testDaemonSet("calico-node", "kube-system", "calico/node:v3.26.0"),
+			},
+			expectedPlugin:   "calico",
+			expectedConflict: true,
+			expectedDetected: []string{"calico", "flannel"},
+		},
+		{
+			name: "same plugin twice",
+			daemonSets: []runtime.Object{
+				testDaemonSet("cilium", "kube-system", "cilium/cilium:v1.14.0"),
+				testDaemonSet("cilium-envoy", "kube-system", "cilium/cilium-envoy:v1.14.0"),
+			},
+			expectedPlugin:   "cilium",
+			expectedConflict: false,
+		},
+		{
+			name:             "single plugin",
+			daemonSets:       []runtime.Object{testDaemonSet("rke2-canal", "kube-system", "rancher/hardened-calico:v3.26.0")},
+			expectedPlugin:   "canal",
+			expectedConflict: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.daemonSets...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["cni-plugin"] != tt.expectedPlugin {
+				t.Errorf("cni-plugin = %v, want %v", data.ExtraFieldInfo["cni-plugin"], tt.expectedPlugin)
+			}
+			if data.ExtraFieldInfo["cni-conflict"] != tt.expectedConflict {
+				t.Errorf("cni-conflict = %v, want %v", data.ExtraFieldInfo["cni-conflict"], tt.expectedConflict)
+			}
+			detected, _ := data.ExtraFieldInfo["cni-detected"].([]string)
+			if fmt.Sprint(detected) != fmt.Sprint(tt.expectedDetected) {
+				t.Errorf("cni-detected = %v, want %v", detected, tt.expectedDetected)
+			}
+		})
+	}
+}
+
+func TestCollect_APISurface(t *testing.T) {
+	tests := []struct {
+		name               string
+		groupVersions      []string
+		discoveryDenied    bool
+		expectedCount      int
+		expectedAlpha      bool
+		expectedNonDefault string
+	}{
+		{"stable only", []string{"v1", "apps/v1", "batch/v1"}, false, 3, false, ""},
+		{"alpha group", []string{"v1", "apps/v1", "resource.k8s.io/v1alpha3"}, false, 3, true, "resource.k8s.io/v1alpha3"},
+		{"multiple versions per group", []string{"v1", "storage.k8s.io/v1", "storage.k8s.io/v1beta1"}, false, 2, false, "storage.k8s.io/v1beta1"},
+		{
+			"alpha and beta groups",
+			[]string{"v1", "storage.k8s.io/v1beta1", "admissionregistration.k8s.io/v1alpha1", "resource.k8s.io/v1beta1"},
+			false,
+			4,
+			true,
+			"admissionregistration.k8s.io/v1alpha1,resource.k8s.io/v1beta1,storage.k8s.io/v1beta1",
+		},
+		{"discovery denied", []string{"v1", "resource.k8s.io/v1alpha3"}, true, -1, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			)
+			discovery, ok := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+			if !ok {
+				t.Fatal("unexpected discovery client type")
+			}
+			for _, gv := range tt.groupVersions {
+				discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: gv})
+			}
+			if tt.discoveryDenied {
+				clientset.PrependReactor("get", "group", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{}, "", errors.New("denied"))
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["api-group-count"] != tt.expectedCount {
+				t.Errorf("api-group-count = %v, want %v", data.ExtraFieldInfo["api-group-count"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["alpha-apis-enabled"] != tt.expectedAlpha {
+				t.Errorf("alpha-apis-enabled = %v, want %v", data.ExtraFieldInfo["alpha-apis-enabled"], tt.expectedAlpha)
+			}
+			if data.ExtraFieldInfo["nondefault-apis"] != tt.expectedNonDefault {
+				t.Errorf("nondefault-apis = %v, want %v", data.ExtraFieldInfo["nondefault-apis"], tt.expectedNonDefault)
+			}
+			ran := strings.Split(data.ExtraFieldInfo["collectors-run"].(string), ",")
+			if slices.Contains(ran, "api-surface") == tt.discoveryDenied {
+				t.Errorf("collectors-run = %v, want api-surface included %v", ran, !tt.discoveryDenied)
+			}
+			wantDenials := 0
+			if tt.discoveryDenied {
+				wantDenials = 1
+			}
+			if data.ExtraFieldInfo["rbac-denied-count"] != wantDenials {
+				t.Errorf("rbac-denied-count = %v, want %v", data.ExtraFieldInfo["rbac-denied-count"], wantDenials)
+			}
+		})
+	}
+}
+
+func TestCollect_SnapshotController(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-snapshot-controller", Namespace: "kube-system"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "rancher/hardened-snapshot-controller:v8.2.0"}}},
+				},
+			},
+		},
+	)
+	discovery, ok := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	if !ok {
+		t.Fatal("unexpected discovery client type")
+	}
+	discovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "snapshot.storage.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "volumesnapshotclasses"}, {Name: "volumesnapshots"}},
+	}}
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["snapshot-controller"] != true {
+		t.Errorf("snapshot-controller = %v, want true", data.ExtraFieldInfo["snapshot-controller"])
+	}
+	if data.ExtraFieldInfo["snapshot-controller-version"] != "v8.2.0" {
+		t.Errorf("snapshot-controller-version = %v, want v8.2.0", data.ExtraFieldInfo["snapshot-controller-version"])
+	}
+	if data.ExtraFieldInfo["volumesnapshotclass-crd"] != true {
+		t.Errorf("volumesnapshotclass-crd = %v, want true", data.ExtraFieldInfo["volumesnapshotclass-crd"])
+	}
+}
+
+func TestContainerImageVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		fragments  []string
+		expected   string
+	}{
+		{"match after sidecar", []corev1.Container{{Image: "istio/proxyv2:1.20.0"}, {Image: "rancher/rancher-agent:v2.8.0"}}, []string{"rancher-agent"}, "v2.8.0"},
+		{"fallback to first", []corev1.Container{{Image: "rancher/hardened-calico:v3.26.0"}, {Image: "rancher/hardened-flannel:v0.22.0"}}, []string{"canal"}, "v3.26.0"},
+		{"any fragment", []corev1.Container{{Image: "linkerd/proxy:stable"}, {Image: "rancher/nginx-ingress-controller:v1.9.0"}}, []string{"ingress-nginx", "nginx-ingress"}, "v1.9.0"},
+		{"no containers", nil, []string{"traefik"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := containerImageVersion(tt.containers, tt.fragments...)
+			if result != tt.expected {
+				t.Errorf("containerImageVersion() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_RancherManagedWithSidecar(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cattle-cluster-agent", Namespace: "cattle-system"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "istio-proxy", Image: "docker.io/istio/proxyv2:1.20.0"},
+							{
+								Name:  "cluster-register",
+								Image: "rancher/rancher-agent:v2.8.0",
+								Env: []corev1.EnvVar{
+									{Name: "CATTLE_INSTALL_UUID", Value: "rancher-install-uuid-123"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
+			Spec: appsv1.DaemonSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "istio-proxy", Image: "docker.io/istio/proxyv2:1.20.0"},
+							{Name: "controller", Image: "rancher/nginx-ingress-controller:v1.9.0"},
+						},
+					},
+				},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["rancher-version"] != "v2.8.0" {
+		t.Errorf("rancher-version = %v, want v2.8.0", data.ExtraFieldInfo["rancher-version"])
+	}
+	if data.ExtraFieldInfo["rancher-install-uuid"] != "rancher-install-uuid-123" {
+		t.Errorf("rancher-install-uuid = %v, want rancher-install-uuid-123", data.ExtraFieldInfo["rancher-install-uuid"])
+	}
+	if data.ExtraFieldInfo["ingress-version"] != "v1.9.0" {
+		t.Errorf("ingress-version = %v, want v1.9.0", data.ExtraFieldInfo["ingress-version"])
+	}
+}
+
+func TestCollect_RancherWebhook(t *testing.T) {
+	webhook := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "rancher-webhook", Namespace: "cattle-system"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "rancher-webhook", Image: "rancher/rancher-webhook:v0.6.3"}},
+		}}},
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		objects  []runtime.Object
+		expected any
+	}{
+		{
+			name:     "webhook present",
+			mode:     "recommended",
+			objects:  []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}}, webhook},
+			expected: "v0.6.3",
+		},
+		{
+			name:     "no webhook",
+			mode:     "recommended",
+			objects:  []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}}},
+			expected: nil,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			objects:  []runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "cattle-system"}}, webhook},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["rancher-webhook-version"] != tt.expected {
+				t.Errorf("rancher-webhook-version = %v, want %v", data.ExtraFieldInfo["rancher-webhook-version"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_ExternalAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployments []runtime.Object
+		expected    string
+	}{
+		{
+			name: "dex",
+			deployments: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "dex", Namespace: "dex"}},
+			},
+			expected: "oidc",
+		},
+		{
+			name: "oauth2-proxy in cattle-system",
+			deployments: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "oauth2-proxy", Namespace: "cattle-system"}},
+			},
+			expected: "oidc",
+		},
+		{
+			name: "saml proxy",
+			deployments: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "saml-auth-proxy", Namespace: "auth"}},
+			},
+			expected: "saml",
+		},
+		{
+			name: "dex outside known namespaces",
+			deployments: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "dex", Namespace: "apps"}},
+			},
+			expected: "none",
+		},
+		{
+			name:     "none",
+			expected: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.deployments...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["external-auth"] != tt.expected {
+				t.Errorf("external-auth = %v, want %v", data.ExtraFieldInfo["external-auth"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectExternalAuth_ListForbidden(t *testing.T) {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("list", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "dex" {
+			return true, nil, fmt.Errorf("forbidden")
+		}
+		return false, nil, nil
+	})
+
+	if got := detectExternalAuth(context.Background(), newWorkloadCache(clientset)); got != "unknown" {
+		t.Errorf("detectExternalAuth() = %q, want unknown", got)
+	}
+}
+
+func TestDumpRequest_RedactsCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "request.dump")
+	body := `{"appVersion":"v1.30.0"}`
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/v1/checkupgrade", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer super-secret-token")
+	req.Header.Set("X-Auth-Token", "another-secret")
+	req.Header.Set(IdempotencyKeyHeader, "run-key")
+
+	if err := dumpRequest(req, path); err != nil {
+		t.Fatalf("dumpRequest() error = %v", err)
+	}
+
+	dump, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	got := string(dump)
+
+	for _, want := range []string{"POST /v1/checkupgrade HTTP/1.1", body, "Authorization: REDACTED", "X-Auth-Token: REDACTED", IdempotencyKeyHeader + ": run-key"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dump missing %q:\n%s", want, got)
+		}
+	}
+	for _, secret := range []string{"super-secret-token", "another-secret"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("dump contains secret %q", secret)
+		}
+	}
+
+	// The original request must still carry its credentials and body
+	if req.Header.Get("Authorization") != "Bearer super-secret-token" {
+		t.Errorf("original Authorization header modified: %q", req.Header.Get("Authorization"))
+	}
+}
+
+func TestSend_DumpRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(received), `"appVersion":"test"`) {
+			t.Errorf("server received body %q", received)
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "request.dump")
+	data := &Data{AppVersion: "test", ExtraTagInfo: map[string]string{}, ExtraFieldInfo: map[string]interface{}{}}
+
+	if _, err := Send(context.Background(), data, server.URL, WithRequestDump(path)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	dump, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(dump), `"appVersion":"test"`) {
+		t.Errorf("dump missing body:\n%s", dump)
+	}
+}
+
+func TestCollect_HostPortPods(t *testing.T) {
+	hostPorts := func(pod *corev1.Pod) {
+		pod.Spec.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 8080, HostPort: 8080}, {ContainerPort: 9090, HostPort: 9090}}
+		pod.Spec.Containers = append([]corev1.Container{{Name: "sidecar"}}, pod.Spec.Containers...)
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		pods     []runtime.Object
+		expected int
+	}{
+		{
+			name: "user pod with hostPort",
+			mode: "recommended",
+			pods: []runtime.Object{
+				testPod("web", "default", hostPorts),
+				testPod("api", "default", func(pod *corev1.Pod) {
+					pod.Spec.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 80}}
+				}),
+			},
+			expected: 1,
+		},
+		{
+			name:     "system namespace excluded",
+			mode:     "recommended",
+			pods:     []runtime.Object{testPod("rke2-ingress-nginx-controller", "kube-system", hostPorts)},
+			expected: 0,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			pods:     []runtime.Object{testPod("web", "default", hostPorts)},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["hostport-pod-count"] != tt.expected {
+				t.Errorf("hostport-pod-count = %v, want %v", data.ExtraFieldInfo["hostport-pod-count"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_HostIPCPods(t *testing.T) {
+	hostIPC := func(pod *corev1.Pod) { pod.Spec.HostIPC = true }
+
+	tests := []struct {
+		name     string
+		mode     string
+		pods     []runtime.Object
+		expected int
+	}{
+		{
+			name: "user pod with hostIPC",
+			mode: "recommended",
+			pods: []runtime.Object{
+				testPod("shm-app", "default", hostIPC),
+				testPod("web", "default"),
+			},
+			expected: 1,
+		},
+		{
+			name:     "system namespace excluded",
+			mode:     "recommended",
+			pods:     []runtime.Object{testPod("node-agent", "kube-system", hostIPC)},
+			expected: 0,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			pods:     []runtime.Object{testPod("shm-app", "default", hostIPC)},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["hostipc-pod-count"] != tt.expected {
+				t.Errorf("hostipc-pod-count = %v, want %v", data.ExtraFieldInfo["hostipc-pod-count"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_UnsafeSysctlPods(t *testing.T) {
+	sysctlPod := func(name, namespace string, sysctls ...string) *corev1.Pod {
+		podSC := &corev1.PodSecurityContext{}
+		for _, sysctl := range sysctls {
+			podSC.Sysctls = append(podSC.Sysctls, corev1.Sysctl{Name: sysctl, Value: "1"})
+		}
+		return testPod(name, namespace, podSecurity(podSC, nil))
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		pods     []runtime.Object
+		expected int
+	}{
+		{
+			name: "safe and unsafe sysctls",
+			mode: "recommended",
+			pods: []runtime.Object{
+				sysctlPod("web", "default", "net.ipv4.ip_local_port_range"),
+				// somaxconn is namespaced but not on the kubelet's safe list
+				sysctlPod("queue", "default", "net.ipv4.tcp_syncookies", "net.core.somaxconn"),
+				sysctlPod("db", "default", "kernel/shm_rmid_forced"),
+				sysctlPod("plain", "default"),
+			},
+			expected: 1,
+		},
+		{
+			name:     "system namespace excluded",
+			mode:     "recommended",
+			pods:     []runtime.Object{sysctlPod("node-agent", "kube-system", "net.core.somaxconn")},
+			expected: 0,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			pods:     []runtime.Object{sysctlPod("queue", "default", "net.core.somaxconn")},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["unsafe-sysctl-pod-count"] != tt.expected {
+				t.Errorf("unsafe-sysctl-pod-count = %v, want %v", data.ExtraFieldInfo["unsafe-sysctl-pod-count"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_EvictedPods(t *testing.T) {
+	pod := func(name, namespace string, phase corev1.PodPhase, reason string) *corev1.Pod {
+		return testPod(name, namespace, func(pod *corev1.Pod) {
+			pod.Status = corev1.PodStatus{Phase: phase, Reason: reason}
+		})
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		pods     []runtime.Object
+		expected int
+	}{
+		{
+			name: "evicted pods accumulating",
+			mode: "recommended",
+			pods: []runtime.Object{
+				pod("web-1", "default", corev1.PodFailed, "Evicted"),
+				pod("web-2", "default", corev1.PodFailed, "Evicted"),
+				pod("coredns-1", "kube-system", corev1.PodFailed, "Evicted"),
+				pod("job-1", "default", corev1.PodFailed, "DeadlineExceeded"),
+				pod("web-3", "default", corev1.PodRunning, ""),
+			},
+			expected: 3,
+		},
+		{
+			name:     "no evicted pods",
+			mode:     "recommended",
+			pods:     []runtime.Object{pod("web", "default", corev1.PodRunning, "")},
+			expected: 0,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			pods:     []runtime.Object{pod("web", "default", corev1.PodFailed, "Evicted")},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["evicted-pod-count"] != tt.expected {
+				t.Errorf("evicted-pod-count = %v, want %v", data.ExtraFieldInfo["evicted-pod-count"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_InitAndEphemeralContainers(t *testing.T) {
+	privileged := &corev1.SecurityContext{Privileged: ptr.To(true)}
+	pod := func(name, namespace string, init []corev1.Container, ephemeral []corev1.EphemeralContainer) *corev1.Pod {
+		return testPod(name, namespace, func(pod *corev1.Pod) {
+			pod.Spec.InitContainers = init
+			pod.Spec.EphemeralContainers = ephemeral
+		})
+	}
+	debug := []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "busybox"}}}
+
+	tests := []struct {
+		name              string
+		mode              string
+		pods              []runtime.Object
+		expectedInit      int
+		expectedEphemeral bool
+	}{
+		{
+			name: "privileged init containers",
+			mode: "recommended",
+			pods: []runtime.Object{
+				pod("sysctl-tuner", "default", []corev1.Container{{Name: "tune", SecurityContext: privileged}}, nil),
+				pod("setup", "default", []corev1.Container{{Name: "chown", SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(false)}}}, nil),
+				pod("canal", "kube-system", []corev1.Container{{Name: "install-cni", SecurityContext: privileged}}, nil),
+				pod("plain", "default", nil, nil),
+			},
+			expectedInit: 1,
+		},
+		{
+			name:              "ephemeral container in system namespace",
+			mode:              "recommended",
+			pods:              []runtime.Object{pod("coredns", "kube-system", nil, debug)},
+			expectedEphemeral: true,
+		},
+		{
+			name: "minimal mode",
+			mode: "minimal",
+			pods: []runtime.Object{
+				pod("sysctl-tuner", "default", []corev1.Container{{Name: "tune", SecurityContext: privileged}}, debug),
+			},
+			expectedInit: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["privileged-init-pod-count"] != tt.expectedInit {
+				t.Errorf("privileged-init-pod-count = %v, want %v", data.ExtraFieldInfo["privileged-init-pod-count"], tt.expectedInit)
+			}
+			if data.ExtraFieldInfo["ephemeral-containers-used"] != tt.expectedEphemeral {
+				t.Errorf("ephemeral-containers-used = %v, want %v", data.ExtraFieldInfo["ephemeral-containers-used"], tt.expectedEphemeral)
+			}
+		})
+	}
+}
+
+func TestCollect_PriorityClasses(t *testing.T) {
+	systemClasses := []runtime.Object{
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "system-cluster-critical"}, Value: 2000000000},
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "system-node-critical"}, Value: 2000001000},
+	}
+	custom := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "high-priority"}, Value: 1000000}
+
+	tests := []struct {
+		name           string
+		mode           string
+		classes        []runtime.Object
+		expectedCount  int
+		expectedSystem bool
+		expectedCustom int
+	}{
+		{"system and custom", "recommended", append(systemClasses, custom), 3, true, 1},
+		{"system only", "recommended", systemClasses, 2, true, 0},
+		{"custom only", "recommended", []runtime.Object{custom}, 1, false, 1},
+		{"minimal mode", "minimal", append(systemClasses, custom), -1, true, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.classes...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["priorityclass-count"] != tt.expectedCount {
+				t.Errorf("priorityclass-count = %v, want %v", data.ExtraFieldInfo["priorityclass-count"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["system-priorityclasses"] != tt.expectedSystem {
+				t.Errorf("system-priorityclasses = %v, want %v", data.ExtraFieldInfo["system-priorityclasses"], tt.expectedSystem)
+			}
+			if data.ExtraFieldInfo["custom-priorityclass-count"] != tt.expectedCustom {
+				t.Errorf("custom-priorityclass-count = %v, want %v", data.ExtraFieldInfo["custom-priorityclass-count"], tt.expectedCustom)
+			}
+		})
+	}
+}
+
+func TestCollect_APF(t *testing.T) {
+	flowSchemas := []runtime.Object{
+		&flowcontrolv1.FlowSchema{ObjectMeta: metav1.ObjectMeta{Name: "exempt"}},
+		&flowcontrolv1.FlowSchema{ObjectMeta: metav1.ObjectMeta{Name: "catch-all"}},
+	}
+
+	tests := []struct {
+		name            string
+		mode            string
+		served          []*metav1.APIResourceList
+		expectedEnabled bool
+		expectedCount   int
+	}{
+		{
+			name: "flowcontrol API served",
+			mode: "recommended",
+			served: []*metav1.APIResourceList{
+				{GroupVersion: "flowcontrol.apiserver.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "flowschemas"}}},
+			},
+			expectedEnabled: true,
+			expectedCount:   2,
+		},
+		{
+			name:            "flowcontrol API not served",
+			mode:            "recommended",
+			expectedEnabled: false,
+			expectedCount:   -1,
+		},
+		{
+			name: "minimal mode",
+			mode: "minimal",
+			served: []*metav1.APIResourceList{
+				{GroupVersion: "flowcontrol.apiserver.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "flowschemas"}}},
+			},
+			expectedEnabled: true,
+			expectedCount:   -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, flowSchemas...)
+			clientset := fake.NewClientset(objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = tt.served
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["apf-enabled"] != tt.expectedEnabled {
+				t.Errorf("apf-enabled = %v, want %v", data.ExtraFieldInfo["apf-enabled"], tt.expectedEnabled)
+			}
+			if data.ExtraFieldInfo["flowschema-count"] != tt.expectedCount {
+				t.Errorf("flowschema-count = %v, want %v", data.ExtraFieldInfo["flowschema-count"], tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestCollect_ValidatingAdmissionPolicies(t *testing.T) {
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&admissionregistrationv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-privileged"}},
+		&admissionregistrationv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Name: "require-labels"}},
+		&admissionregistrationv1.ValidatingAdmissionPolicyBinding{ObjectMeta: metav1.ObjectMeta{Name: "deny-privileged-binding"}},
+	}
+	vapAPI := []*metav1.APIResourceList{{
+		GroupVersion: "admissionregistration.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "validatingadmissionpolicies"}, {Name: "validatingadmissionpolicybindings"}},
+	}}
+
+	tests := []struct {
+		name             string
+		mode             string
+		served           []*metav1.APIResourceList
+		expectedPolicies any
+		expectedBindings any
+	}{
+		{"API served", "recommended", vapAPI, 2, 1},
+		{"minimal mode", "minimal", vapAPI, -1, -1},
+		{"API not served", "recommended", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = tt.served
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["vap-count"] != tt.expectedPolicies {
+				t.Errorf("vap-count = %v, want %v", data.ExtraFieldInfo["vap-count"], tt.expectedPolicies)
+			}
+			if data.ExtraFieldInfo["vap-binding-count"] != tt.expectedBindings {
+				t.Errorf("vap-binding-count = %v, want %v", data.ExtraFieldInfo["vap-binding-count"], tt.expectedBindings)
+			}
+		})
+	}
+}
+
+func TestCollect_Tenancy(t *testing.T) {
+	namespaces := func(names ...string) []runtime.Object {
+		objects := []runtime.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-public"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		}
+		for _, name := range names {
+			objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+		return objects
+	}
+
+	tests := []struct {
+		name            string
+		mode            string
+		objects         []runtime.Object
+		expectedCount   int
+		expectedTenancy string
+	}{
+		{"several user namespaces", "recommended", namespaces("team-a", "team-b", "team-c"), 6, "multi"},
+		{"one user namespace", "recommended", namespaces("app"), 4, "single"},
+		{"system namespaces only", "recommended", namespaces(), 3, "single"},
+		{"minimal mode", "minimal", namespaces("team-a", "team-b"), -1, "multi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(tt.objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["namespace-count"] != tt.expectedCount {
+				t.Errorf("namespace-count = %v, want %v", data.ExtraFieldInfo["namespace-count"], tt.expectedCount)
+			}
+			if data.ExtraFieldInfo["tenancy"] != tt.expectedTenancy {
+				t.Errorf("tenancy = %v, want %v", data.ExtraFieldInfo["tenancy"], tt.expectedTenancy)
+			}
+		})
+	}
+}
+
+func TestCollect_NamespaceLimits(t *testing.T) {
+	base := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+		&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "team-a"}},
+		&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "objects", Namespace: "team-a"}},
+		&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "team-a"}},
+		&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "team-b"}},
+	}
+
+	tests := []struct {
+		name               string
+		mode               string
+		denied             string
+		expectedQuota      int
+		expectedLimitRange int
+	}{
+		{name: "quota and limitrange", mode: "recommended", expectedQuota: 2, expectedLimitRange: 1},
+		{name: "minimal mode", mode: "minimal", expectedQuota: -1, expectedLimitRange: -1},
+		{name: "limitranges denied", mode: "recommended", denied: "limitranges", expectedQuota: 2, expectedLimitRange: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset(base...)
+			if tt.denied != "" {
+				clientset.PrependReactor("list", tt.denied, func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: tt.denied}, "", errors.New("denied"))
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["namespaces-with-quota"] != tt.expectedQuota {
+				t.Errorf("namespaces-with-quota = %v, want %v", data.ExtraFieldInfo["namespaces-with-quota"], tt.expectedQuota)
+			}
+			if data.ExtraFieldInfo["namespaces-with-limitrange"] != tt.expectedLimitRange {
+				t.Errorf("namespaces-with-limitrange = %v, want %v", data.ExtraFieldInfo["namespaces-with-limitrange"], tt.expectedLimitRange)
+			}
+		})
+	}
+}
+
+func TestCollect_ClusterDomain(t *testing.T) {
+	corefile := func(name, zones string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			Data: map[string]string{"Corefile": `.:53 {
+    errors
+    health
+    kubernetes ` + zones + ` {
+        pods insecure
+        fallthrough in-addr.arpa ip6.arpa
+    }
+    forward . /etc/resolv.conf
+}`},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{"custom domain", []runtime.Object{corefile("rke2-coredns-rke2-coredns", "corp.example in-addr.arpa ip6.arpa")}, "corp.example"},
+		{"default domain", []runtime.Object{corefile("rke2-coredns-rke2-coredns", "cluster.local in-addr.arpa ip6.arpa")}, "cluster.local"},
+		{"upstream configmap", []runtime.Object{corefile("coredns", "in-addr.arpa k8s.internal.")}, "k8s.internal"},
+		{"no domain in corefile", []runtime.Object{corefile("rke2-coredns-rke2-coredns", "")}, "cluster.local"},
+		{"no coredns", nil, "cluster.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}}}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended", WithCollectors("dns"))
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["cluster-domain"] != tt.expected {
+				t.Errorf("cluster-domain = %v, want %v", data.ExtraFieldInfo["cluster-domain"], tt.expected)
+			}
+		})
+	}
+}
+
+const kubeBenchOutput = `[INFO] 1 Control Plane Security Configuration
+[PASS] 1.1.1 Ensure that the API server pod specification file permissions are set to 600 or more restrictive (Automated)
+[FAIL] 1.1.2 Ensure that the API server pod specification file ownership is set to root:root (Automated)
+
+== Summary master ==
+38 checks PASS
+9 checks FAIL
+11 checks WARN
+0 checks INFO
+
+== Summary node ==
+17 checks PASS
+2 checks FAIL
+4 checks WARN
+0 checks INFO
+
+== Summary total ==
+55 checks PASS
+11 checks FAIL
+15 checks WARN
+0 checks INFO
+`
+
+func TestParseKubeBenchSummary(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		wantPass  int
+		wantFail  int
+		wantFound bool
+	}{
+		{"total block", kubeBenchOutput, 55, 11, true},
+		{"sections only", "== Summary master ==\n38 checks PASS\n9 checks FAIL\n== Summary node ==\n17 checks PASS\n2 checks FAIL\n", 55, 11, true},
+		{"not kube-bench output", "hello world", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pass, fail, ok := parseKubeBenchSummary(tt.output)
+			if pass != tt.wantPass || fail != tt.wantFail || ok != tt.wantFound {
+				t.Errorf("parseKubeBenchSummary() = %d, %d, %v, want %d, %d, %v", pass, fail, ok, tt.wantPass, tt.wantFail, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestCollect_KubeBench(t *testing.T) {
+	results := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-bench-results", Namespace: "kube-system"},
+		Data:       map[string]string{"results.txt": kubeBenchOutput},
+	}
+
+	tests := []struct {
+		name         string
+		mode         string
+		objects      []runtime.Object
+		expectedPass any
+		expectedFail any
+	}{
+		{"results configmap", "recommended", []runtime.Object{results}, 55, 11},
+		{"minimal mode", "minimal", []runtime.Object{results}, -1, -1},
+		{"no configmap", "recommended", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["cis-pass-count"] != tt.expectedPass {
+				t.Errorf("cis-pass-count = %v, want %v", data.ExtraFieldInfo["cis-pass-count"], tt.expectedPass)
+			}
+			if data.ExtraFieldInfo["cis-fail-count"] != tt.expectedFail {
+				t.Errorf("cis-fail-count = %v, want %v", data.ExtraFieldInfo["cis-fail-count"], tt.expectedFail)
+			}
+		})
+	}
+}
+
+func TestCollect_MonitoringStack(t *testing.T) {
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		expectedStack   string
+		expectedVersion any
+	}{
+		{
+			name: "rancher monitoring",
+			objects: []runtime.Object{
+				testDeployment("rancher-monitoring-grafana", "cattle-monitoring-system", "rancher/mirrored-grafana-grafana:10.4.1"),
+				testDeployment("rancher-monitoring-operator", "cattle-monitoring-system", "rancher/mirrored-prometheus-operator-prometheus-operator:v0.72.0"),
+			},
+			expectedStack:   "rancher-monitoring",
+			expectedVersion: "v0.72.0",
+		},
+		{
+			name: "kube-prometheus-stack",
+			objects: []runtime.Object{
+				testDeployment("kube-prometheus-stack-operator", "monitoring", "quay.io/prometheus-operator/prometheus-operator:v0.75.2"),
+			},
+			expectedStack:   "prometheus-operator",
+			expectedVersion: "v0.75.2",
+		},
+		{
+			name:            "none",
+			expectedStack:   "none",
+			expectedVersion: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["monitoring-stack"] != tt.expectedStack {
+				t.Errorf("monitoring-stack = %v, want %v", data.ExtraFieldInfo["monitoring-stack"], tt.expectedStack)
+			}
+			if data.ExtraFieldInfo["monitoring-stack-version"] != tt.expectedVersion {
+				t.Errorf("monitoring-stack-version = %v, want %v", data.ExtraFieldInfo["monitoring-stack-version"], tt.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestCollect_SecretManager(t *testing.T) {
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		expectedManager string
+		expectedVersion any
+	}{
+		{
+			name: "external-secrets-operator",
+			objects: []runtime.Object{
+				testDeployment("external-secrets-cert-controller", "external-secrets", "ghcr.io/external-secrets/external-secrets:v0.10.4"),
+				testDeployment("external-secrets", "external-secrets", "ghcr.io/external-secrets/external-secrets:v0.10.4"),
+				testDeployment("external-secrets-webhook", "external-secrets", "ghcr.io/external-secrets/external-secrets:v0.10.4"),
+			},
+			expectedManager: "external-secrets",
+			expectedVersion: "v0.10.4",
+		},
+		{
+			name:            "vault agent injector",
+			objects:         []runtime.Object{testDeployment("vault-agent-injector", "vault", "hashicorp/vault-k8s:1.4.2")},
+			expectedManager: "vault",
+			expectedVersion: "1.4.2",
+		},
+		{
+			name:            "none",
+			expectedManager: "none",
+			expectedVersion: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["secret-manager"] != tt.expectedManager {
+				t.Errorf("secret-manager = %v, want %v", data.ExtraFieldInfo["secret-manager"], tt.expectedManager)
+			}
+			if data.ExtraFieldInfo["secret-manager-version"] != tt.expectedVersion {
+				t.Errorf("secret-manager-version = %v, want %v", data.ExtraFieldInfo["secret-manager-version"], tt.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestCollect_CustomSchedulers(t *testing.T) {
+	pod := func(name, scheduler string) *corev1.Pod {
+		return testPod(name, "default", func(pod *corev1.Pod) { pod.Spec.SchedulerName = scheduler })
+	}
+
+	tests := []struct {
+		name               string
+		mode               string
+		objects            []runtime.Object
+		expectedSchedulers string
+		expectedVersions   map[string]any
+	}{
+		{
+			name: "volcano",
+			mode: "recommended",
+			objects: []runtime.Object{
+				pod("web", corev1.DefaultSchedulerName),
+				pod("unset", ""),
+				pod("training-job-0", "volcano"),
+				pod("training-job-1", "volcano"),
+				testDeployment("volcano-controllers", "volcano-system", "volcanosh/vc-controller-manager:v1.10.0"),
+				testDeployment("volcano-scheduler", "volcano-system", "volcanosh/vc-scheduler:v1.10.0"),
+			},
+			expectedSchedulers: "volcano",
+			expectedVersions:   map[string]any{"volcano-version": "v1.10.0", "yunikorn-version": nil},
+		},
+		{
+			name: "several schedulers",
+			mode: "recommended",
+			objects: []runtime.Object{
+				pod("spark-driver", "yunikorn"),
+				pod("batch", "volcano"),
+				testDeployment("yunikorn-scheduler", "yunikorn", "apache/yunikorn:scheduler-1.6.0"),
+			},
+			expectedSchedulers: "volcano,yunikorn",
+			expectedVersions:   map[string]any{"volcano-version": nil, "yunikorn-version": "scheduler-1.6.0"},
+		},
+		{
+			name:               "default scheduler only",
+			mode:               "recommended",
+			objects:            []runtime.Object{pod("web", corev1.DefaultSchedulerName)},
+			expectedSchedulers: "",
+			expectedVersions:   map[string]any{"volcano-version": nil, "yunikorn-version": nil},
+		},
+		{
+			name:               "minimal mode",
+			mode:               "minimal",
+			objects:            []runtime.Object{pod("training-job-0", "volcano")},
+			expectedSchedulers: "",
+			expectedVersions:   map[string]any{"volcano-version": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["custom-schedulers"] != tt.expectedSchedulers {
+				t.Errorf("custom-schedulers = %v, want %q", data.ExtraFieldInfo["custom-schedulers"], tt.expectedSchedulers)
+			}
+			for key, want := range tt.expectedVersions {
+				if data.ExtraFieldInfo[key] != want {
+					t.Errorf("%s = %v, want %v", key, data.ExtraFieldInfo[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCollect_Virtualization(t *testing.T) {
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		expected        string
+		expectedVersion any
+	}{
+		{
+			name: "kubevirt control plane",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kubevirt"}},
+				testDeployment("virt-api", "kubevirt", "quay.io/kubevirt/virt-api:v1.3.1"),
+				testDeployment("virt-controller", "kubevirt", "quay.io/kubevirt/virt-controller:v1.3.1"),
+				testDeployment("virt-operator", "kubevirt", "quay.io/kubevirt/virt-operator:v1.3.1"),
+				testDaemonSet("virt-handler", "kubevirt", "quay.io/kubevirt/virt-handler:v1.3.1"),
+			},
+			expected:        "kubevirt",
+			expectedVersion: "v1.3.1",
+		},
+		{
+			name: "harvester",
+			objects: []runtime.Object{
+				testDaemonSet("virt-handler", "harvester-system", "registry.suse.com/suse/sles/15.6/virt-handler:1.3.1-150600.5.9.1"),
+			},
+			expected:        "kubevirt",
+			expectedVersion: "1.3.1-150600.5.9.1",
+		},
+		{
+			name:            "operator only",
+			objects:         []runtime.Object{testDeployment("virt-operator", "kubevirt", "quay.io/kubevirt/virt-operator:v1.3.1")},
+			expected:        "none",
+			expectedVersion: nil,
+		},
+		{
+			name:            "none",
+			expected:        "none",
+			expectedVersion: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["virtualization"] != tt.expected {
+				t.Errorf("virtualization = %v, want %v", data.ExtraFieldInfo["virtualization"], tt.expected)
+			}
+			if data.ExtraFieldInfo["virtualization-version"] != tt.expectedVersion {
+				t.Errorf("virtualization-version = %v, want %v", data.ExtraFieldInfo["virtualization-version"], tt.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestCollect_CustomDataDir(t *testing.T) {
+	daemonSet := func(name string, hostPaths ...string) *appsv1.DaemonSet {
+		ds := testDaemonSet(name, "kube-system", "")
+		for i, p := range hostPaths {
+			ds.Spec.Template.Spec.Volumes = append(ds.Spec.Template.Spec.Volumes, corev1.Volume{
+				Name:         fmt.Sprintf("host-%d", i),
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: p}},
+			})
+		}
+		return ds
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected bool
+	}{
+		{
+			name:     "relocated data dir",
+			objects:  []runtime.Object{daemonSet("rke2-canal", "/opt/cni/bin", "/data/rke2/agent/etc/cni/net.d")},
+			expected: true,
+		},
+		{
+			name:     "default data dir",
+			objects:  []runtime.Object{daemonSet("rke2-canal", "/opt/cni/bin", "/var/lib/rancher/rke2/agent/etc/cni/net.d", "/etc/rancher/rke2")},
+			expected: false,
+		},
+		{
+			name:     "kube-proxy relocated",
+			objects:  []runtime.Object{daemonSet("kube-proxy", "/srv/rancher/rke2/agent/kubeproxy.kubeconfig")},
+			expected: true,
+		},
+		{
+			name:     "unrelated daemonset",
+			objects:  []runtime.Object{daemonSet("log-shipper", "/data/rke2/agent/logs")},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["custom-data-dir"] != tt.expected {
+				t.Errorf("custom-data-dir = %v, want %v", data.ExtraFieldInfo["custom-data-dir"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_WorkloadIdentity(t *testing.T) {
+	daemonSet := func(name, namespace, image string, sidecars ...string) *appsv1.DaemonSet {
+		ds := testDaemonSet(name, namespace, image)
+		for _, sidecar := range sidecars {
+			ds.Spec.Template.Spec.Containers = append(ds.Spec.Template.Spec.Containers, corev1.Container{Image: sidecar})
+		}
+		return ds
+	}
+
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		expected        string
+		expectedVersion any
+	}{
+		{
+			name: "spire chart layout",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "spire-system"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "spire-server"}},
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "spire-spiffe-oidc-discovery-provider", Namespace: "spire-server"}},
+				daemonSet("spire-agent", "spire-system",
+					"ghcr.io/spiffe/spire-agent:1.9.6",
+					"ghcr.io/spiffe/spiffe-csi-driver:0.2.6"),
+				daemonSet("spire-spiffe-csi-driver", "spire-system", "ghcr.io/spiffe/spiffe-csi-driver:0.2.6"),
+			},
+			expected:        "spire",
+			expectedVersion: "1.9.6",
+		},
+		{
+			name:            "single namespace manifests",
+			objects:         []runtime.Object{daemonSet("spire-agent", "spire", "ghcr.io/spiffe/spire-agent:1.8.0")},
+			expected:        "spire",
+			expectedVersion: "1.8.0",
+		},
+		{
+			name:            "agent outside spire namespaces",
+			objects:         []runtime.Object{daemonSet("spire-agent", "default", "ghcr.io/spiffe/spire-agent:1.9.6")},
+			expected:        "none",
+			expectedVersion: nil,
+		},
+		{
+			name:            "none",
+			expected:        "none",
+			expectedVersion: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["workload-identity"] != tt.expected {
+				t.Errorf("workload-identity = %v, want %v", data.ExtraFieldInfo["workload-identity"], tt.expected)
+			}
+			if data.ExtraFieldInfo["workload-identity-version"] != tt.expectedVersion {
+				t.Errorf("workload-identity-version = %v, want %v", data.ExtraFieldInfo["workload-identity-version"], tt.expectedVersion)
+			}
+		})
+	}
+}
+
+func TestCollect_PodSignalsSinglePass(t *testing.T) {
+	hostProcess := true
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "windows-exporter", Namespace: "monitoring"},
+			Spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{
+					WindowsOptions: &corev1.WindowsSecurityContextOptions{HostProcess: &hostProcess},
+				},
+				Containers: []corev1.Container{{Name: "exporter", Ports: []corev1.ContainerPort{{ContainerPort: 9182, HostPort: 9182}}}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "web", Ports: []corev1.ContainerPort{{ContainerPort: 80, HostPort: 80}}}},
+			},
+		},
+	)
+
+	data, err := Collect(context.Background(), clientset, "recommended")
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	if data.ExtraFieldInfo["hostprocess-pod-count"] != 1 {
+		t.Errorf("hostprocess-pod-count = %v, want 1", data.ExtraFieldInfo["hostprocess-pod-count"])
+	}
+	if data.ExtraFieldInfo["hostport-pod-count"] != 2 {
+		t.Errorf("hostport-pod-count = %v, want 2", data.ExtraFieldInfo["hostport-pod-count"])
+	}
+
+	podLists := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			podLists++
+		}
+	}
+	if podLists != 1 {
+		t.Errorf("pods listed %d times, want 1", podLists)
+	}
+}
+
+func TestScanPods_Paged(t *testing.T) {
+	var pods []corev1.Pod
+	for i := 0; i < 2*podListPageSize+1; i++ {
+		pods = append(pods, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "default"}})
+	}
+	clientset := fake.NewClientset()
+	calls := 0
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		start := calls * podListPageSize
+		calls++
+		end := start + podListPageSize
+		list := &corev1.PodList{}
+		if end < len(pods) {
+			list.Continue = fmt.Sprintf("page-%d", calls)
+		} else {
+			end = len(pods)
+		}
+		list.Items = pods[start:end]
+		return true, list, nil
+	})
+
+	var first, second int
+	err := scanPods(context.Background(), clientset,
+		countPods(&first, func(*corev1.Pod) bool { return true }),
+		countPods(&second, func(pod *corev1.Pod) bool { return pod.Name == "pod-0" }),
+	)
+	if err != nil {
+		t.Fatalf("scanPods() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("pod list calls = %d, want 3", calls)
+	}
+	if first != len(pods) {
+		t.Errorf("first inspector saw %d pods, want %d", first, len(pods))
+	}
+	if second != 1 {
+		t.Errorf("second inspector matched %d pods, want 1", second)
+	}
+}
+
+func TestCollect_EtcdTLS(t *testing.T) {
+	clientPort := int32(2379)
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		failing  string
+		expected string
+	}{
+		{
+			name: "etcd service on client port",
+			objects: []runtime.Object{
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "rke2-etcd", Namespace: "kube-system"},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "client", Port: 2379}}},
+				},
+			},
+			expected: "enabled",
+		},
+		{
+			name: "etcd endpointslice on client port",
+			objects: []runtime.Object{
+				&discoveryv1.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "etcd-abcde",
+						Namespace: "kube-system",
+						Labels:    map[string]string{discoveryv1.LabelServiceName: "etcd"},
+					},
+					Ports: []discoveryv1.EndpointPort{{Port: &clientPort}},
+				},
+			},
+			expected: "enabled",
+		},
+		{
+			name: "etcd configmap with certificate settings",
+			objects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "etcd-config", Namespace: "kube-system"},
+					Data:       map[string]string{"peer-trusted-ca-file": "/var/lib/rancher/rke2/server/tls/etcd/peer-ca.crt"},
+				},
+			},
+			expected: "enabled",
+		},
+		{
+			name: "metrics-only etcd service",
+			objects: []runtime.Object{
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "kube-etcd", Namespace: "kube-system"},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http-metrics", Port: 2381}}},
+				},
+			},
+			expected: "unknown",
+		},
+		{
+			name:     "no evidence",
+			expected: "unknown",
+		},
+		{
+			name:     "failed endpointslice list",
+			failing:  "endpointslices",
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+			if tt.failing != "" {
+				clientset.PrependReactor("list", tt.failing, func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("etcd leader changed")
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["etcd-tls"] != tt.expected {
+				t.Errorf("etcd-tls = %v, want %v", data.ExtraFieldInfo["etcd-tls"], tt.expected)
+			}
+			// A failed list means the check did not run cleanly
+			ran := strings.Split(data.ExtraFieldInfo["collectors-run"].(string), ",")
+			if got := slices.Contains(ran, "etcd-tls"); got != (tt.failing == "") {
+				t.Errorf("etcd-tls in collectors-run = %v, want %v", got, tt.failing == "")
+			}
+		})
+	}
+}
+
+func TestCollect_IngressWAF(t *testing.T) {
+	nginx := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "rancher/nginx-ingress-controller:v1.9.0"}}},
+			},
+		},
+	}
+	traefik := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "traefik", Namespace: "kube-system"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "traefik:v2.10"}}},
+			},
+		},
+	}
+	controllerConfig := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rke2-ingress-nginx-controller", Namespace: "kube-system"},
+			Data:       data,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected interface{}
+	}{
+		{"modsecurity enabled", []runtime.Object{nginx, controllerConfig(map[string]string{"enable-modsecurity": "true"})}, "modsecurity"},
+		{"owasp crs enabled", []runtime.Object{nginx, controllerConfig(map[string]string{"enable-owasp-modsecurity-crs": "true"})}, "modsecurity"},
+		{"modsecurity disabled", []runtime.Object{nginx, controllerConfig(map[string]string{"enable-modsecurity": "false"})}, "none"},
+		{"no configmap", []runtime.Object{nginx}, "none"},
+		{"not nginx", []runtime.Object{traefik}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["ingress-waf"] != tt.expected {
+				t.Errorf("ingress-waf = %v, want %v", data.ExtraFieldInfo["ingress-waf"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestEffectivePullPolicy(t *testing.T) {
+	tests := []struct {
+		container corev1.Container
+		expected  corev1.PullPolicy
+	}{
+		{corev1.Container{Image: "nginx:1.21", ImagePullPolicy: corev1.PullAlways}, corev1.PullAlways},
+		{corev1.Container{Image: "nginx:1.21", ImagePullPolicy: corev1.PullNever}, corev1.PullNever},
+		{corev1.Container{Image: "nginx:1.21"}, corev1.PullIfNotPresent},
+		{corev1.Container{Image: "nginx:latest"}, corev1.PullAlways},
+		{corev1.Container{Image: "nginx"}, corev1.PullAlways},
+		{corev1.Container{Image: "registry.example.com:5000/nginx"}, corev1.PullAlways},
+		{corev1.Container{Image: "nginx@sha256:abc123"}, corev1.PullIfNotPresent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.container.Image+"/"+string(tt.container.ImagePullPolicy), func(t *testing.T) {
+			if got := effectivePullPolicy(tt.container); got != tt.expected {
+				t.Errorf("effectivePullPolicy() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_SystemPullPolicy(t *testing.T) {
+	deployment := func(policy corev1.PullPolicy) *appsv1.Deployment {
+		d := testDeployment("rke2-coredns-rke2-coredns", "kube-system", "rancher/hardened-coredns:v1.11.1")
+		d.Spec.Template.Spec.Containers[0].ImagePullPolicy = policy
+		return d
+	}
+	daemonSet := func(policy corev1.PullPolicy) *appsv1.DaemonSet {
+		ds := testDaemonSet("rke2-canal", "kube-system", "rancher/hardened-calico:v3.26.0")
+		ds.Spec.Template.Spec.Containers[0].ImagePullPolicy = policy
+		return ds
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{"mixed", []runtime.Object{deployment(corev1.PullAlways), daemonSet(corev1.PullIfNotPresent)}, "mixed"},
+		{"always", []runtime.Object{deployment(corev1.PullAlways), daemonSet(corev1.PullAlways)}, "always"},
+		{"ifnotpresent", []runtime.Object{deployment(corev1.PullIfNotPresent), daemonSet(corev1.PullNever)}, "ifnotpresent"},
+		{"no workloads", nil, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["system-pull-policy"] != tt.expected {
+				t.Errorf("system-pull-policy = %v, want %v", data.ExtraFieldInfo["system-pull-policy"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_FIPSMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+	}{
+		{
+			name: "fips tagged image",
+			objects: []runtime.Object{
+				testDaemonSet("kube-proxy", "kube-system", "rancher/hardened-kubernetes:v1.32.2-rke2r1-build20250213-fips"),
+				testDaemonSet("rke2-canal", "kube-system", "rancher/hardened-calico:v3.26.0-build20250213"),
+			},
+			expected: "fips",
+		},
+		{
+			name:     "standard image",
+			objects:  []runtime.Object{testDaemonSet("rke2-canal", "kube-system", "rancher/hardened-calico:v3.26.0-build20250213")},
+			expected: "standard",
+		},
+		{
+			name:     "no rke2 images",
+			objects:  []runtime.Object{testDaemonSet("cilium", "kube-system", "quay.io/cilium/cilium:v1.16.5")},
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["fips-mode"] != tt.expected {
+				t.Errorf("fips-mode = %v, want %v", data.ExtraFieldInfo["fips-mode"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_RootPods(t *testing.T) {
+	nonRoot := true
+	uid := int64(1000)
+	rootUID := int64(0)
+
+	tests := []struct {
+		name     string
+		mode     string
+		pods     []runtime.Object
+		expected int
+	}{
+		{
+			name: "root and non-root pods",
+			mode: "recommended",
+			pods: []runtime.Object{
+				testPod("root", "default"),
+				testPod("nonroot", "default", podSecurity(&corev1.PodSecurityContext{RunAsNonRoot: &nonRoot}, nil)),
+				testPod("uid", "default", podSecurity(nil, &corev1.SecurityContext{RunAsUser: &uid})),
+			},
+			expected: 1,
+		},
+		{
+			name: "container overrides pod user to root",
+			mode: "recommended",
+			pods: []runtime.Object{
+				testPod("override", "default", podSecurity(&corev1.PodSecurityContext{RunAsUser: &uid}, &corev1.SecurityContext{RunAsUser: &rootUID})),
+			},
+			expected: 1,
+		},
+		{
+			name:     "system namespace excluded",
+			mode:     "recommended",
+			pods:     []runtime.Object{testPod("coredns", "kube-system")},
+			expected: 0,
+		},
+		{
+			name:     "minimal mode",
+			mode:     "minimal",
+			pods:     []runtime.Object{testPod("root", "default")},
+			expected: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+
+			data, err := Collect(context.Background(), clientset, tt.mode)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["root-pod-count"] != tt.expected {
+				t.Errorf("root-pod-count = %v, want %v", data.ExtraFieldInfo["root-pod-count"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_MACInUse(t *testing.T) {
+	seLinux := &corev1.SELinuxOptions{Type: "spc_t"}
+	runtimeDefault := &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeRuntimeDefault}
+	unconfined := &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeUnconfined}
+	legacyAppArmor := func(pod *corev1.Pod) {
+		pod.Annotations = map[string]string{"container.apparmor.security.beta.kubernetes.io/app": "runtime/default"}
+	}
+
+	tests := []struct {
+		name     string
+		pods     []runtime.Object
+		listErr  error
+		expected string
+	}{
+		{
+			name:     "pod-level seLinuxOptions",
+			pods:     []runtime.Object{testPod("app", "default", podSecurity(&corev1.PodSecurityContext{SELinuxOptions: seLinux}, nil))},
+			expected: "selinux",
+		},
+		{
+			name:     "container-level seLinuxOptions",
+			pods:     []runtime.Object{testPod("app", "default", podSecurity(nil, &corev1.SecurityContext{SELinuxOptions: seLinux}))},
+			expected: "selinux",
+		},
+		{
+			name:     "empty seLinuxOptions ignored",
+			pods:     []runtime.Object{testPod("app", "default", podSecurity(&corev1.PodSecurityContext{SELinuxOptions: &corev1.SELinuxOptions{}}, nil))},
+			expected: "none",
+		},
+		{
+			name: "selinux wins over apparmor",
+			pods: []runtime.Object{
+				testPod("a", "default", podSecurity(&corev1.PodSecurityContext{AppArmorProfile: runtimeDefault}, nil)),
+				testPod("b", "default", podSecurity(nil, &corev1.SecurityContext{SELinuxOptions: seLinux})),
+			},
+			expected: "selinux",
+		},
+		{
+			name:     "appArmorProfile",
+			pods:     []runtime.Object{testPod("app", "default", podSecurity(nil, &corev1.SecurityContext{AppArmorProfile: runtimeDefault}))},
+			expected: "apparmor",
+		},
+		{
+			name:     "legacy apparmor annotation",
+			pods:     []runtime.Object{testPod("app", "default", legacyAppArmor)},
+			expected: "apparmor",
+		},
+		{
+			name:     "unconfined apparmor ignored",
+			pods:     []runtime.Object{testPod("app", "default", podSecurity(&corev1.PodSecurityContext{AppArmorProfile: unconfined}, nil))},
+			expected: "none",
+		},
+		{
+			name:     "no pods",
+			expected: "none",
+		},
+		{
+			name:     "pod list error",
+			listErr:  apierrors.NewServiceUnavailable("unavailable"),
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.pods...)
+			clientset := fake.NewClientset(objects...)
+			if tt.listErr != nil {
+				clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.listErr
+				})
+			}
+
+			data, err := Collect(context.Background(), clientset, "recommended")
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if data.ExtraFieldInfo["mac-in-use"] != tt.expected {
+				t.Errorf("mac-in-use = %v, want %v", data.ExtraFieldInfo["mac-in-use"], tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollect_MeshMTLS(t *testing.T) {
+	peerAuthentication := func(name, namespace, mode string, selector map[string]interface{}) runtime.Object {
+		spec := map[string]interface{}{"mtls": map[string]interface{}{"mode": mode}}
+		if selector != nil {
+			spec["selector"] = selector
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "security.istio.io/v1",
+			"kind":       "PeerAuthentication",
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+			"spec":       spec,
+		}}
+	}
+	istiod := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"}}
+	peerAuthenticationAPI := []*metav1.APIResourceList{
+		{GroupVersion: "security.istio.io/v1", APIResources: []metav1.APIResource{{Name: "peerauthentications"}}},
+	}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		served   []*metav1.APIResourceList
+		policies []runtime.Object
+		expected interface{}
+	}{
+		{
+			name:     "istio mesh-wide strict",
+			objects:  []runtime.Object{istiod},
+			served:   peerAuthenticationAPI,
+			policies: []runtime.Object{peerAuthentication("default", "istio-system", "STRICT", nil)},
+			expected: "strict",
+		},
+		{
+			name:    "istio strict for one workload only",
+			objects: []runtime.Object{istiod},
+			served:  peerAuthenticationAPI,
+			policies: []runtime.Object{
+				peerAuthentication("default", "istio-system", "PERMISSIVE", nil),
+				peerAuthentication("payments", "istio-system", "STRICT", map[string]interface{}{"matchLabels": map[string]interface{}{"app": "payments"}}),
+			},
+			expected: "permissive",
+		},
+		{
+			name:     "istio without policies",
+			objects:  []runtime.Object{istiod},
+			served:   peerAuthenticationAPI,
+			expected: "permissive",
+		},
+		{
+			name:     "istio without peerauthentication API",
+			objects:  []runtime.Object{istiod},
+			expected: "unknown",
+		},
+		{
+			name:     "linkerd",
+			objects:  []runtime.Object{&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "linkerd-destination", Namespace: "linkerd"}}},
+			expected: "enabled",
+		},
+		{
+			name:     "no mesh",
+			served:   peerAuthenticationAPI,
+			policies: []runtime.Object{peerAuthentication("default", "istio-system", "STRICT", nil)},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+			}, tt.objects...)
+			clientset := fake.NewClientset(objects...)
+			clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = tt.served
+			dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{
+					peerAuthenticationResources[0]: "PeerAuthenticationList",
+					peerAuthenticationResources[1]: "PeerAuthenticationList",
+				},
+				tt.policies...)
+
+			data, err := Collect(context.Background(), clientset, "recommended", WithDynamicClient(dynamicClient))
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+
+			if got := data.ExtraFieldInfo["mesh-mtls"]; got != tt.expected {
+				t.Errorf("mesh-mtls = %v, want %v", got, tt.expected)
+			}
+		})
 	}
 }
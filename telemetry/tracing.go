@@ -0,0 +1,16 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies spans from this package.
+const tracerName = "github.com/rancher/rke2-security-responder/telemetry"
+
+// tracer returns the tracer of the global provider. It is looked up on every
+// use so a provider installed after package init is picked up; until one is
+// installed the global provider is a no-op.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
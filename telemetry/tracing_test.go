@@ -0,0 +1,69 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTracing_CollectAndSendSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(Response{})
+	}))
+	defer server.Close()
+
+	clientset := fake.NewClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uuid"}},
+	)
+	data, err := Collect(context.Background(), clientset, "recommended", WithCollectors("cni"))
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if _, err := Send(context.Background(), data, server.URL); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	for _, name := range []string{"Collect", "cni", "Send"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("no %q span among %d exported", name, len(spans))
+		}
+	}
+	if _, ok := spans["pdb"]; ok {
+		t.Error("got a span for the skipped pdb collector")
+	}
+	if spans["cni"].Parent.SpanID() != spans["Collect"].SpanContext.SpanID() {
+		t.Error("cni span is not a child of the Collect span")
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range spans["Send"].Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if got := attrs["endpoint"].AsString(); got != server.URL {
+		t.Errorf("Send span endpoint = %q, want %q", got, server.URL)
+	}
+	if got := attrs["http.response.status_code"].AsInt64(); got != http.StatusOK {
+		t.Errorf("Send span status code = %d, want %d", got, http.StatusOK)
+	}
+}